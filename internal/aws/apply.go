@@ -0,0 +1,128 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/jakebark/corset/internal/core"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+// policyDescription is set on policies corset creates
+const policyDescription = "Managed by corset"
+
+// policyID matches the IDs Organizations gives policies, so a name such as p-guardrails is not taken for one
+var policyID = regexp.MustCompile(`^p-[0-9a-z_]{8,128}$`)
+
+// Client is the subset of the AWS Organizations API corset uses, for one policy type
+type Client interface {
+	// FindPolicy returns the ID of the policy with the name, or "" if there is none
+	FindPolicy(ctx context.Context, name string) (string, error)
+	CreatePolicy(ctx context.Context, name, description, content string) (string, error)
+	UpdatePolicy(ctx context.Context, id, content string) error
+}
+
+// Change creates or updates one policy from an output file
+type Change struct {
+	File string
+	Name string
+	ID   string // empty when the policy will be created
+}
+
+// ApplyResults pushes the written files to AWS Organizations, only reporting the changes unless --confirm is set
+func ApplyResults(ctx context.Context, userInput inputs.UserInput, results []core.WriteResult) error {
+	client, err := NewClient(ctx, userInput.PolicyType)
+	if err != nil {
+		return err
+	}
+	changes, err := Plan(ctx, client, userInput.Apply, results)
+	if err != nil {
+		return err
+	}
+	return Apply(ctx, client, changes, userInput.Confirm)
+}
+
+// Plan matches each output file to the policy it will create or update.
+// A policy ID targets one existing policy, a name is suffixed -2, -3... for each further file.
+func Plan(ctx context.Context, client Client, target string, results []core.WriteResult) ([]Change, error) {
+	if isPolicyID(target) {
+		if len(results) != 1 {
+			return nil, fmt.Errorf("policy %s can only be updated from a single file, got %d", target, len(results))
+		}
+		return []Change{{File: results[0].Filename, ID: target}}, nil
+	}
+
+	var changes []Change
+	for i, result := range results {
+		name := target
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d", target, i+1)
+		}
+		id, err := client.FindPolicy(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("finding policy %s: %w", name, err)
+		}
+		changes = append(changes, Change{File: result.Filename, Name: name, ID: id})
+	}
+	return changes, nil
+}
+
+// Apply makes the planned changes when confirm is set, otherwise it prints what would change
+func Apply(ctx context.Context, client Client, changes []Change, confirm bool) error {
+	for _, change := range changes {
+		data, err := core.ReadPolicyFile(change.File)
+		if err != nil {
+			return err
+		}
+		file := filepath.Base(change.File)
+		// AWS counts whitespace against the size limit, so indented output is pushed minified, as it was packed
+		var content bytes.Buffer
+		if err := json.Compact(&content, data); err != nil {
+			return fmt.Errorf("%s is not valid JSON: %w", file, err)
+		}
+
+		if !confirm {
+			if change.ID == "" {
+				fmt.Fprintf(os.Stderr, "Would create policy %s from %s\n", change.Name, file)
+			} else {
+				fmt.Fprintf(os.Stderr, "Would update policy %s from %s\n", change.label(), file)
+			}
+			continue
+		}
+
+		if change.ID == "" {
+			id, err := client.CreatePolicy(ctx, change.Name, policyDescription, content.String())
+			if err != nil {
+				return fmt.Errorf("creating policy %s: %w", change.Name, err)
+			}
+			fmt.Fprintf(os.Stderr, "Created policy %s (%s) from %s\n", change.Name, id, file)
+			continue
+		}
+		if err := client.UpdatePolicy(ctx, change.ID, content.String()); err != nil {
+			return fmt.Errorf("updating policy %s: %w", change.label(), err)
+		}
+		fmt.Fprintf(os.Stderr, "Updated policy %s from %s\n", change.label(), file)
+	}
+
+	if !confirm {
+		fmt.Fprintln(os.Stderr, "Dry run, add --confirm to apply")
+	}
+	return nil
+}
+
+func (c Change) label() string {
+	if c.Name == "" {
+		return c.ID
+	}
+	return fmt.Sprintf("%s (%s)", c.Name, c.ID)
+}
+
+// isPolicyID reports whether a target is an Organizations policy ID rather than a name
+func isPolicyID(target string) bool {
+	return policyID.MatchString(target)
+}
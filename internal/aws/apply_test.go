@@ -0,0 +1,168 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jakebark/corset/internal/core"
+)
+
+type mockClient struct {
+	policies map[string]string // name -> id
+	created  map[string]string // name -> content
+	updated  map[string]string // id -> content
+	err      error
+}
+
+func newMockClient(policies map[string]string) *mockClient {
+	return &mockClient{policies: policies, created: map[string]string{}, updated: map[string]string{}}
+}
+
+func (m *mockClient) FindPolicy(ctx context.Context, name string) (string, error) {
+	return m.policies[name], m.err
+}
+
+func (m *mockClient) CreatePolicy(ctx context.Context, name, description, content string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	m.created[name] = content
+	return "p-new" + name, nil
+}
+
+func (m *mockClient) UpdatePolicy(ctx context.Context, id, content string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.updated[id] = content
+	return nil
+}
+
+func writeResults(t *testing.T, contents ...string) []core.WriteResult {
+	t.Helper()
+	tempDir := t.TempDir()
+	var results []core.WriteResult
+	for i, content := range contents {
+		filename := filepath.Join(tempDir, "corset"+string(rune('1'+i))+".json")
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		results = append(results, core.WriteResult{Filename: filename})
+	}
+	return results
+}
+
+func TestPlan(t *testing.T) {
+	ctx := context.Background()
+	results := writeResults(t, `{"a":1}`, `{"b":2}`, `{"c":3}`)
+	client := newMockClient(map[string]string{"guardrails-2": "p-existing"})
+
+	changes, err := Plan(ctx, client, "guardrails", results)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Change{
+		{File: results[0].Filename, Name: "guardrails"},
+		{File: results[1].Filename, Name: "guardrails-2", ID: "p-existing"},
+		{File: results[2].Filename, Name: "guardrails-3"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %d", len(expected), len(changes))
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Change %d: expected %+v, got %+v", i, expected[i], changes[i])
+		}
+	}
+}
+
+func TestPlanPolicyID(t *testing.T) {
+	ctx := context.Background()
+	client := newMockClient(nil)
+
+	changes, err := Plan(ctx, client, "p-abc12345", writeResults(t, `{"a":1}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changes) != 1 || changes[0].ID != "p-abc12345" {
+		t.Errorf("Expected an update of p-abc12345, got %+v", changes)
+	}
+
+	// one policy ID cannot hold several files
+	if _, err := Plan(ctx, client, "p-abc12345", writeResults(t, `{"a":1}`, `{"b":2}`)); err == nil {
+		t.Error("Expected an error for several files and a policy ID")
+	}
+}
+
+func TestIsPolicyID(t *testing.T) {
+	tests := map[string]bool{
+		"p-abc12345":           true,
+		"p-examplepolicyid111": true,
+		"p-FullAWSAccess":      false,
+		"p-abc123":             false,
+		"p-guard-rails":        false,
+		"guardrails":           false,
+		"p-":                   false,
+	}
+	for target, expected := range tests {
+		if result := isPolicyID(target); result != expected {
+			t.Errorf("isPolicyID(%s): expected %v, got %v", target, expected, result)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	results := writeResults(t, `{"a":1}`, `{"b":2}`)
+	changes := []Change{
+		{File: results[0].Filename, Name: "guardrails"},
+		{File: results[1].Filename, Name: "guardrails-2", ID: "p-existing"},
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		client := newMockClient(nil)
+		if err := Apply(ctx, client, changes, false); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(client.created) != 0 || len(client.updated) != 0 {
+			t.Errorf("Expected no changes in a dry run, got created %v updated %v", client.created, client.updated)
+		}
+	})
+
+	t.Run("confirm", func(t *testing.T) {
+		client := newMockClient(nil)
+		if err := Apply(ctx, client, changes, true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if client.created["guardrails"] != `{"a":1}` {
+			t.Errorf("Expected guardrails to be created, got %v", client.created)
+		}
+		if client.updated["p-existing"] != `{"b":2}` {
+			t.Errorf("Expected p-existing to be updated, got %v", client.updated)
+		}
+	})
+
+	t.Run("indented", func(t *testing.T) {
+		client := newMockClient(nil)
+		indented := writeResults(t, "{\n  \"Version\": \"2012-10-17\",\n  \"Statement\": []\n}")
+		if err := Apply(ctx, client, []Change{{File: indented[0].Filename, Name: "guardrails"}}, true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// whitespace counts toward the AWS size limit, so it is never pushed
+		if expected := `{"Version":"2012-10-17","Statement":[]}`; client.created["guardrails"] != expected {
+			t.Errorf("Expected %s, got %q", expected, client.created["guardrails"])
+		}
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		client := newMockClient(nil)
+		client.err = errors.New("access denied")
+		if err := Apply(ctx, client, changes, true); err == nil {
+			t.Error("Expected the client error to be returned")
+		}
+	})
+}
@@ -0,0 +1,73 @@
+//go:build aws
+
+package aws
+
+import (
+	"context"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/jakebark/corset/internal/config"
+)
+
+type organizationsClient struct {
+	api        *organizations.Client
+	policyType types.PolicyType
+}
+
+// NewClient returns an Organizations client for the policy type using the default AWS credential chain
+func NewClient(ctx context.Context, policyType string) (Client, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client := &organizationsClient{
+		api:        organizations.NewFromConfig(cfg),
+		policyType: types.PolicyTypeServiceControlPolicy,
+	}
+	if policyType == config.PolicyTypeRCP {
+		client.policyType = types.PolicyTypeResourceControlPolicy
+	}
+	return client, nil
+}
+
+func (c *organizationsClient) FindPolicy(ctx context.Context, name string) (string, error) {
+	paginator := organizations.NewListPoliciesPaginator(c.api, &organizations.ListPoliciesInput{
+		Filter: c.policyType,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+		for _, policy := range page.Policies {
+			if awssdk.ToString(policy.Name) == name {
+				return awssdk.ToString(policy.Id), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func (c *organizationsClient) CreatePolicy(ctx context.Context, name, description, content string) (string, error) {
+	output, err := c.api.CreatePolicy(ctx, &organizations.CreatePolicyInput{
+		Name:        awssdk.String(name),
+		Description: awssdk.String(description),
+		Content:     awssdk.String(content),
+		Type:        c.policyType,
+	})
+	if err != nil {
+		return "", err
+	}
+	return awssdk.ToString(output.Policy.PolicySummary.Id), nil
+}
+
+func (c *organizationsClient) UpdatePolicy(ctx context.Context, id, content string) error {
+	_, err := c.api.UpdatePolicy(ctx, &organizations.UpdatePolicyInput{
+		PolicyId: awssdk.String(id),
+		Content:  awssdk.String(content),
+	})
+	return err
+}
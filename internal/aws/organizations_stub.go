@@ -0,0 +1,13 @@
+//go:build !aws
+
+package aws
+
+import (
+	"context"
+	"errors"
+)
+
+// NewClient is unavailable unless corset is built with the aws tag, keeping the SDK out of default builds
+func NewClient(ctx context.Context, policyType string) (Client, error) {
+	return nil, errors.New("corset was built without AWS support, rebuild with -tags aws to use --apply")
+}
@@ -10,23 +10,107 @@ const (
 	// MaxPolicySize is the AWS SCP character limit
 	MaxPolicySize = 5120
 
+	// MaxIAMPolicySize is the AWS IAM managed policy character limit
+	MaxIAMPolicySize = 6144
+
 	// CorsetSuffix is appended to output filenames
 	CorsetSuffix = "_corset"
 
-	// SCPBaseStructure is the minified base SCP structure
-	SCPBaseStructure = `{"Version":"2012-10-17","Statement":[]}`
+	// ManifestFilename is written alongside the outputs by --manifest
+	ManifestFilename = "corset-manifest.json"
+
+	// IgnoreFilename lists gitignore-style patterns of paths to skip in a target directory
+	IgnoreFilename = ".corsetignore"
+
+	// ReportCSV writes a CSV report of the output files with --report csv
+	ReportCSV = "csv"
+
+	// ReportFilename is written alongside the outputs by --report csv
+	ReportFilename = "corset-report.csv"
+
+	// DefaultWarnThreshold is the percent of the limit a file can fill before --warn-threshold warns
+	DefaultWarnThreshold = 90
+
+	// DefaultGroupDelimiter ends the filename prefix --group-by-prefix groups by when no delimiter is given
+	DefaultGroupDelimiter = "-"
+
+	// StrategyFirstFit packs each statement into the first file with room (first-fit-decreasing)
+	StrategyFirstFit = "ffd"
+
+	// StrategyBestFit packs each statement into the fullest file with room (best-fit-decreasing)
+	StrategyBestFit = "bfd"
+
+	// StrategyBalance packs each statement into the emptiest file with room, keeping file sizes even
+	StrategyBalance = "balance"
+
+	// PolicyTypeSCP is a service control policy, the default type
+	PolicyTypeSCP = "scp"
+
+	// PolicyTypeRCP is a resource control policy, with the same size limits but stricter statement rules
+	PolicyTypeRCP = "rcp"
+
+	// PolicyTypeIAM is an IAM managed policy, with a larger size limit and no SCP statement rules
+	PolicyTypeIAM = "iam"
+
+	// PartitionAWS is the standard AWS partition
+	PartitionAWS = "aws"
+
+	// PartitionGovCloud is the AWS GovCloud (US) partition
+	PartitionGovCloud = "aws-us-gov"
+
+	// PartitionChina is the AWS China partition
+	PartitionChina = "aws-cn"
+
+	// FormatJSON writes each packed policy as its own JSON file, the default format
+	FormatJSON = "json"
+
+	// FormatCloudFormation writes every packed policy into one CloudFormation template in JSON
+	FormatCloudFormation = "cloudformation"
+
+	// FormatCloudFormationYAML writes every packed policy into one CloudFormation template in YAML
+	FormatCloudFormationYAML = "cloudformation-yaml"
+
+	// LogLevelDebug also logs each file read and where each statement is placed
+	LogLevelDebug = "debug"
+
+	// LogLevelInfo logs informational messages, warnings and errors, the default level
+	LogLevelInfo = "info"
+
+	// LogLevelWarn logs only warnings and errors
+	LogLevelWarn = "warn"
+
+	// LogLevelError logs only errors
+	LogLevelError = "error"
+
+	// LogFormatText logs concise lines such as "Warning: ...", the default format
+	LogFormatText = "text"
+
+	// LogFormatJSON logs each record as a line of slog JSON
+	LogFormatJSON = "json"
+
+	// CommandSplit packs statements across files within the size limit, the default command
+	CommandSplit = "split"
+
+	// CommandMerge combines all statements into one file without enforcing the size limit
+	CommandMerge = "merge"
+
+	// CommandValidate checks statements without writing any output
+	CommandValidate = "validate"
+
+	// CommandStats prints an analysis of the input statements
+	CommandStats = "stats"
+
+	// CommandClean removes the files corset generated in a directory
+	CommandClean = "clean"
 
-	// SCPBaseWithWS is the formatted base SCP structure with whitespace
-	SCPBaseWithWS = `{
-  "Version": "2012-10-17",
-  "Statement": []
-}`
+	// ExitFailure is the exit code when no statements are found, packing fails or output cannot be written
+	ExitFailure = 1
 
-	// SCPBaseSizeMinified is the character overhead for minified SCP structure (minus Statement array)
-	SCPBaseSizeMinified = 37 // len(SCPBaseStructure) - 2 for []
+	// ExitUsage is the exit code for invalid arguments or flags
+	ExitUsage = 2
 
-	// SCPBaseSizeWithWS is the character overhead for formatted SCP structure (minus Statement array)
-	SCPBaseSizeWithWS = 46 // len(SCPBaseWithWS) - 2 for []
+	// DefaultIndent is the indentation used for whitespace output
+	DefaultIndent = "  "
 
 	// SCPVersion is the AWS SCP policy version
 	SCPVersion = "2012-10-17"
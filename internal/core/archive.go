@@ -0,0 +1,94 @@
+package core
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// zipEntries lists the policy files inside a zip archive, as paths beneath the archive.
+// Entries named outside the archive, such as ../policy.json, are skipped with a warning,
+// as joined onto the archive's path they would name a file on disk.
+func zipEntries(archive string) ([]string, error) {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var entries []string
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || !isPolicyFile(file.Name) {
+			continue
+		}
+		name, ok := archiveEntryName(file.Name)
+		if !ok {
+			slog.Warn("skipping an entry named outside the archive", "file", archive, "entry", file.Name)
+			continue
+		}
+		entries = append(entries, filepath.Join(archive, filepath.FromSlash(name)))
+	}
+	sort.Strings(entries)
+	return entries, nil
+}
+
+// splitArchivePath splits a path beneath a zip archive into the archive and the entry name within it
+func splitArchivePath(filename string) (string, string, bool) {
+	marker := ".zip" + string(filepath.Separator)
+	i := strings.Index(filename, marker)
+	if i < 0 {
+		return "", "", false
+	}
+	archive := filename[:i+len(".zip")]
+	if info, err := os.Stat(archive); err != nil || info.IsDir() {
+		return "", "", false
+	}
+	entry, ok := archiveEntryName(filepath.ToSlash(filename[i+len(marker):]))
+	if !ok {
+		return "", "", false
+	}
+	return archive, entry, true
+}
+
+// archiveEntryName cleans a zip entry name, rejecting one that is absolute or climbs out of the archive
+func archiveEntryName(name string) (string, bool) {
+	if strings.Contains(name, `\`) {
+		return "", false
+	}
+	name = path.Clean(name)
+	return name, fs.ValidPath(name)
+}
+
+// readZipEntry returns the uncompressed contents of one entry in a zip archive
+func readZipEntry(archive, entry string) ([]byte, error) {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	file, err := reader.Open(path.Clean(entry))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// sourcePath returns the archive holding a file, or the file itself when it is not inside one
+func sourcePath(filename string) string {
+	if archive, _, ok := splitArchivePath(filename); ok {
+		return archive
+	}
+	return filename
+}
+
+func isZipFile(path string) bool {
+	return strings.HasSuffix(path, ".zip")
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+// writeZip builds an archive in memory from name -> content and writes it to disk
+func writeZip(t *testing.T, filename string, files map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range files {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create zip entry: %v", err)
+		}
+		entry.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+}
+
+func TestZipExtraction(t *testing.T) {
+	tempDir := t.TempDir()
+	archive := filepath.Join(tempDir, "bundle.zip")
+	first := `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`
+	writeZip(t, archive, map[string]string{
+		"policies/a.json": first,
+		"policies/b.json": `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}]}`,
+		"readme.txt":      "not a policy",
+	})
+
+	userInput := inputs.UserInput{Target: archive, Targets: []string{archive}, IsArchive: true, MaxFiles: config.DefaultMaxFiles}
+	files := ResolveFiles(userInput)
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 policy files in the archive, got %v", files)
+	}
+
+	statements, header := extractAllStatements(files)
+	if len(statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(statements))
+	}
+	if header.Version != config.SCPVersion {
+		t.Errorf("Expected version %s, got %s", config.SCPVersion, header.Version)
+	}
+	// sizes are measured on the uncompressed JSON
+	if total := totalFileSize(files[:1]); total != len(first) {
+		t.Errorf("Expected size %d, got %d", len(first), total)
+	}
+
+	if _, err := ProcessFiles(userInput, files); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "bundle.json")); err != nil {
+		t.Errorf("Expected bundle.json alongside the archive: %v", err)
+	}
+	if _, err := os.Stat(archive); err != nil {
+		t.Errorf("Expected the archive to be left in place: %v", err)
+	}
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	tempDir := t.TempDir()
+	archive := filepath.Join(tempDir, "bundle.zip")
+	writeZip(t, archive, map[string]string{"a.json": "{}"})
+
+	gotArchive, entry, ok := splitArchivePath(filepath.Join(archive, "policies", "a.json"))
+	if !ok || gotArchive != archive || entry != "policies/a.json" {
+		t.Errorf("Expected %s and policies/a.json, got %s, %s, %v", archive, gotArchive, entry, ok)
+	}
+
+	// a directory named like an archive is not one
+	dir := filepath.Join(tempDir, "folder.zip")
+	os.Mkdir(dir, 0755)
+	if _, _, ok := splitArchivePath(filepath.Join(dir, "a.json")); ok {
+		t.Error("Expected a directory ending in .zip not to be treated as an archive")
+	}
+	if _, _, ok := splitArchivePath(filepath.Join(tempDir, "a.json")); ok {
+		t.Error("Expected a plain path not to be treated as an archive")
+	}
+}
+
+func TestZipEntriesTraversal(t *testing.T) {
+	tempDir := t.TempDir()
+	archive := filepath.Join(tempDir, "bundle.zip")
+	policy := `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`
+	writeZip(t, archive, map[string]string{
+		"policies/a.json":       policy,
+		"../victim.json":        policy,
+		"/etc/absolute.json":    policy,
+		"policies/../../b.json": policy,
+	})
+	victim := filepath.Join(tempDir, "victim.json")
+	other := filepath.Join(tempDir, "other.json")
+	for _, file := range []string{victim, other} {
+		if err := os.WriteFile(file, []byte(policy), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	captureLogs(t, slog.LevelInfo)
+	entries, err := zipEntries(archive)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{filepath.Join(archive, "policies", "a.json")}; !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected only the entry inside the archive, got %v", entries)
+	}
+	if _, _, ok := splitArchivePath(filepath.Join(archive, "..", "victim.json")); ok {
+		t.Error("Expected a path climbing out of the archive not to be an entry")
+	}
+
+	// packed with another file, the inputs are replaced, but nothing outside the archive is touched
+	userInput := inputs.UserInput{Target: archive, Targets: []string{archive, other}, MaxFiles: config.DefaultMaxFiles, Force: true, Quiet: true}
+	if _, err := ProcessFiles(userInput, ResolveFiles(userInput)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Errorf("Expected %s left alone: %v", victim, err)
+	}
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+// CleanDirectory removes the files corset generated in the target directory, or lists them with --dry-run
+func CleanDirectory(userInput inputs.UserInput) error {
+	files, err := findGeneratedFiles(userInput, userInput.Target)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if userInput.DryRun {
+			fmt.Fprintf(os.Stderr, "Would remove %s\n", file)
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+		if !userInput.Quiet {
+			fmt.Fprintf(os.Stderr, "Removed %s\n", file)
+		}
+	}
+	if userInput.DryRun {
+		fmt.Fprintf(os.Stderr, "%d generated files would be removed\n", len(files))
+	} else if !userInput.Quiet {
+		fmt.Fprintf(os.Stderr, "Removed %d generated files\n", len(files))
+	}
+	return nil
+}
+
+// findGeneratedFiles returns the files under dir named like corset's outputs, manifest and report.
+// It only goes by name, and only the outputs of packing dir itself are matched by the directory's
+// name, so a hand-written policy named after a subdirectory is never removed.
+func findGeneratedFiles(userInput inputs.UserInput, dir string) ([]string, error) {
+	// absolute, as discovery has it, so a target of . is matched by the directory's own name
+	dir = absPath(dir)
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && !userInput.IncludeHidden && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if userInput.NoRecurse && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if isGeneratedOutput(path) || isDirectoryOutput(dir, path) || filepath.Base(path) == config.ReportFilename || filepath.Base(path) == config.ManifestFilename {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
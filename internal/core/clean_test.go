@@ -0,0 +1,118 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+func TestCleanDirectory(t *testing.T) {
+	generated := []string{
+		"corset1.json",
+		"corset2.json.gz",
+		"corset-manifest.json",
+		"corset-report.csv",
+		"scp_corset.json",
+		"policies.json",
+		"policies-2.json",
+		"nested/corset1.json",
+	}
+	kept := []string{
+		"deny.json",
+		"policies-notes.json",
+		"nested/nested.json",
+		"nested/policies.json",
+		"readme.txt",
+		".cache/corset1.json",
+	}
+
+	for _, tt := range []struct {
+		name   string
+		dryRun bool
+	}{
+		{name: "remove", dryRun: false},
+		{name: "dry run", dryRun: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "policies")
+			for _, file := range append(append([]string{}, generated...), kept...) {
+				path := filepath.Join(dir, file)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("Failed to create directory: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(`{}`), 0644); err != nil {
+					t.Fatalf("Failed to write %s: %v", file, err)
+				}
+			}
+
+			userInput := inputs.UserInput{Target: dir, IsDirectory: true, DryRun: tt.dryRun}
+			found, err := findGeneratedFiles(userInput, dir)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var expected []string
+			for _, file := range generated {
+				expected = append(expected, filepath.Join(dir, file))
+			}
+			sort.Strings(expected)
+			sort.Strings(found)
+			if len(found) != len(expected) {
+				t.Fatalf("Expected %v, got %v", expected, found)
+			}
+			for i := range expected {
+				if found[i] != expected[i] {
+					t.Errorf("Expected %s, got %s", expected[i], found[i])
+				}
+			}
+
+			if err := CleanDirectory(userInput); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, file := range generated {
+				_, err := os.Stat(filepath.Join(dir, file))
+				if tt.dryRun && err != nil {
+					t.Errorf("Expected %s to be left by a dry run: %v", file, err)
+				}
+				if !tt.dryRun && !os.IsNotExist(err) {
+					t.Errorf("Expected %s to be removed", file)
+				}
+			}
+			for _, file := range kept {
+				if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+					t.Errorf("Expected %s to be kept: %v", file, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanDirectoryRelative(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "policies")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, file := range []string{"policies.json", "policies-2.json", "deny.json"} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(`{}`), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+	t.Chdir(dir)
+	// the working directory is reported with any links in the temp path resolved
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	found, err := findGeneratedFiles(inputs.UserInput{Target: ".", IsDirectory: true}, ".")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sort.Strings(found)
+	expected := []string{filepath.Join(dir, "policies-2.json"), filepath.Join(dir, "policies.json")}
+	if len(found) != len(expected) || found[0] != expected[0] || found[1] != expected[1] {
+		t.Errorf("Expected %v for a target of ., got %v", expected, found)
+	}
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	cfnFormatVersion = "2010-09-09"
+	cfnPolicyType    = "AWS::Organizations::Policy"
+	cfnDescription   = "Managed by corset"
+)
+
+func isCloudFormation(userInput inputs.UserInput) bool {
+	return userInput.Format == config.FormatCloudFormation || userInput.Format == config.FormatCloudFormationYAML
+}
+
+// writeTemplate writes every packed policy into one CloudFormation template, returning a result per policy
+func writeTemplate(userInput inputs.UserInput, header Header, packedFiles [][]Statement, outputDir string, inputFiles []string) ([]WriteResult, error) {
+	filename := filepath.Join(outputDir, "template.json")
+	if userInput.Format == config.FormatCloudFormationYAML {
+		filename = filepath.Join(outputDir, "template.yaml")
+	}
+	if err := checkOverwrite(userInput, filename, inputFiles); err != nil {
+		return nil, err
+	}
+
+	base, _ := outputBase(userInput, inputFiles)
+	template, results, err := buildTemplate(userInput, header, packedFiles, base)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Filename = filename
+	}
+
+	err = writeFileAtomic(filename, func(w io.Writer) error {
+		if userInput.Format == config.FormatCloudFormationYAML {
+			encoder := yaml.NewEncoder(w)
+			encoder.SetIndent(2)
+			if err := encoder.Encode(template); err != nil {
+				return err
+			}
+			return encoder.Close()
+		}
+		_, err := w.Write(append(marshalJSON(template, "", "  "), '\n'))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("writing %s: %w", filename, err)
+	}
+	return results, nil
+}
+
+// buildTemplate creates a policy resource for each packed file, named base, base-2... like --apply
+func buildTemplate(userInput inputs.UserInput, header Header, packedFiles [][]Statement, base string) (cfnTemplate, []WriteResult, error) {
+	policyType := "SERVICE_CONTROL_POLICY"
+	if userInput.PolicyType == config.PolicyTypeRCP {
+		policyType = "RESOURCE_CONTROL_POLICY"
+	}
+
+	template := cfnTemplate{
+		AWSTemplateFormatVersion: cfnFormatVersion,
+		Description:              cfnDescription,
+		Resources:                map[string]cfnResource{},
+	}
+	var results []WriteResult
+	for i, statements := range packedFiles {
+		name := base
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d", base, i+1)
+		}
+		logicalID := fmt.Sprintf("%s%d", logicalIDPrefix(base), i+1)
+
+		// the content is measured as AWS counts it, minified whatever the template's own formatting
+		data := writeJSON(userInput, header, statements)
+		var content interface{} = json.RawMessage(data)
+		if userInput.Format == config.FormatCloudFormationYAML {
+			var node yaml.Node
+			if err := yaml.Unmarshal(data, &node); err != nil {
+				return template, nil, err
+			}
+			content = yamlFlowToBlock(node.Content[0])
+		}
+
+		template.Resources[logicalID] = cfnResource{
+			Type: cfnPolicyType,
+			Properties: cfnPolicyProperties{
+				Name:        name,
+				Description: cfnDescription,
+				Type:        policyType,
+				Content:     content,
+			},
+		}
+		results = append(results, WriteResult{
+			Size:       effectiveSize(userInput, header, statements, data),
+			Statements: len(statements),
+			Resource:   logicalID,
+		})
+	}
+	return template, results, nil
+}
+
+// logicalIDPrefix converts a base name into the alphanumeric PascalCase CloudFormation requires
+func logicalIDPrefix(base string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range base {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) || r > unicode.MaxASCII {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "Policy"
+	}
+	return b.String()
+}
+
+// yamlFlowToBlock clears the flow and quoted styles JSON parses with, so the policy is written as block YAML.
+// Strings are still quoted where YAML would otherwise read them as another type.
+func yamlFlowToBlock(node *yaml.Node) *yaml.Node {
+	node.Style &^= yaml.FlowStyle | yaml.DoubleQuotedStyle
+	for _, child := range node.Content {
+		yamlFlowToBlock(child)
+	}
+	return node
+}
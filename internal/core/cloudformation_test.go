@@ -0,0 +1,124 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+	"gopkg.in/yaml.v3"
+)
+
+func TestProcessFilesCloudFormation(t *testing.T) {
+	tests := []struct {
+		format    string
+		filename  string
+		unmarshal func([]byte, interface{}) error
+	}{
+		{format: config.FormatCloudFormation, filename: "template.json", unmarshal: json.Unmarshal},
+		{format: config.FormatCloudFormationYAML, filename: "template.yaml", unmarshal: yaml.Unmarshal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			tempDir := t.TempDir()
+			testFile := filepath.Join(tempDir, "org-guardrails.json")
+			var statements []map[string]interface{}
+			for i := 0; i < 6; i++ {
+				statements = append(statements, map[string]interface{}{
+					"Sid":      fmt.Sprintf("Deny%d", i),
+					"Effect":   "Deny",
+					"Action":   "s3:*",
+					"Resource": fmt.Sprintf("arn:aws:s3:::bucket-%d", i),
+				})
+			}
+			policy := map[string]interface{}{"Version": config.SCPVersion, "Statement": statements}
+			original := mustMarshal(t, policy)
+			if err := os.WriteFile(testFile, []byte(original), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			// a small limit splits the statements across three policies
+			userInput := inputs.UserInput{
+				Target:   testFile,
+				MaxFiles: config.DefaultMaxFiles,
+				MaxSize:  210,
+				Format:   tt.format,
+			}
+			summary, err := ProcessFiles(userInput, []string{testFile})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			results := summary.Files
+
+			data, err := os.ReadFile(filepath.Join(tempDir, tt.filename))
+			if err != nil {
+				t.Fatalf("Failed to read template: %v", err)
+			}
+			var template struct {
+				Resources map[string]struct {
+					Type       string `yaml:"Type"`
+					Properties struct {
+						Name    string `yaml:"Name"`
+						Content Policy `yaml:"Content"`
+					} `yaml:"Properties"`
+				} `yaml:"Resources"`
+			}
+			if err := tt.unmarshal(data, &template); err != nil {
+				t.Fatalf("Template is not valid: %v", err)
+			}
+			if len(template.Resources) != len(results) || len(results) != 3 {
+				t.Fatalf("Expected 3 resources, got %d for %d results", len(template.Resources), len(results))
+			}
+
+			total := 0
+			for i := range results {
+				resource, ok := template.Resources[fmt.Sprintf("OrgGuardrails%d", i+1)]
+				if !ok {
+					t.Fatalf("Expected resource OrgGuardrails%d, got %v", i+1, template.Resources)
+				}
+				if resource.Type != "AWS::Organizations::Policy" {
+					t.Errorf("Expected an Organizations policy, got %s", resource.Type)
+				}
+				if resource.Properties.Content.Version != config.SCPVersion {
+					t.Errorf("Expected policy version %s, got %s", config.SCPVersion, resource.Properties.Content.Version)
+				}
+				total += len(resource.Properties.Content.Statement)
+			}
+			if total != len(statements) {
+				t.Errorf("Expected %d statements in the template, got %d", len(statements), total)
+			}
+			if name := template.Resources["OrgGuardrails2"].Properties.Name; name != "org-guardrails-2" {
+				t.Errorf("Expected the second policy to be named org-guardrails-2, got %s", name)
+			}
+
+			// the input is left in place
+			if input, _ := os.ReadFile(testFile); string(input) != original {
+				t.Error("Expected the input file to be left untouched")
+			}
+		})
+	}
+}
+
+func TestLogicalIDPrefix(t *testing.T) {
+	tests := []struct {
+		base     string
+		expected string
+	}{
+		{base: "organisation-scp", expected: "OrganisationScp"},
+		{base: "policy_v2.final", expected: "PolicyV2Final"},
+		{base: "corset", expected: "Corset"},
+		{base: "---", expected: "Policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.base, func(t *testing.T) {
+			if result := logicalIDPrefix(tt.base); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"os"
+
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// nearLimitPercent is how full a file can be before its size is shown as near the limit
+const nearLimitPercent = 90
+
+// useColor reports whether the summary should be colored, only when stderr is a terminal and never when NO_COLOR is set
+func useColor(userInput inputs.UserInput) bool {
+	if userInput.NoColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(os.Stderr)
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// sizeColor is green under the limit, yellow near it and red over it
+func sizeColor(size, limit int) string {
+	switch {
+	case size > limit:
+		return ansiRed
+	case size*100 >= limit*nearLimitPercent:
+		return ansiYellow
+	}
+	return ansiGreen
+}
+
+func colorize(color, text string) string {
+	return color + text + ansiReset
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+func TestSizeColor(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		expected string
+	}{
+		{name: "under the limit", size: 1000, expected: ansiGreen},
+		{name: "near the limit", size: 4700, expected: ansiYellow},
+		{name: "at the limit", size: 5120, expected: ansiYellow},
+		{name: "over the limit", size: 6000, expected: ansiRed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := sizeColor(tt.size, 5120); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatResult(t *testing.T) {
+	result := WriteResult{Filename: "/tmp/corset1.json", Size: 6000, Statements: 3}
+
+	plain := formatResult(inputs.UserInput{}, result, false)
+	if strings.Contains(plain, "\x1b[") {
+		t.Errorf("Expected no color codes when disabled, got %q", plain)
+	}
+	if plain != "- corset1.json (6,000/5,120 chars, 117%, 880 over, 3 statements)" {
+		t.Errorf("Unexpected summary line %q", plain)
+	}
+
+	colored := formatResult(inputs.UserInput{}, result, true)
+	if !strings.Contains(colored, ansiRed+"6,000/5,120 chars, 117%"+ansiReset) {
+		t.Errorf("Expected an over-limit size in red, got %q", colored)
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	// test output is never a terminal, so color is always off here
+	if useColor(inputs.UserInput{}) {
+		t.Error("Expected no color when stdout is not a terminal")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if useColor(inputs.UserInput{}) {
+		t.Error("Expected no color when NO_COLOR is set")
+	}
+	if useColor(inputs.UserInput{NoColor: true}) {
+		t.Error("Expected no color with --no-color")
+	}
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// mergeConditions combines statements that differ only in one condition key's values, re-sizing any that change.
+//
+// Condition blocks are ANDed and a key's values are ORed, so two statements are merged only when their
+// conditions are identical apart from the values of a single operator and key, giving X and (k in A)
+// or X and (k in B), which is exactly X and (k in A or B). Negated operators (StringNotEquals...) and
+// ForAllValues: match only when every value does, so unioning their values would widen or narrow the
+// statement and they are never merged. Everything else in the statements, except Sid, must be identical.
+func mergeConditions(statements []Statement) []Statement {
+	var merged []Statement
+	for _, stmt := range statements {
+		placed := false
+		for i := range merged {
+			condition, ok := combineConditions(merged[i].Content, stmt.Content)
+			if !ok {
+				continue
+			}
+			content := make(map[string]interface{}, len(merged[i].Content))
+			for k, v := range merged[i].Content {
+				content[k] = v
+			}
+			content["Condition"] = condition
+			resized := newStatement(content)
+			resized.Source, resized.Index = merged[i].Source, merged[i].Index
+			merged[i] = resized
+			placed = true
+			break
+		}
+		if !placed {
+			merged = append(merged, stmt)
+		}
+	}
+	return merged
+}
+
+// combineConditions returns the single condition equivalent to either statement's, if there is one
+func combineConditions(a, b map[string]interface{}) (map[string]interface{}, bool) {
+	conditionA, okA := a["Condition"].(map[string]interface{})
+	conditionB, okB := b["Condition"].(map[string]interface{})
+	if !okA || !okB || !sameExcept(a, b, "Condition", "Sid") || len(conditionA) != len(conditionB) {
+		return nil, false
+	}
+
+	var diffOperator, diffKey string
+	differences := 0
+	for operator, keysA := range conditionA {
+		blockA, okA := keysA.(map[string]interface{})
+		blockB, okB := conditionB[operator].(map[string]interface{})
+		if !okA || !okB || len(blockA) != len(blockB) {
+			return nil, false
+		}
+		for key, valuesA := range blockA {
+			valuesB, ok := blockB[key]
+			if !ok {
+				return nil, false
+			}
+			if canonicalJSON(valuesA) != canonicalJSON(valuesB) {
+				diffOperator, diffKey = operator, key
+				differences++
+			}
+		}
+	}
+
+	switch differences {
+	case 0:
+		return conditionA, true
+	case 1:
+	default:
+		return nil, false
+	}
+
+	blockA := conditionA[diffOperator].(map[string]interface{})
+	blockB := conditionB[diffOperator].(map[string]interface{})
+	if !isUnionOperator(diffOperator) || !isStringOrStringArray(blockA[diffKey]) || !isStringOrStringArray(blockB[diffKey]) {
+		return nil, false
+	}
+
+	block := make(map[string]interface{}, len(blockA))
+	for k, v := range blockA {
+		block[k] = v
+	}
+	block[diffKey] = combineActions(blockA[diffKey], blockB[diffKey])
+
+	condition := make(map[string]interface{}, len(conditionA))
+	for k, v := range conditionA {
+		condition[k] = v
+	}
+	condition[diffOperator] = block
+	return condition, true
+}
+
+// isUnionOperator reports whether a condition operator matches when any one of its values does
+func isUnionOperator(operator string) bool {
+	return !strings.Contains(operator, "Not") && !strings.HasPrefix(operator, "ForAllValues:")
+}
+
+// sameExcept reports whether two statements are identical once the given elements are ignored
+func sameExcept(a, b map[string]interface{}, ignored ...string) bool {
+	strip := func(content map[string]interface{}) map[string]interface{} {
+		rest := make(map[string]interface{}, len(content))
+		for k, v := range content {
+			rest[k] = v
+		}
+		for _, key := range ignored {
+			delete(rest, key)
+		}
+		return rest
+	}
+	return canonicalJSON(strip(a)) == canonicalJSON(strip(b))
+}
+
+// canonicalJSON encodes a value with map keys in sorted order, for comparison
+func canonicalJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
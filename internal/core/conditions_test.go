@@ -0,0 +1,140 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeConditions(t *testing.T) {
+	region := func(operator string, regions ...interface{}) map[string]interface{} {
+		return map[string]interface{}{operator: map[string]interface{}{"aws:RequestedRegion": regions}}
+	}
+	statement := func(sid string, condition map[string]interface{}) Statement {
+		return newStatement(map[string]interface{}{
+			"Sid":       sid,
+			"Effect":    "Deny",
+			"Action":    "ec2:RunInstances",
+			"Resource":  "*",
+			"Condition": condition,
+		})
+	}
+
+	tests := []struct {
+		name     string
+		input    []Statement
+		expected []map[string]interface{} // resulting conditions, in order
+	}{
+		{
+			name: "values of one key are combined",
+			input: []Statement{
+				statement("A", region("StringEquals", "eu-west-1")),
+				statement("B", region("StringEquals", "eu-west-2", "eu-west-1")),
+				statement("C", region("StringEquals", "us-east-1")),
+			},
+			expected: []map[string]interface{}{
+				region("StringEquals", "eu-west-1", "eu-west-2", "us-east-1"),
+			},
+		},
+		{
+			name: "identical conditions",
+			input: []Statement{
+				statement("A", region("StringLike", "eu-*")),
+				statement("B", region("StringLike", "eu-*")),
+			},
+			expected: []map[string]interface{}{region("StringLike", "eu-*")},
+		},
+		{
+			name: "negated operators are kept apart",
+			input: []Statement{
+				statement("A", region("StringNotEquals", "eu-west-1")),
+				statement("B", region("StringNotEquals", "eu-west-2")),
+			},
+			expected: []map[string]interface{}{
+				region("StringNotEquals", "eu-west-1"),
+				region("StringNotEquals", "eu-west-2"),
+			},
+		},
+		{
+			name: "for all values is kept apart",
+			input: []Statement{
+				statement("A", region("ForAllValues:StringEquals", "eu-west-1")),
+				statement("B", region("ForAllValues:StringEquals", "eu-west-2")),
+			},
+			expected: []map[string]interface{}{
+				region("ForAllValues:StringEquals", "eu-west-1"),
+				region("ForAllValues:StringEquals", "eu-west-2"),
+			},
+		},
+		{
+			name: "different operators are kept apart",
+			input: []Statement{
+				statement("A", region("StringEquals", "eu-west-1")),
+				statement("B", region("StringLike", "eu-west-2")),
+			},
+			expected: []map[string]interface{}{
+				region("StringEquals", "eu-west-1"),
+				region("StringLike", "eu-west-2"),
+			},
+		},
+		{
+			name: "two differing keys are kept apart",
+			input: []Statement{
+				statement("A", map[string]interface{}{"StringEquals": map[string]interface{}{
+					"aws:RequestedRegion": "eu-west-1", "aws:PrincipalTag/team": "red",
+				}}),
+				statement("B", map[string]interface{}{"StringEquals": map[string]interface{}{
+					"aws:RequestedRegion": "eu-west-2", "aws:PrincipalTag/team": "blue",
+				}}),
+			},
+			expected: []map[string]interface{}{
+				{"StringEquals": map[string]interface{}{"aws:RequestedRegion": "eu-west-1", "aws:PrincipalTag/team": "red"}},
+				{"StringEquals": map[string]interface{}{"aws:RequestedRegion": "eu-west-2", "aws:PrincipalTag/team": "blue"}},
+			},
+		},
+		{
+			name: "an extra condition block is kept apart",
+			input: []Statement{
+				statement("A", region("StringEquals", "eu-west-1")),
+				statement("B", map[string]interface{}{
+					"StringEquals": map[string]interface{}{"aws:RequestedRegion": []interface{}{"eu-west-2"}},
+					"Bool":         map[string]interface{}{"aws:MultiFactorAuthPresent": "false"},
+				}),
+			},
+			expected: []map[string]interface{}{
+				region("StringEquals", "eu-west-1"),
+				{
+					"StringEquals": map[string]interface{}{"aws:RequestedRegion": []interface{}{"eu-west-2"}},
+					"Bool":         map[string]interface{}{"aws:MultiFactorAuthPresent": "false"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := mergeConditions(tt.input)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %d statements, got %d", len(tt.expected), len(result))
+			}
+			for i, expected := range tt.expected {
+				if canonicalJSON(result[i].Content["Condition"]) != canonicalJSON(expected) {
+					t.Errorf("Expected condition %s, got %s", canonicalJSON(expected), canonicalJSON(result[i].Content["Condition"]))
+				}
+			}
+		})
+	}
+}
+
+func TestMergeConditionsDifferentStatements(t *testing.T) {
+	condition := map[string]interface{}{"StringEquals": map[string]interface{}{"aws:RequestedRegion": "eu-west-1"}}
+	input := []Statement{
+		newStatement(map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": "*", "Condition": condition}),
+		newStatement(map[string]interface{}{"Effect": "Deny", "Action": "ec2:*", "Resource": "*", "Condition": condition}),
+		newStatement(map[string]interface{}{"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}),
+	}
+
+	result := mergeConditions(input)
+	if !reflect.DeepEqual(result, input) {
+		t.Errorf("Expected statements with different actions, or no condition, to be kept as is")
+	}
+}
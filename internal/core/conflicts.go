@@ -0,0 +1,81 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: Statement[%d] Allow may overlap %s: Statement[%d] Deny, the Deny always wins",
+		filepath.Base(c.Allow.Source), c.Allow.Index, filepath.Base(c.Deny.Source), c.Deny.Index)
+}
+
+// reportConflicts warns about each Allow that a Deny may override
+func reportConflicts(statements []Statement) {
+	for _, conflict := range findConflicts(statements) {
+		slog.Warn(conflict.String())
+	}
+}
+
+// findConflicts pairs every Allow with each Deny that may match the same action on the same resource.
+// Wildcards are treated conservatively, any pattern two entries could both match counts as an overlap.
+// Statements using NotAction or NotResource are skipped, their reach cannot be compared this way.
+func findConflicts(statements []Statement) []Conflict {
+	var conflicts []Conflict
+	for _, allow := range statements {
+		if allow.Content["Effect"] != "Allow" || !isComparable(allow.Content) {
+			continue
+		}
+		for _, deny := range statements {
+			if deny.Content["Effect"] != "Deny" || !isComparable(deny.Content) {
+				continue
+			}
+			// actions are case-insensitive, resource ARNs are not
+			if anyOverlap(allow.Content["Action"], deny.Content["Action"], true) &&
+				anyOverlap(allow.Content["Resource"], deny.Content["Resource"], false) {
+				conflicts = append(conflicts, Conflict{Allow: allow, Deny: deny})
+			}
+		}
+	}
+	return conflicts
+}
+
+func isComparable(content map[string]interface{}) bool {
+	_, hasAction := content["Action"]
+	_, hasResource := content["Resource"]
+	return hasAction && hasResource
+}
+
+// anyOverlap reports whether some entry of a can match the same value as some entry of b
+func anyOverlap(a, b interface{}, foldCase bool) bool {
+	for _, left := range actionList(a) {
+		for _, right := range actionList(b) {
+			if foldCase {
+				left, right = strings.ToLower(left), strings.ToLower(right)
+			}
+			if patternsOverlap(left, right) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// patternsOverlap reports whether a string exists that both wildcard patterns match
+func patternsOverlap(a, b string) bool {
+	switch {
+	case a == "" && b == "":
+		return true
+	case a != "" && a[0] == '*':
+		return patternsOverlap(a[1:], b) || (b != "" && patternsOverlap(a, b[1:]))
+	case b != "" && b[0] == '*':
+		return patternsOverlap(a, b[1:]) || (a != "" && patternsOverlap(a[1:], b))
+	case a == "" || b == "":
+		return false
+	case a[0] == '?' || b[0] == '?' || a[0] == b[0]:
+		return patternsOverlap(a[1:], b[1:])
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPatternsOverlap(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"s3:getobject", "s3:getobject", true},
+		{"s3:getobject", "s3:putobject", false},
+		{"s3:*", "s3:getobject", true},
+		{"s3:get*", "s3:*object", true},
+		{"s3:get*", "s3:put*", false},
+		{"*", "ec2:runinstances", true},
+		{"s3:getobjec?", "s3:getobject", true},
+		{"s3:get?", "s3:getobject", false},
+		{"arn:aws:s3:::logs/*", "arn:aws:s3:::logs/2024/*", true},
+		{"arn:aws:s3:::logs/*", "arn:aws:s3:::data/*", false},
+	}
+
+	for _, tt := range tests {
+		if result := patternsOverlap(tt.a, tt.b); result != tt.expected {
+			t.Errorf("patternsOverlap(%s, %s): expected %v, got %v", tt.a, tt.b, tt.expected, result)
+		}
+		if result := patternsOverlap(tt.b, tt.a); result != tt.expected {
+			t.Errorf("patternsOverlap(%s, %s): expected %v, got %v", tt.b, tt.a, tt.expected, result)
+		}
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	statements := []Statement{
+		{Source: "allow.json", Index: 0, Content: map[string]interface{}{
+			"Effect": "Allow", "Action": []interface{}{"s3:GetObject", "s3:PutObject"}, "Resource": "arn:aws:s3:::logs/*",
+		}},
+		{Source: "allow.json", Index: 1, Content: map[string]interface{}{
+			"Effect": "Allow", "Action": "ec2:RunInstances", "Resource": "arn:aws:ec2:*:*:instance/*",
+		}},
+		{Source: "deny.json", Index: 0, Content: map[string]interface{}{
+			"Effect": "Deny", "Action": "S3:Put*", "Resource": "*",
+		}},
+		{Source: "deny.json", Index: 1, Content: map[string]interface{}{
+			"Effect": "Deny", "Action": "ec2:*", "Resource": "arn:aws:s3:::logs/*",
+		}},
+		{Source: "deny.json", Index: 2, Content: map[string]interface{}{
+			"Effect": "Deny", "NotAction": "iam:*", "Resource": "*",
+		}},
+	}
+
+	conflicts := findConflicts(statements)
+
+	// the ec2 Allow and Deny share an action but not a resource, and NotAction is skipped
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d: %v", len(conflicts), conflicts)
+	}
+	conflict := conflicts[0]
+	if conflict.Allow.Source != "allow.json" || conflict.Allow.Index != 0 || conflict.Deny.Index != 0 {
+		t.Errorf("Expected allow.json[0] against deny.json[0], got %v", conflict)
+	}
+	message := conflict.String()
+	if !strings.Contains(message, "allow.json: Statement[0]") || !strings.Contains(message, "deny.json: Statement[0]") {
+		t.Errorf("Expected both statement indices in %s", message)
+	}
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// dedupeStatements drops statements equivalent to an earlier one, returning the rest and how many were removed
+func dedupeStatements(statements []Statement) ([]Statement, int) {
+	seen := make(map[string]bool)
+	var deduped []Statement
+	for _, stmt := range statements {
+		key := semanticKey(stmt.Content)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		deduped = append(deduped, stmt)
+	}
+	return deduped, len(statements) - len(deduped)
+}
+
+// semanticKey identifies a statement regardless of key order, element ordering or Sid
+func semanticKey(content map[string]interface{}) string {
+	canonical := make(map[string]interface{}, len(content))
+	for k, v := range content {
+		switch k {
+		case "Sid":
+			continue
+		case "Action", "NotAction", "Resource", "NotResource":
+			canonical[k] = canonicalElements(v)
+		default:
+			canonical[k] = v
+		}
+	}
+	key, _ := json.Marshal(canonical) // map keys are marshaled in sorted order
+	return string(key)
+}
+
+// canonicalElements returns each entry of a value or list as JSON, sorted. A single value and a one element
+// list mean the same, and entries that are not strings still tell statements apart.
+func canonicalElements(value interface{}) []string {
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	case []string:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	default:
+		items = []interface{}{v}
+	}
+	values := make([]string, len(items))
+	for i, item := range items {
+		data, _ := json.Marshal(item)
+		values[i] = string(data)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// filePolicies records the policy each file holds as the files are read, to report files holding the same one
+type filePolicies struct {
+	files  map[[sha256.Size]byte][]string
+	hashes [][sha256.Size]byte // in the order each policy is first seen
+}
+
+// add records the policies of the files from the statements read from them, matched by Source. Files
+// without a header were never read, and those without statements are left out.
+func (p *filePolicies) add(files []string, fileHeaders map[string]Header, statements []Statement) {
+	keys := make(map[string][]string)
+	for _, stmt := range statements {
+		keys[stmt.Source] = append(keys[stmt.Source], semanticKey(stmt.Content))
+	}
+	if p.files == nil {
+		p.files = make(map[[sha256.Size]byte][]string)
+	}
+	for _, file := range files {
+		header, ok := fileHeaders[file]
+		if !ok || len(keys[file]) == 0 {
+			continue
+		}
+		sort.Strings(keys[file])
+		hash := sha256.Sum256([]byte(header.Version + "\n" + header.Id + "\n" + strings.Join(keys[file], "\n")))
+		if _, ok := p.files[hash]; !ok {
+			p.hashes = append(p.hashes, hash)
+		}
+		p.files[hash] = append(p.files[hash], file)
+	}
+}
+
+// duplicates groups the files whose policies match, ignoring formatting, key order, Sids and statement order
+func (p *filePolicies) duplicates() [][]string {
+	var duplicates [][]string
+	for _, hash := range p.hashes {
+		if len(p.files[hash]) > 1 {
+			duplicates = append(duplicates, p.files[hash])
+		}
+	}
+	return duplicates
+}
+
+// reportDuplicateFiles warns about input files holding the same policy as another, often copied between accounts
+func reportDuplicateFiles(policies *filePolicies) {
+	for _, group := range policies.duplicates() {
+		slog.Warn("files hold the same policy, remove all but one", "files", strings.Join(group, ", "))
+	}
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDedupeStatements(t *testing.T) {
+	statements := []Statement{
+		newStatement(map[string]interface{}{"Sid": "First", "Effect": "Deny", "Action": []interface{}{"s3:GetObject", "s3:PutObject"}, "Resource": "*"}),
+		newStatement(map[string]interface{}{"Resource": "*", "Action": []interface{}{"s3:PutObject", "s3:GetObject"}, "Effect": "Deny"}),
+		newStatement(map[string]interface{}{"Effect": "Deny", "Action": "ec2:*", "Resource": []interface{}{"*"}}),
+		newStatement(map[string]interface{}{"Effect": "Deny", "Action": []interface{}{"ec2:*"}, "Resource": "*"}),
+		newStatement(map[string]interface{}{"Effect": "Allow", "Action": []interface{}{"s3:GetObject", "s3:PutObject"}, "Resource": "*"}),
+	}
+
+	deduped, removed := dedupeStatements(statements)
+
+	if removed != 2 {
+		t.Errorf("Expected 2 removed, got %d", removed)
+	}
+	if len(deduped) != 3 {
+		t.Fatalf("Expected 3 statements, got %d", len(deduped))
+	}
+	if deduped[0].Content["Sid"] != "First" {
+		t.Errorf("Expected the first of the equivalent statements to be kept, got %v", deduped[0].Content)
+	}
+	// a different Effect survives
+	if deduped[2].Content["Effect"] != "Allow" {
+		t.Errorf("Expected the Allow statement to survive, got %v", deduped[2].Content)
+	}
+}
+
+func TestSemanticKeyNonStringEntries(t *testing.T) {
+	five := map[string]interface{}{"Effect": "Deny", "Action": []interface{}{"s3:*", 5.0}, "Resource": "*"}
+	six := map[string]interface{}{"Effect": "Deny", "Action": []interface{}{"s3:*", 6.0}, "Resource": "*"}
+	quoted := map[string]interface{}{"Effect": "Deny", "Action": []interface{}{"s3:*", "5"}, "Resource": "*"}
+	if semanticKey(five) == semanticKey(six) || semanticKey(five) == semanticKey(quoted) {
+		t.Errorf("Expected statements differing in an entry that is not a string to differ, got %s", semanticKey(five))
+	}
+	reordered := map[string]interface{}{"Effect": "Deny", "Action": []interface{}{5.0, "s3:*"}, "Resource": []interface{}{"*"}}
+	if semanticKey(five) != semanticKey(reordered) {
+		t.Errorf("Expected entry order and a one element list to be ignored, got %s and %s", semanticKey(five), semanticKey(reordered))
+	}
+}
+
+func TestReportDuplicateFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"account-a.json": `{"Version": "2012-10-17", "Statement": [{"Sid": "DenyS3", "Effect": "Deny", "Action": "s3:*", "Resource": "*"}, {"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}]}`,
+		"account-b.json": `{"Version": "2012-10-17", "Statement": [{"Sid": "DenyS3", "Effect": "Deny", "Action": "s3:*", "Resource": "*"}, {"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}]}`,
+		// reformatted and reordered, without the Sid
+		"account-c.json": "{\n  \"Statement\": [\n    {\"Resource\": \"*\", \"Action\": [\"ec2:*\"], \"Effect\": \"Deny\"},\n    {\"Effect\": \"Deny\", \"Action\": \"s3:*\", \"Resource\": \"*\"}\n  ],\n  \"Version\": \"2012-10-17\"\n}",
+		"other.json":     `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`,
+		"empty-1.json":   `{"Version": "2012-10-17", "Statement": []}`,
+		"empty-2.json":   `{"Version": "2012-10-17", "Statement": []}`,
+	}
+	var paths []string
+	for _, name := range []string{"account-a.json", "account-b.json", "account-c.json", "other.json", "empty-1.json", "empty-2.json"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	logs := captureLogs(t, slog.LevelInfo)
+	statements, _, fileHeaders, _ := extractWithProgress(paths, nil)
+	policies := &filePolicies{}
+	policies.add(paths, fileHeaders, statements)
+	reportDuplicateFiles(policies)
+
+	expected := fmt.Sprintf(`Warning: files hold the same policy, remove all but one files="%s, %s, %s"`, paths[0], paths[1], paths[2])
+	if !strings.Contains(logs.String(), expected) || strings.Count(logs.String(), "Warning") != 1 {
+		t.Errorf("Expected only the warning %q, got %q", expected, logs.String())
+	}
+}
@@ -1,34 +1,395 @@
 package core
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+	"gopkg.in/yaml.v3"
 )
 
-func extractAllStatements(files []string) []Statement {
-	var allStatements []Statement
+// utf8BOM is the byte-order mark some Windows editors write at the start of a file
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// progressThreshold is the number of files before reading them shows progress
+const progressThreshold = 20
+
+// progressWriter returns stderr when a progress counter is worth showing, or nil.
+// It is kept off stdout so --json output stays clean.
+func progressWriter(userInput inputs.UserInput, files []string) io.Writer {
+	if userInput.Quiet || len(files) < progressThreshold || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return os.Stderr
+}
+
+// FileError is an input file that could not be read or parsed
+type FileError struct {
+	File string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// FileErrors are the files --continue-on-error carried on past, reported together at the end of a run
+type FileErrors []*FileError
+
+func (e FileErrors) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d files failed:", len(e))
+	for _, err := range e {
+		fmt.Fprintf(&b, "\n- %v", err)
+	}
+	return b.String()
+}
+
+func (e FileErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// err returns the failures as an error, nil when there are none
+func (e FileErrors) err() error {
+	return e.or(nil)
+}
+
+// or returns the failures as an error, or err when there are none
+func (e FileErrors) or(err error) error {
+	if len(e) == 0 {
+		return err
+	}
+	return e
+}
+
+// exclude returns the files that did not fail, in order
+func (e FileErrors) exclude(files []string) []string {
+	failed := make(map[string]bool, len(e))
+	for _, err := range e {
+		failed[err.File] = true
+	}
+	var remaining []string
 	for _, file := range files {
-		statements := extractIndividualStatements(file)
+		if !failed[file] {
+			remaining = append(remaining, file)
+		}
+	}
+	return remaining
+}
+
+// readFiles extracts the statements of the files, with the merged header and each file's own. The first
+// file that can't be read or parsed is returned as an error, unless --continue-on-error leaves it out and
+// returns it among the failures.
+func readFiles(userInput inputs.UserInput, files []string) ([]Statement, Header, map[string]Header, FileErrors, error) {
+	allStatements, header, fileHeaders, failed := extractWithProgress(files, progressWriter(userInput, files))
+	if len(failed) > 0 && !userInput.ContinueOnErr {
+		return nil, Header{}, nil, nil, failed[0]
+	}
+	return allStatements, header, fileHeaders, failed, nil
+}
+
+func extractAllStatements(files []string) ([]Statement, Header) {
+	allStatements, header, _, _ := extractWithProgress(files, nil)
+	return allStatements, header
+}
+
+// extractWithProgress writes a counter to progress as each file is read, a nil progress writes nothing.
+// Files that can't be read or parsed add no statements and no header, and are returned as failures.
+func extractWithProgress(files []string, progress io.Writer) ([]Statement, Header, map[string]Header, FileErrors) {
+	var allStatements []Statement
+	var header Header
+	fileHeaders := make(map[string]Header)
+	var failed FileErrors
+	var versions []string
+	versionFiles := make(map[string][]string)
+	for i, file := range files {
+		if progress != nil {
+			fmt.Fprintf(progress, "\rReading %d/%d", i+1, len(files))
+		}
+		statements, fileHeader, err := readStatements(file)
+		if err != nil {
+			slog.Debug("failed to read policy file", "file", file, "error", err)
+			failed = append(failed, &FileError{File: file, Err: err})
+			continue
+		}
+		slog.Debug("read policy file", "file", file, "statements", len(statements), "version", fileHeader.Version)
 		allStatements = append(allStatements, statements...)
+		fileHeaders[file] = fileHeader
+
+		if version := fileHeader.Version; version != "" {
+			if _, ok := versionFiles[version]; !ok {
+				versions = append(versions, version)
+			}
+			versionFiles[version] = append(versionFiles[version], file)
+		}
+		// first declared value wins, warn on disagreement
+		header.Id = mergeHeaderField(file, "Id", header.Id, fileHeader.Id)
+	}
+	if progress != nil && len(files) > 0 {
+		fmt.Fprintln(progress)
 	}
-	return allStatements
+
+	header.Version = resolveVersion(versions)
+	if len(versions) > 1 {
+		declared := make([]string, len(versions))
+		for i, version := range versions {
+			declared[i] = fmt.Sprintf("%s (%s)", version, strings.Join(versionFiles[version], ", "))
+		}
+		slog.Warn("policies declare different Versions", "declared", strings.Join(declared, ", "), "using", header.Version)
+	}
+	return allStatements, header, fileHeaders, failed
+}
+
+func extractIndividualStatements(filename string) ([]Statement, Header) {
+	statements, header, _ := readStatements(filename)
+	return statements, header
+}
+
+// readStatements reads the statements of a policy file, with an error when it can't be read or parsed
+func readStatements(filename string) ([]Statement, Header, error) {
+	// plain JSON is streamed, other formats and archive entries are read whole
+	if _, _, inArchive := splitArchivePath(filename); !inArchive && !isYAMLFile(filename) && !isJSONLFile(filename) && !isJSONCFile(filename) {
+		return streamPolicyFile(filename)
+	}
+	data, err := ReadPolicyFile(filename)
+	if err != nil {
+		return nil, Header{}, err
+	}
+	return decodeStatements(filename, data)
+}
+
+// parseStatements reads the statements of a policy, in the format its filename indicates.
+// A policy that can't be parsed has no statements.
+func parseStatements(filename string, data []byte) ([]Statement, Header) {
+	statements, header, _ := decodeStatements(filename, data)
+	return statements, header
 }
 
-func extractIndividualStatements(filename string) []Statement {
-	data, _ := os.ReadFile(filename)
+// decodeStatements is parseStatements, also returning the error when the policy can't be parsed or
+// an element of its Statement is not an object. An empty file is not an error, it has no statements.
+func decodeStatements(filename string, data []byte) ([]Statement, Header, error) {
+	// json.Unmarshal rejects a leading BOM
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, Header{}, nil
+	}
+
+	var statements []Statement
+	if isYAMLFile(filename) {
+		var policy Policy
+		err := yaml.Unmarshal(data, &policy)
+		for i, stmt := range policy.Statement {
+			statements = append(statements, buildStatement(filename, i, stmt, nil))
+		}
+		return statements, Header{Version: policy.Version, Id: policy.Id}, err
+	}
+
+	if isJSONLFile(filename) {
+		statements, err := extractLineStatements(filename, data)
+		return statements, Header{}, err
+	}
 
-	var policy Policy
-	json.Unmarshal(data, &policy)
+	if isJSONCFile(filename) {
+		data = stripComments(data)
+	}
+
+	// keep each statement's raw JSON so it can be written back verbatim
+	var policy rawPolicy
+	err := json.Unmarshal(data, &policy)
+	for i, raw := range policy.Statement {
+		content, contentErr := decodeContent(raw)
+		if contentErr != nil {
+			// an element dropped here would be a Deny silently missing from the outputs
+			if err == nil {
+				err = fmt.Errorf("Statement[%d] is not a statement object", i)
+			}
+			continue
+		}
+		statements = append(statements, buildStatement(filename, i, content, raw))
+	}
 
+	return statements, Header{Version: policy.Version, Id: policy.Id}, err
+}
+
+// extractLineStatements reads newline-delimited JSON, one statement object per line. A line that is not
+// a statement object is an error naming the first, as a dropped element is for a JSON policy.
+func extractLineStatements(filename string, data []byte) ([]Statement, error) {
 	var statements []Statement
-	for _, stmt := range policy.Statement {
-		stmtJSON, _ := json.Marshal(stmt)
+	var err error
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		content, lineErr := decodeContent(line)
+		if lineErr != nil {
+			if err == nil {
+				err = fmt.Errorf("line %d is not a statement object: %w", i+1, lineErr)
+			}
+			continue
+		}
+		statements = append(statements, buildStatement(filename, len(statements), content, line))
+	}
+	return statements, err
+}
 
-		statements = append(statements, Statement{
-			Content: stmt,
-			Size:    len(stmtJSON),
-		})
+// decodeContent unmarshals a statement object, keeping numbers as json.Number so a large
+// integer re-encodes exactly as written rather than through float64
+func decodeContent(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var content map[string]interface{}
+	if err := decoder.Decode(&content); err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, errors.New("null is not a statement object")
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid character after top-level value")
 	}
+	return content, nil
+}
+
+// ReadPolicyFile returns a file's contents, decompressing gzip so sizes reflect the uncompressed policy
+func ReadPolicyFile(filename string) ([]byte, error) {
+	var data []byte
+	var err error
+	if archive, entry, ok := splitArchivePath(filename); ok {
+		data, err = readZipEntry(archive, entry)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !isGzipFile(filename) && !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// buildStatement normalizes content, keeping the raw JSON only when normalization left it unchanged
+func buildStatement(filename string, index int, content map[string]interface{}, raw json.RawMessage) Statement {
+	var statement Statement
+	if normalizeStatement(content) || raw == nil {
+		statement = newStatement(content)
+	} else {
+		statement = newRawStatement(content, raw)
+	}
+	statement.Source = filename
+	statement.Index = index
+	return statement
+}
 
-	return statements
+// newStatement wraps statement content with its minified size, counted in characters as AWS does
+func newStatement(content map[string]interface{}) Statement {
+	raw, _ := orderedStatement(content).MarshalJSON()
+	return newRawStatement(content, raw)
+}
+
+// newRawStatement wraps statement content with its original JSON, minified but otherwise untouched
+func newRawStatement(content map[string]interface{}, raw json.RawMessage) Statement {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return newStatement(content)
+	}
+	return Statement{
+		Content: content,
+		Raw:     buf.Bytes(),
+		Size:    utf8.RuneCount(buf.Bytes()),
+	}
+}
+
+// UnmarshalJSON accepts Statement as either an array or a single statement object
+func (p *rawPolicy) UnmarshalJSON(data []byte) error {
+	var document struct {
+		Version   string          `json:"Version"`
+		Id        string          `json:"Id"`
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	p.Version, p.Id = document.Version, document.Id
+
+	statement := bytes.TrimSpace(document.Statement)
+	if len(statement) == 0 {
+		return nil
+	}
+	if statement[0] == '{' {
+		p.Statement = []json.RawMessage{statement}
+		return nil
+	}
+	return json.Unmarshal(statement, &p.Statement)
+}
+
+// UnmarshalYAML accepts Statement as either a sequence or a single statement mapping
+func (p *Policy) UnmarshalYAML(value *yaml.Node) error {
+	var document struct {
+		Version   string    `yaml:"Version"`
+		Id        string    `yaml:"Id"`
+		Statement yaml.Node `yaml:"Statement"`
+	}
+	if err := value.Decode(&document); err != nil {
+		return err
+	}
+	p.Version, p.Id = document.Version, document.Id
+
+	switch document.Statement.Kind {
+	case yaml.MappingNode:
+		var statement map[string]interface{}
+		if err := document.Statement.Decode(&statement); err != nil {
+			return err
+		}
+		p.Statement = []map[string]interface{}{statement}
+	case yaml.SequenceNode:
+		return document.Statement.Decode(&p.Statement)
+	}
+	return nil
+}
+
+// resolveVersion keeps the Version the policies agree on, falling back to the default when none is
+// declared or they disagree, as no one of them is safe to force on the others
+func resolveVersion(versions []string) string {
+	if len(versions) == 1 {
+		return versions[0]
+	}
+	return config.SCPVersion
+}
+
+func mergeHeaderField(file, field, current, declared string) string {
+	if declared == "" {
+		return current
+	}
+	if current == "" {
+		return declared
+	}
+	if declared != current {
+		slog.Warn("policy declares a different "+field, "file", file, "declared", declared, "using", current)
+	}
+	return current
 }
@@ -1,10 +1,19 @@
 package core
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
 )
 
 func TestExtractIndividualStatements(t *testing.T) {
@@ -94,7 +103,7 @@ func TestExtractIndividualStatements(t *testing.T) {
 			}
 
 			// Test the function
-			statements := extractIndividualStatements(testFile)
+			statements, _ := extractIndividualStatements(testFile)
 
 			if len(statements) != tt.expectedStatements {
 				t.Errorf("Expected %d statements, got %d", tt.expectedStatements, len(statements))
@@ -196,7 +205,7 @@ func TestExtractAllStatements(t *testing.T) {
 			}
 
 			// Test the function
-			statements := extractAllStatements(files)
+			statements, _ := extractAllStatements(files)
 
 			if len(statements) != tt.expectedTotal {
 				t.Errorf("Expected %d total statements, got %d", tt.expectedTotal, len(statements))
@@ -254,7 +263,7 @@ func TestExtractIndividualStatementsInvalidFile(t *testing.T) {
 			}
 
 			// Should not panic, should handle gracefully
-			statements := extractIndividualStatements(testFile)
+			statements, _ := extractIndividualStatements(testFile)
 
 			if len(statements) != tt.expected {
 				t.Errorf("Expected %d statements, got %d", tt.expected, len(statements))
@@ -263,6 +272,36 @@ func TestExtractIndividualStatementsInvalidFile(t *testing.T) {
 	}
 }
 
+func TestReadStatementsNonObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+	}{
+		{name: "string and number", filename: "mixed.json", content: `{"Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}, "oops", 42]}`},
+		{name: "null", filename: "null.jsonc", content: `{"Statement": [null, {"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`},
+		{name: "yaml scalar", filename: "mixed.yaml", content: "Statement:\n  - Effect: Deny\n    Action: s3:*\n  - oops\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), tt.filename)
+			if err := os.WriteFile(filename, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+			data, _ := ReadPolicyFile(filename)
+			if _, _, err := decodeStatements(filename, data); err == nil {
+				t.Errorf("Expected an error for an element that is not a statement object")
+			}
+		})
+	}
+
+	data := []byte(`{"Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}, "oops"]}`)
+	if _, _, err := decodeStatements("policy.json", data); err == nil || err.Error() != "Statement[1] is not a statement object" {
+		t.Errorf("Expected the element named, got %v", err)
+	}
+}
+
 // Helper function to compare maps - simplified for testing
 func mapsEqual(a, b map[string]interface{}) bool {
 	if len(a) != len(b) {
@@ -279,3 +318,502 @@ func mapsEqual(a, b map[string]interface{}) bool {
 
 	return true
 }
+
+func TestExtractWithProgress(t *testing.T) {
+	tempDir := t.TempDir()
+	var files []string
+	for i := 0; i < 3; i++ {
+		file := filepath.Join(tempDir, fmt.Sprintf("policy%d.json", i))
+		if err := os.WriteFile(file, []byte(`{"Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		files = append(files, file)
+	}
+
+	var progress bytes.Buffer
+	statements, _, _, _ := extractWithProgress(files, &progress)
+	if len(statements) != 3 {
+		t.Fatalf("Expected 3 statements, got %d", len(statements))
+	}
+
+	// one update per file, then a newline to finish the line
+	if updates := strings.Count(progress.String(), "\r"); updates != len(files) {
+		t.Errorf("Expected %d progress updates, got %d: %q", len(files), updates, progress.String())
+	}
+	if !strings.HasSuffix(progress.String(), "Reading 3/3\n") {
+		t.Errorf("Expected progress to end at 3/3, got %q", progress.String())
+	}
+
+	// progress is never shown for a handful of files or under --quiet
+	if progressWriter(inputs.UserInput{}, files) != nil {
+		t.Error("Expected no progress for 3 files")
+	}
+	if progressWriter(inputs.UserInput{Quiet: true}, make([]string, progressThreshold)) != nil {
+		t.Error("Expected no progress with --quiet")
+	}
+}
+
+func TestExtractAllStatementsVersion(t *testing.T) {
+	tests := []struct {
+		name            string
+		contents        []string
+		expectedVersion string
+	}{
+		{
+			name:            "version preserved",
+			contents:        []string{`{"Version": "2008-10-17", "Statement": [{"Effect": "Deny"}]}`},
+			expectedVersion: "2008-10-17",
+		},
+		{
+			name:            "missing version falls back to default",
+			contents:        []string{`{"Statement": [{"Effect": "Deny"}]}`},
+			expectedVersion: config.SCPVersion,
+		},
+		{
+			name: "matching versions preserved",
+			contents: []string{
+				`{"Version": "2008-10-17", "Statement": [{"Effect": "Deny"}]}`,
+				`{"Statement": [{"Effect": "Deny"}]}`,
+				`{"Version": "2008-10-17", "Statement": [{"Effect": "Allow"}]}`,
+			},
+			expectedVersion: "2008-10-17",
+		},
+		{
+			name: "mismatched versions fall back to default",
+			contents: []string{
+				`{"Version": "2008-10-17", "Statement": [{"Effect": "Deny"}]}`,
+				`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow"}]}`,
+			},
+			expectedVersion: config.SCPVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			var files []string
+			for i, content := range tt.contents {
+				testFile := filepath.Join(tempDir, "policy-"+string(rune('a'+i))+".json")
+				err := os.WriteFile(testFile, []byte(content), 0644)
+				if err != nil {
+					t.Fatalf("Failed to write test file %d: %v", i, err)
+				}
+				files = append(files, testFile)
+			}
+
+			_, header := extractAllStatements(files)
+
+			if header.Version != tt.expectedVersion {
+				t.Errorf("Expected version %s, got %s", tt.expectedVersion, header.Version)
+			}
+		})
+	}
+}
+
+func TestExtractAllStatementsVersionWarning(t *testing.T) {
+	tempDir := t.TempDir()
+	contents := map[string]string{
+		"a.json": `{"Version": "2008-10-17", "Statement": [{"Effect": "Deny"}]}`,
+		"b.json": `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny"}]}`,
+		"c.json": `{"Version": "2008-10-17", "Statement": [{"Effect": "Allow"}]}`,
+	}
+	var files []string
+	for _, name := range []string{"a.json", "b.json", "c.json"} {
+		testFile := filepath.Join(tempDir, name)
+		if err := os.WriteFile(testFile, []byte(contents[name]), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		files = append(files, testFile)
+	}
+
+	logs := captureLogs(t, slog.LevelInfo)
+	_, header := extractAllStatements(files)
+
+	if header.Version != config.SCPVersion {
+		t.Errorf("Expected the default version %s, got %s", config.SCPVersion, header.Version)
+	}
+	expected := fmt.Sprintf(`Warning: policies declare different Versions declared="2008-10-17 (%s, %s), 2012-10-17 (%s)" using=%s`,
+		files[0], files[2], files[1], config.SCPVersion)
+	if !strings.Contains(logs.String(), expected) {
+		t.Errorf("Expected %q, got %q", expected, logs.String())
+	}
+}
+
+func TestExtractAllStatementsId(t *testing.T) {
+	tests := []struct {
+		name       string
+		contents   []string
+		expectedId string
+	}{
+		{
+			name:       "id preserved",
+			contents:   []string{`{"Version": "2012-10-17", "Id": "GuardRails", "Statement": [{"Effect": "Deny"}]}`},
+			expectedId: "GuardRails",
+		},
+		{
+			name:       "missing id",
+			contents:   []string{`{"Version": "2012-10-17", "Statement": [{"Effect": "Deny"}]}`},
+			expectedId: "",
+		},
+		{
+			name: "multiple ids keep first",
+			contents: []string{
+				`{"Version": "2012-10-17", "Statement": [{"Effect": "Deny"}]}`,
+				`{"Version": "2012-10-17", "Id": "First", "Statement": [{"Effect": "Deny"}]}`,
+				`{"Version": "2012-10-17", "Id": "Second", "Statement": [{"Effect": "Allow"}]}`,
+			},
+			expectedId: "First",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			var files []string
+			for i, content := range tt.contents {
+				testFile := filepath.Join(tempDir, "policy-"+string(rune('a'+i))+".json")
+				err := os.WriteFile(testFile, []byte(content), 0644)
+				if err != nil {
+					t.Fatalf("Failed to write test file %d: %v", i, err)
+				}
+				files = append(files, testFile)
+			}
+
+			_, header := extractAllStatements(files)
+
+			if header.Id != tt.expectedId {
+				t.Errorf("Expected Id %q, got %q", tt.expectedId, header.Id)
+			}
+		})
+	}
+}
+
+func TestExtractIndividualStatementsYAML(t *testing.T) {
+	content := `Version: "2012-10-17"
+Statement:
+  - Sid: DenyLeaveOrg
+    Effect: Deny
+    Action: organizations:LeaveOrganization
+    Resource: "*"
+  - Sid: DenyRootUser
+    Effect: Deny
+    Action: "*"
+    Resource: "*"
+    Condition:
+      StringLike:
+        aws:PrincipalArn: arn:aws:iam::*:root
+  - Effect: Deny
+    Action:
+      - s3:DeleteBucket
+      - s3:PutBucketPolicy
+    Resource: "*"
+`
+
+	for _, ext := range []string{".yaml", ".yml"} {
+		t.Run(ext, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "policy"+ext)
+			err := os.WriteFile(testFile, []byte(content), 0644)
+			if err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			statements, header := extractIndividualStatements(testFile)
+
+			if len(statements) != 3 {
+				t.Fatalf("Expected 3 statements, got %d", len(statements))
+			}
+			if header.Version != "2012-10-17" {
+				t.Errorf("Expected version 2012-10-17, got %s", header.Version)
+			}
+			if statements[0].Content["Sid"] != "DenyLeaveOrg" {
+				t.Errorf("Expected first Sid DenyLeaveOrg, got %v", statements[0].Content["Sid"])
+			}
+
+			// nested maps must marshal back to JSON
+			for i, stmt := range statements {
+				if _, err := json.Marshal(stmt.Content); err != nil {
+					t.Errorf("Statement %d cannot be marshaled to JSON: %v", i, err)
+				}
+				if stmt.Size <= 0 {
+					t.Errorf("Statement %d has invalid size: %d", i, stmt.Size)
+				}
+			}
+		})
+	}
+}
+
+func TestNewStatementCountsRunes(t *testing.T) {
+	content := map[string]interface{}{
+		"Sid":      "RefuserAccèsÉtéCafé",
+		"Effect":   "Deny",
+		"Action":   "s3:*",
+		"Resource": "arn:aws:s3:::données-équipe",
+	}
+
+	stmtJSON, err := json.Marshal(content)
+	if err != nil {
+		t.Fatalf("Failed to marshal statement: %v", err)
+	}
+
+	stmt := newStatement(content)
+
+	expected := len([]rune(string(stmtJSON)))
+	if stmt.Size != expected {
+		t.Errorf("Expected rune size %d, got %d", expected, stmt.Size)
+	}
+	if stmt.Size >= len(stmtJSON) {
+		t.Errorf("Expected rune size %d to be smaller than byte size %d", stmt.Size, len(stmtJSON))
+	}
+}
+
+func TestExtractIndividualStatementsPreservesRaw(t *testing.T) {
+	content := `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Resource": "*",
+      "Effect": "Deny",
+      "Condition": {"NumericGreaterThan": {"s3:max-keys": 1.50e2}},
+      "Action": "s3:ListBucket"
+    },
+    {
+      "Resource": "*",
+      "Action": ["s3:GetObject", "s3:GetObject"],
+      "Effect": "Deny"
+    }
+  ]
+}`
+	testFile := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	statements, header := extractIndividualStatements(testFile)
+	if len(statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(statements))
+	}
+
+	// unchanged statements keep their key order and number formatting
+	expected := `{"Resource":"*","Effect":"Deny","Condition":{"NumericGreaterThan":{"s3:max-keys":1.50e2}},"Action":"s3:ListBucket"}`
+	if string(statements[0].Raw) != expected {
+		t.Errorf("Expected raw %s, got %s", expected, statements[0].Raw)
+	}
+	if statements[0].Size != len(expected) {
+		t.Errorf("Expected size %d, got %d", len(expected), statements[0].Size)
+	}
+
+	// normalized statements are re-serialized in canonical order
+	expectedNormalized := `{"Effect":"Deny","Action":["s3:GetObject"],"Resource":"*"}`
+	if string(statements[1].Raw) != expectedNormalized {
+		t.Errorf("Expected raw %s, got %s", expectedNormalized, statements[1].Raw)
+	}
+
+	output := string(writeJSON(inputs.UserInput{}, header, statements))
+	if !strings.Contains(output, expected) {
+		t.Errorf("Expected output to contain the original statement verbatim, got %s", output)
+	}
+}
+
+func TestExtractIndividualStatementsSingleObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+	}{
+		{
+			name:     "json",
+			filename: "policy.json",
+			content:  `{"Version": "2012-10-17", "Statement": {"Effect": "Deny", "Action": "s3:*", "Resource": "*"}}`,
+		},
+		{
+			name:     "yaml",
+			filename: "policy.yaml",
+			content:  "Version: \"2012-10-17\"\nStatement:\n  Effect: Deny\n  Action: s3:*\n  Resource: \"*\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), tt.filename)
+			if err := os.WriteFile(testFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			statements, header := extractIndividualStatements(testFile)
+
+			if len(statements) != 1 {
+				t.Fatalf("Expected 1 statement, got %d", len(statements))
+			}
+			if statements[0].Content["Effect"] != "Deny" {
+				t.Errorf("Expected Effect Deny, got %v", statements[0].Content["Effect"])
+			}
+			if header.Version != "2012-10-17" {
+				t.Errorf("Expected version 2012-10-17, got %s", header.Version)
+			}
+
+			// output is always an array
+			output := string(writeJSON(inputs.UserInput{}, header, statements))
+			if !strings.Contains(output, `"Statement":[{`) {
+				t.Errorf("Expected Statement array in output, got %s", output)
+			}
+		})
+	}
+}
+
+func TestExtractIndividualStatementsGzip(t *testing.T) {
+	content := `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}, {"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}]}`
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write([]byte(content))
+	writer.Close()
+
+	tests := []struct {
+		name     string
+		filename string
+	}{
+		{name: "gz suffix", filename: "policy.json.gz"},
+		{name: "gzip header without suffix", filename: "policy.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), tt.filename)
+			if err := os.WriteFile(testFile, buf.Bytes(), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			statements, header := extractIndividualStatements(testFile)
+
+			if len(statements) != 2 {
+				t.Fatalf("Expected 2 statements, got %d", len(statements))
+			}
+			if header.Version != "2012-10-17" {
+				t.Errorf("Expected version 2012-10-17, got %s", header.Version)
+			}
+
+			// sizes are measured on the decompressed JSON
+			expected := len(`{"Effect":"Deny","Action":"s3:*","Resource":"*"}`)
+			if statements[0].Size != expected {
+				t.Errorf("Expected size %d, got %d", expected, statements[0].Size)
+			}
+			if total := totalFileSize([]string{testFile}); total != len(content) {
+				t.Errorf("Expected total size %d, got %d", len(content), total)
+			}
+		})
+	}
+}
+
+func TestExtractIndividualStatementsBOM(t *testing.T) {
+	content := "\xEF\xBB\xBF" + `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`
+	testFile := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	statements, header := extractIndividualStatements(testFile)
+
+	if len(statements) != 1 {
+		t.Fatalf("Expected 1 statement, got %d", len(statements))
+	}
+	if header.Version != "2012-10-17" {
+		t.Errorf("Expected version 2012-10-17, got %s", header.Version)
+	}
+}
+
+func TestExtractIndividualStatementsJSONL(t *testing.T) {
+	content := `{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}
+
+{"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}
+{"Effect": "Deny", "Action": "iam:*", "Resource": "*"}
+`
+	testFile := filepath.Join(t.TempDir(), "statements.jsonl")
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	statements, header := extractIndividualStatements(testFile)
+
+	if len(statements) != 3 {
+		t.Fatalf("Expected 3 statements, got %d", len(statements))
+	}
+	for i, expected := range []string{"s3:*", "ec2:*", "iam:*"} {
+		if statements[i].Content["Action"] != expected {
+			t.Errorf("Statement %d: expected Action %s, got %v", i, expected, statements[i].Content["Action"])
+		}
+		if statements[i].Index != i {
+			t.Errorf("Statement %d: expected index %d, got %d", i, i, statements[i].Index)
+		}
+	}
+	if header.Version != "" {
+		t.Errorf("Expected no version from JSONL, got %s", header.Version)
+	}
+}
+
+func TestExtractLineStatementsMalformed(t *testing.T) {
+	data := []byte("{\"Effect\": \"Deny\", \"Action\": \"s3:*\", \"Resource\": \"*\"}\n{\"Effect\": \n{\"Effect\": \"Deny\", \"Action\": \"ec2:*\", \"Resource\": \"*\"}\n")
+	statements, err := extractLineStatements("statements.jsonl", data)
+	if err == nil || !strings.Contains(err.Error(), "line 2 is not a statement object") {
+		t.Errorf("Expected an error naming line 2, got %v", err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("Expected the 2 other statements, got %d", len(statements))
+	}
+
+	// the file fails as a whole, unless --continue-on-error leaves it out
+	dir := t.TempDir()
+	malformed, valid := filepath.Join(dir, "statements.jsonl"), filepath.Join(dir, "policy.json")
+	if err := os.WriteFile(malformed, data, 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(valid, []byte(`{"Statement": [{"Effect": "Deny", "Action": "iam:*", "Resource": "*"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	var fileErr *FileError
+	if _, _, _, _, err := readFiles(inputs.UserInput{}, []string{malformed, valid}); !errors.As(err, &fileErr) || fileErr.File != malformed {
+		t.Errorf("Expected %s to fail, got %v", malformed, err)
+	}
+	read, _, _, failed, err := readFiles(inputs.UserInput{ContinueOnErr: true}, []string{malformed, valid})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(read) != 1 || len(failed) != 1 || failed[0].File != malformed {
+		t.Errorf("Expected the valid file's statement and %s failed, got %d statements and %v", malformed, len(read), failed)
+	}
+}
+
+func TestExtractIndividualStatementsLargeNumbers(t *testing.T) {
+	// the single-element Action array is collapsed with --collapse-arrays, so the statement is re-encoded rather than kept verbatim
+	policy := `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":["s3:*"],"Resource":"*",` +
+		`"Condition":{"NumericLessThan":{"aws:EpochTime":9007199254740993,"aws:MultiFactorAuthAge":3600.50}}}]}`
+	expected := `{"Effect":"Deny","Action":"s3:*","Resource":"*",` +
+		`"Condition":{"NumericLessThan":{"aws:EpochTime":9007199254740993,"aws:MultiFactorAuthAge":3600.50}}}`
+
+	for _, name := range []string{"policy.json", "policy.jsonl", "policy.jsonc"} {
+		t.Run(name, func(t *testing.T) {
+			data := policy
+			if name == "policy.jsonl" {
+				data = `{"Effect":"Deny","Action":["s3:*"],"Resource":"*",` +
+					`"Condition":{"NumericLessThan":{"aws:EpochTime":9007199254740993,"aws:MultiFactorAuthAge":3600.50}}}`
+			}
+			filename := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			statements, _ := extractIndividualStatements(filename)
+			statements, _ = transformStatements(inputs.UserInput{Collapse: true}, statements)
+			if len(statements) != 1 {
+				t.Fatalf("Expected 1 statement, got %d", len(statements))
+			}
+			if string(statements[0].Raw) != expected {
+				t.Errorf("Expected %s, got %s", expected, statements[0].Raw)
+			}
+
+			output := writeJSON(inputs.UserInput{}, Header{Version: config.SCPVersion}, statements)
+			if !bytes.Contains(output, []byte(expected)) {
+				t.Errorf("Expected the output to contain %s, got %s", expected, output)
+			}
+		})
+	}
+}
@@ -2,17 +2,193 @@ package core
 
 import (
 	"io/fs"
+	"log/slog"
+	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
 )
 
-func FindJSONFilesInDirectory(dir string) []string {
+// ResolveFiles expands the user's targets into policy files, walking any directories
+func ResolveFiles(userInput inputs.UserInput) []string {
+	var files []string
+	for _, target := range userInput.Targets {
+		if info, err := os.Stat(target); err == nil && info.IsDir() {
+			files = append(files, FindJSONFilesInDirectory(userInput, target)...)
+			continue
+		}
+		if isZipFile(target) {
+			entries, err := zipEntries(target)
+			if err != nil {
+				slog.Warn("could not read the archive", "file", target, "error", err)
+			}
+			files = append(files, entries...)
+			continue
+		}
+		files = append(files, target)
+	}
+	return files
+}
+
+// FindJSONFilesInDirectory returns the absolute paths of the policy files under dir, however dir is given,
+// sorted so the statements they hold are read, and packed, in the same order on every platform
+func FindJSONFilesInDirectory(userInput inputs.UserInput, dir string) []string {
+	dir = absPath(dir)
+	patterns, err := loadIgnoreFile(filepath.Join(dir, config.IgnoreFilename))
+	if err != nil {
+		slog.Warn(err.Error())
+	}
+	walk := directoryWalk{userInput: userInput, root: dir, ignore: patterns, visited: map[string]bool{}}
+	files := walk.findPolicyFiles(dir)
+	sort.Strings(files)
+	return files
+}
+
+// directoryWalk holds the state shared by the walks of a target and the symlinked directories within it
+type directoryWalk struct {
+	userInput inputs.UserInput
+	root      string
+	ignore    []ignorePattern // from the root's .corsetignore
+	visited   map[string]bool
+}
+
+// findPolicyFiles walks dir, descending into symlinked directories only with --follow-symlinks.
+// Directories are tracked by their resolved path, so a link back up the tree is walked once.
+func (w *directoryWalk) findPolicyFiles(dir string) []string {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil || w.visited[realDir] {
+		return nil
+	}
+	w.visited[realDir] = true
+
 	var jsonFiles []string
-	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
-		if !d.IsDir() && strings.HasSuffix(path, ".json") {
+	filepath.WalkDir(realDir, func(path string, d fs.DirEntry, err error) error {
+		// report paths under dir, as given, rather than where its links resolve
+		rel, _ := filepath.Rel(realDir, path)
+		path = filepath.Join(dir, rel)
+
+		// dotfiles and directories such as .git and .terraform are rarely meant as input
+		if rel != "." && !w.userInput.IncludeHidden && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rootRel, _ := filepath.Rel(w.root, path); rootRel != "." && isIgnored(w.ignore, rootRel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			// only list direct children of the target when not recursing
+			if w.userInput.NoRecurse && rel != "." {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				if w.userInput.FollowLinks && !w.userInput.NoRecurse {
+					jsonFiles = append(jsonFiles, w.findPolicyFiles(path)...)
+				}
+				return nil
+			}
+		}
+		// corset's own outputs are never read back in on a later run, which would duplicate their
+		// statements and, once the inputs are replaced, delete the outputs just written. A directory's
+		// <dir>.json is read, as the inputs it replaced are gone and it is the only copy of their statements
+		if isGeneratedOutput(path) {
+			return nil
+		}
+		if isPolicyFile(path) && filepath.Base(path) != config.ManifestFilename {
 			jsonFiles = append(jsonFiles, path)
 		}
 		return nil
 	})
 	return jsonFiles
 }
+
+// absPath returns the absolute form of a path, or the path cleaned when the working directory is unknown
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// gzipMagic is the header that starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// isPolicyFile reports whether a path has a supported policy extension
+func isPolicyFile(path string) bool {
+	return strings.HasSuffix(path, ".json") || strings.HasSuffix(path, ".json.gz") || isJSONCFile(path) || isJSONLFile(path) || isYAMLFile(path)
+}
+
+// isJSONLFile reports whether a path is newline-delimited JSON, one statement per line
+func isJSONLFile(path string) bool {
+	return strings.HasSuffix(path, ".jsonl")
+}
+
+// isJSONCFile reports whether a path is JSON with comments
+func isJSONCFile(path string) bool {
+	return strings.HasSuffix(path, ".jsonc")
+}
+
+func isGzipFile(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+func isYAMLFile(path string) bool {
+	return strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")
+}
+
+// isGeneratedOutput reports whether a path uses corset's fallback or suffixed output naming, gzipped or not:
+// corset.json, corset<N>.json, corset-<N>.json, or <base>_corset.json and <base>_corset-<N>.json
+func isGeneratedOutput(path string) bool {
+	name, found := strings.CutSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".json")
+	if !found {
+		return false
+	}
+	if suffix, found := strings.CutPrefix(name, "corset"); found {
+		return suffix == "" || isFileNumber(suffix) || isFileNumber(strings.TrimPrefix(suffix, "-"))
+	}
+	if i := strings.LastIndex(name, config.CorsetSuffix); i > 0 {
+		suffix := name[i+len(config.CorsetSuffix):]
+		return suffix == "" || (strings.HasPrefix(suffix, "-") && isFileNumber(suffix[1:]))
+	}
+	return false
+}
+
+// isFileNumber reports whether s is the number of an output file, digits only
+func isFileNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isDirectoryOutput reports whether a path is named like the outputs of packing dir,
+// <dir>.json and <dir>-2.json onwards, directly within it
+func isDirectoryOutput(dir, path string) bool {
+	if filepath.Dir(path) != filepath.Clean(dir) {
+		return false
+	}
+	base := filepath.Base(dir)
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".json")
+	if name == base {
+		return true
+	}
+	suffix, found := strings.CutPrefix(name, base+"-")
+	index, err := strconv.Atoi(suffix)
+	return found && err == nil && index >= 2
+}
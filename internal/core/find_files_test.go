@@ -3,13 +3,19 @@ package core
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
+
+	"github.com/jakebark/corset/internal/inputs"
 )
 
 func TestFindJSONFilesInDirectory(t *testing.T) {
 	tests := []struct {
 		name          string
 		files         map[string]string // filename -> content
+		noRecurse     bool
+		includeHidden bool
 		expectedCount int
 	}{
 		{
@@ -18,7 +24,7 @@ func TestFindJSONFilesInDirectory(t *testing.T) {
 				"policy1.json": `{"Version": "2012-10-17", "Statement": []}`,
 				"policy2.json": `{"Version": "2012-10-17", "Statement": []}`,
 				"readme.txt":   "not json",
-				"config.yaml":  "also not json",
+				"config.toml":  "also not json",
 			},
 			expectedCount: 2,
 		},
@@ -26,10 +32,36 @@ func TestFindJSONFilesInDirectory(t *testing.T) {
 			name: "directory with no JSON files",
 			files: map[string]string{
 				"readme.txt":  "not json",
-				"config.yaml": "also not json",
+				"config.toml": "also not json",
 			},
 			expectedCount: 0,
 		},
+		{
+			name: "directory with YAML files",
+			files: map[string]string{
+				"policy1.yaml": "Version: 2012-10-17",
+				"policy2.yml":  "Version: 2012-10-17",
+				"policy3.json": `{"Version": "2012-10-17"}`,
+			},
+			expectedCount: 3,
+		},
+		{
+			name: "directory with JSONC files",
+			files: map[string]string{
+				"policy1.jsonc": "// commented\n{}",
+				"policy2.json":  `{"Version": "2012-10-17"}`,
+			},
+			expectedCount: 2,
+		},
+		{
+			name: "directory with gzip files",
+			files: map[string]string{
+				"policy1.json.gz": "compressed",
+				"policy2.json":    `{"Version": "2012-10-17"}`,
+				"archive.tar.gz":  "not a policy",
+			},
+			expectedCount: 2,
+		},
 		{
 			name:          "empty directory",
 			files:         map[string]string{},
@@ -43,6 +75,34 @@ func TestFindJSONFilesInDirectory(t *testing.T) {
 			},
 			expectedCount: 2,
 		},
+		{
+			name: "directory with nested JSON files, no recurse",
+			files: map[string]string{
+				"policy.json":        `{"Version": "2012-10-17"}`,
+				"subdir/nested.json": `{"Version": "2012-10-17"}`,
+			},
+			noRecurse:     true,
+			expectedCount: 1,
+		},
+		{
+			name: "directory with hidden files",
+			files: map[string]string{
+				"policy.json":         `{"Version": "2012-10-17"}`,
+				".hidden/policy.json": `{"Version": "2012-10-17"}`,
+				".draft.json":         `{"Version": "2012-10-17"}`,
+			},
+			expectedCount: 1,
+		},
+		{
+			name: "directory with hidden files, include hidden",
+			files: map[string]string{
+				"policy.json":         `{"Version": "2012-10-17"}`,
+				".hidden/policy.json": `{"Version": "2012-10-17"}`,
+				".draft.json":         `{"Version": "2012-10-17"}`,
+			},
+			includeHidden: true,
+			expectedCount: 3,
+		},
 	}
 
 	for _, tt := range tests {
@@ -69,25 +129,162 @@ func TestFindJSONFilesInDirectory(t *testing.T) {
 			}
 
 			// Test the function
-			result := FindJSONFilesInDirectory(tempDir)
+			result := FindJSONFilesInDirectory(inputs.UserInput{NoRecurse: tt.noRecurse, IncludeHidden: tt.includeHidden}, tempDir)
 
 			if len(result) != tt.expectedCount {
 				t.Errorf("Expected %d JSON files, got %d", tt.expectedCount, len(result))
 			}
 
-			// Verify all returned files are JSON files
+			// Verify all returned files are policy files
 			for _, file := range result {
 				if !filepath.IsAbs(file) {
 					t.Errorf("Expected absolute path, got %s", file)
 				}
-				if filepath.Ext(file) != ".json" {
-					t.Errorf("Expected .json extension, got %s", file)
+				if !isPolicyFile(file) {
+					t.Errorf("Expected policy file extension, got %s", file)
 				}
 			}
 		})
 	}
 }
 
+func TestFindJSONFilesInDirectorySymlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "policy.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "shared.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(tempDir, "shared")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	// a link back to the root would loop forever if followed naively
+	if err := os.Symlink(tempDir, filepath.Join(outside, "loop")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		follow   bool
+		expected []string
+	}{
+		{
+			name:     "skipped by default",
+			expected: []string{filepath.Join(tempDir, "policy.json")},
+		},
+		{
+			name:     "followed with follow-symlinks",
+			follow:   true,
+			expected: []string{filepath.Join(tempDir, "policy.json"), filepath.Join(tempDir, "shared", "shared.json")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := FindJSONFilesInDirectory(inputs.UserInput{FollowLinks: tt.follow}, tempDir)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFindJSONFilesInDirectorySorted(t *testing.T) {
+	tempDir := t.TempDir()
+	// walked a/ before a-b.json, as entries are visited by name, though "-" sorts before "/"
+	for _, name := range []string{"b.json", "a/z.json", "a-b.json", "a/y/x.json", "c.yaml"} {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(`{}`), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	result := FindJSONFilesInDirectory(inputs.UserInput{}, tempDir)
+	if len(result) != 5 {
+		t.Fatalf("Expected 5 files, got %v", result)
+	}
+	if !sort.StringsAreSorted(result) {
+		t.Errorf("Expected the files sorted, got %v", result)
+	}
+}
+
+func TestFindJSONFilesInDirectoryRelativeTarget(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "policies", "nested"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, name := range []string{"policies/a.json", "policies/nested/b.json"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(`{}`), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	t.Chdir(tempDir)
+
+	// paths resolve against the working directory, which can differ from tempDir by a link, e.g. /tmp on macOS
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	expected := []string{filepath.Join(wd, "policies", "a.json"), filepath.Join(wd, "policies", "nested", "b.json")}
+
+	for _, target := range []string{"policies", "./policies/", filepath.Join("policies", "nested", "..")} {
+		result := FindJSONFilesInDirectory(inputs.UserInput{}, target)
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v for target %s, got %v", expected, target, result)
+		}
+	}
+}
+
+func TestIsGeneratedOutput(t *testing.T) {
+	tests := map[string]bool{
+		"/policies/corset1.json":         true,
+		"corset12.json":                  true,
+		"/policies/corset1.json.gz":      true,
+		"/policies/scp_corset.json":      true,
+		"/policies/scp_corset-2.json":    true,
+		"/policies/policy.json":          false,
+		"/policies/corset.yaml":          false,
+		"/policies/corsetry-notes.txt":   false,
+		"/policies/corset.json":          true,
+		"/policies/corset-3.json":        true,
+		"/policies/scp_corset-2.json.gz": true,
+		"/policies/corset-baseline.json": false,
+		"/policies/corsetry.json":        false,
+		"/policies/corset-.json":         false,
+		"/policies/scp_corset_v2.json":   false,
+		"/policies/scp_corsetry.json":    false,
+		"/policies/_corset.json":         false,
+		"/policies/corset-manifest.json": false,
+	}
+	for path, expected := range tests {
+		if result := isGeneratedOutput(path); result != expected {
+			t.Errorf("isGeneratedOutput(%s): expected %v, got %v", path, expected, result)
+		}
+	}
+}
+
+func TestIsDirectoryOutput(t *testing.T) {
+	tests := map[string]bool{
+		"/org/scp/scp.json":        true,
+		"/org/scp/scp-2.json":      true,
+		"/org/scp/scp-5.json.gz":   true,
+		"/org/scp/scp-1.json":      false,
+		"/org/scp/scp-extra.json":  false,
+		"/org/scp/nested/scp.json": false,
+		"/org/scp/deny.json":       false,
+	}
+	for path, expected := range tests {
+		if result := isDirectoryOutput("/org/scp", path); result != expected {
+			t.Errorf("isDirectoryOutput(%s): expected %v, got %v", path, expected, result)
+		}
+	}
+}
+
 func TestIsDirectory(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -132,3 +329,48 @@ func TestIsDirectory(t *testing.T) {
 	}
 }
 
+func TestResolveFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"a.json":        `{}`,
+		"b.json":        `{}`,
+		"dir/c.json":    `{}`,
+		"dir/d.json":    `{}`,
+		"dir/notes.txt": "not json",
+	}
+	for filename, content := range files {
+		filePath := filepath.Join(tempDir, filename)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file %s: %v", filePath, err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		targets       []string
+		expectedCount int
+	}{
+		{
+			name:          "two files",
+			targets:       []string{filepath.Join(tempDir, "a.json"), filepath.Join(tempDir, "b.json")},
+			expectedCount: 2,
+		},
+		{
+			name:          "file and directory",
+			targets:       []string{filepath.Join(tempDir, "a.json"), filepath.Join(tempDir, "dir")},
+			expectedCount: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ResolveFiles(inputs.UserInput{Targets: tt.targets})
+			if len(result) != tt.expectedCount {
+				t.Errorf("Expected %d files, got %v", tt.expectedCount, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignorePattern is one line of a .corsetignore, split into the path segments it matches
+type ignorePattern struct {
+	segments []string
+	anchored bool // matched from the ignore file's directory, rather than at any depth
+	dirOnly  bool // a trailing / matches directories only
+}
+
+// loadIgnoreFile reads the gitignore-style patterns of a .corsetignore, if there is one.
+// Blank lines and # comments are skipped, and * ? [] and ** are supported, but not ! negation.
+func loadIgnoreFile(filename string) ([]ignorePattern, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pattern := ignorePattern{dirOnly: strings.HasSuffix(line, "/")}
+		line = strings.TrimSuffix(line, "/")
+		// like gitignore, a separator anywhere but the end anchors the pattern
+		pattern.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		pattern.segments = strings.Split(line, "/")
+		patterns = append(patterns, pattern)
+	}
+	return patterns, scanner.Err()
+}
+
+// isIgnored reports whether a path, relative to the ignore file's directory, matches any pattern
+func isIgnored(patterns []ignorePattern, rel string, isDir bool) bool {
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for _, pattern := range patterns {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		if pattern.anchored {
+			if matchSegments(pattern.segments, segments) {
+				return true
+			}
+			continue
+		}
+		if matchSegments(pattern.segments, segments[len(segments)-1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against pattern segments, where ** matches any number of them
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+func TestIsIgnored(t *testing.T) {
+	dir := t.TempDir()
+	ignoreFile := filepath.Join(dir, config.IgnoreFilename)
+	content := "# generated and vendored policies\n\nfixtures/\n*.draft.json\n/legacy.json\nvendor/**/aws.json\n"
+	if err := os.WriteFile(ignoreFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write ignore file: %v", err)
+	}
+	patterns, err := loadIgnoreFile(ignoreFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		rel      string
+		isDir    bool
+		expected bool
+	}{
+		{rel: "fixtures", isDir: true, expected: true},
+		{rel: "nested/fixtures", isDir: true, expected: true},
+		{rel: "fixtures", isDir: false, expected: false},
+		{rel: "deny.draft.json", expected: true},
+		{rel: "team/deny.draft.json", expected: true},
+		{rel: "legacy.json", expected: true},
+		{rel: "team/legacy.json", expected: false},
+		{rel: "vendor/aws.json", expected: true},
+		{rel: "vendor/a/b/aws.json", expected: true},
+		{rel: "vendor/a/other.json", expected: false},
+		{rel: "deny.json", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			if result := isIgnored(patterns, tt.rel, tt.isDir); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestLoadIgnoreFileMissing(t *testing.T) {
+	patterns, err := loadIgnoreFile(filepath.Join(t.TempDir(), config.IgnoreFilename))
+	if err != nil || patterns != nil {
+		t.Errorf("Expected no patterns and no error, got %v, %v", patterns, err)
+	}
+}
+
+func TestFindJSONFilesInDirectoryIgnoreFile(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		config.IgnoreFilename:       "fixtures/\n*.draft.json\n",
+		"deny.json":                 `{}`,
+		"team/allow.json":           `{}`,
+		"team/allow.draft.json":     `{}`,
+		"fixtures/sample.json":      `{}`,
+		"fixtures/deep/sample.json": `{}`,
+		"corset1.json":              `{}`,
+	}
+	for filename, content := range files {
+		path := filepath.Join(tempDir, filename)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", filename, err)
+		}
+	}
+
+	result := FindJSONFilesInDirectory(inputs.UserInput{}, tempDir)
+	sort.Strings(result)
+	expected := []string{filepath.Join(tempDir, "deny.json"), filepath.Join(tempDir, "team", "allow.json")}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
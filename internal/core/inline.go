@@ -0,0 +1,55 @@
+package core
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+// inlineSource names the statements of a --policy document in warnings and errors
+const inlineSource = "--policy"
+
+// WritePolicy packs the --policy document and writes each packed policy to w on its own line,
+// rather than reading and writing files
+func WritePolicy(userInput inputs.UserInput, w io.Writer) error {
+	allStatements, header, err := decodeStatements(inlineSource, []byte(userInput.Policy))
+	if err != nil {
+		return fmt.Errorf("invalid --policy: %w", err)
+	}
+	if len(allStatements) == 0 {
+		return ErrNoStatements
+	}
+	if userInput.Schema != "" {
+		schema, err := loadSchema(userInput.Schema)
+		if err != nil {
+			return err
+		}
+		if err := reportSchemaViolations(userInput, schemaViolations(schema, inlineSource, header, allStatements)); err != nil {
+			return err
+		}
+	}
+	if header.Version == "" {
+		header.Version = config.SCPVersion
+	}
+
+	packedFiles, err := packGroup(userInput, header, allStatements)
+	if userInput.Check {
+		return checkFit(userInput, header, packedFiles, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(packedFiles))
+	sizes := make([]int, len(packedFiles))
+	for i, statements := range packedFiles {
+		data := writeJSON(userInput, header, statements)
+		if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+			return err
+		}
+		names[i], sizes[i] = fmt.Sprintf("policy %d", i+1), effectiveSize(userInput, header, statements, data)
+	}
+	return reportThreshold(userInput, names, sizes)
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+func TestWritePolicy(t *testing.T) {
+	userInput := inputs.UserInput{
+		MaxFiles: config.DefaultMaxFiles,
+		MaxSize:  config.MaxPolicySize,
+		Policy: `{"Version": "2012-10-17", "Statement": [
+			{"Sid": "DenyS3", "Effect": "Deny", "Action": ["s3:*"], "Resource": "*"},
+			{"Sid": "DenyEC2", "Effect": "Deny", "Action": "ec2:*", "Resource": "*"}
+		]}`,
+	}
+
+	var out bytes.Buffer
+	if err := WritePolicy(userInput, &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `{"Version":"2012-10-17","Statement":[{"Sid":"DenyEC2","Effect":"Deny","Action":"ec2:*","Resource":"*"},{"Sid":"DenyS3","Effect":"Deny","Action":["s3:*"],"Resource":"*"}]}` + "\n"
+	if out.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, out.String())
+	}
+}
+
+func TestWritePolicySplits(t *testing.T) {
+	statements := largeStatements(t, 40)
+	userInput := inputs.UserInput{
+		MaxFiles: config.DefaultMaxFiles,
+		MaxSize:  config.MaxPolicySize,
+		Policy:   string(writeJSON(inputs.UserInput{}, Header{Version: config.SCPVersion}, statements)),
+	}
+
+	var out bytes.Buffer
+	if err := WritePolicy(userInput, &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("Expected the policy to be split across lines, got %d", len(lines))
+	}
+	written := 0
+	for i, line := range lines {
+		packed, _ := parseStatements("policy.json", []byte(line))
+		if len(packed) == 0 {
+			t.Fatalf("Expected policy %d to hold statements, got %s", i+1, line)
+		}
+		if size := len(line); size > config.MaxPolicySize {
+			t.Errorf("Expected policy %d within the limit, got %d characters", i+1, size)
+		}
+		written += len(packed)
+	}
+	if written != len(statements) {
+		t.Errorf("Expected %d statements written, got %d", len(statements), written)
+	}
+}
+
+func TestWritePolicyNoStatements(t *testing.T) {
+	userInput := inputs.UserInput{MaxFiles: config.DefaultMaxFiles, MaxSize: config.MaxPolicySize, Policy: `{"Version": "2012-10-17"}`}
+
+	var out bytes.Buffer
+	if err := WritePolicy(userInput, &out); !errors.Is(err, ErrNoStatements) {
+		t.Errorf("Expected ErrNoStatements, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing written, got %s", out.String())
+	}
+}
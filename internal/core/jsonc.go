@@ -0,0 +1,49 @@
+package core
+
+// stripComments removes // line and /* */ block comments from JSONC, leaving string values such as ARNs untouched
+func stripComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				// keep escaped characters, including \", as part of the string
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		if c == '/' && i+1 < len(data) {
+			switch data[i+1] {
+			case '/':
+				// skip to the end of the line, keeping the newline
+				for i+1 < len(data) && data[i+1] != '\n' {
+					i++
+				}
+				continue
+			case '*':
+				// skip past the closing */, an unterminated comment runs to the end
+				i += 2
+				for i < len(data) && !(data[i] == '*' && i+1 < len(data) && data[i+1] == '/') {
+					i++
+				}
+				i++
+				out = append(out, ' ')
+				continue
+			}
+		}
+
+		if c == '"' {
+			inString = true
+		}
+		out = append(out, c)
+	}
+	return out
+}
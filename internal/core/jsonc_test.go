@@ -0,0 +1,109 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "no comments",
+			input:    `{"Effect": "Deny"}`,
+			expected: `{"Effect": "Deny"}`,
+		},
+		{
+			name:     "line comment",
+			input:    "{\n  // deny everything\n  \"Effect\": \"Deny\"\n}",
+			expected: "{\n  \n  \"Effect\": \"Deny\"\n}",
+		},
+		{
+			name:     "trailing line comment",
+			input:    "{\"Effect\": \"Deny\" // always\n}",
+			expected: "{\"Effect\": \"Deny\" \n}",
+		},
+		{
+			name:     "block comment",
+			input:    `{/* reviewed */"Effect": /* inline */ "Deny"}`,
+			expected: `{ "Effect":   "Deny"}`,
+		},
+		{
+			name:     "multiline block comment",
+			input:    "{\n/*\n * owned by security\n */\n\"Effect\": \"Deny\"}",
+			expected: "{\n \n\"Effect\": \"Deny\"}",
+		},
+		{
+			name:     "slashes in ARN",
+			input:    `{"Resource": "arn:aws:s3:::bucket//prefix/*"} // comment`,
+			expected: `{"Resource": "arn:aws:s3:::bucket//prefix/*"} `,
+		},
+		{
+			name:     "comment markers in URL",
+			input:    `{"Condition": {"StringLike": {"aws:Referer": "https://example.com/*"}}}`,
+			expected: `{"Condition": {"StringLike": {"aws:Referer": "https://example.com/*"}}}`,
+		},
+		{
+			name:     "escaped quote in string",
+			input:    `{"Sid": "say \"//hi\"" /* note */}`,
+			expected: `{"Sid": "say \"//hi\""  }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(stripComments([]byte(tt.input)))
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestExtractIndividualStatementsJSONC(t *testing.T) {
+	content := `{
+  // organisation guardrails
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      /* block public buckets */
+      "Effect": "Deny",
+      "Action": "s3:*", // everything
+      "Resource": "arn:aws:s3:::logs//archive/*"
+    }
+  ]
+}`
+	testFile := filepath.Join(t.TempDir(), "policy.jsonc")
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	statements, header := extractIndividualStatements(testFile)
+
+	if len(statements) != 1 {
+		t.Fatalf("Expected 1 statement, got %d", len(statements))
+	}
+	if header.Version != "2012-10-17" {
+		t.Errorf("Expected version 2012-10-17, got %s", header.Version)
+	}
+	if statements[0].Content["Resource"] != "arn:aws:s3:::logs//archive/*" {
+		t.Errorf("Expected ARN to be kept, got %v", statements[0].Content["Resource"])
+	}
+
+	// sized and written without the comments
+	expected := `{"Effect":"Deny","Action":"s3:*","Resource":"arn:aws:s3:::logs//archive/*"}`
+	if string(statements[0].Raw) != expected {
+		t.Errorf("Expected raw %s, got %s", expected, statements[0].Raw)
+	}
+	if statements[0].Size != len(expected) {
+		t.Errorf("Expected size %d, got %d", len(expected), statements[0].Size)
+	}
+	if !json.Valid(statements[0].Raw) {
+		t.Error("Expected raw statement to be valid JSON")
+	}
+}
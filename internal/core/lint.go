@@ -0,0 +1,95 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+//go:embed actions.txt
+var embeddedActions []byte
+
+// actionShape is service:Action, where the action may contain * and ? wildcards
+var actionShape = regexp.MustCompile(`^[A-Za-z0-9-]+:[A-Za-z0-9*?]+$`)
+
+// actionCatalog lists the known actions of each service, lowercased
+type actionCatalog map[string][]string
+
+// loadActionCatalog reads a catalog from a file, or the embedded subset when path is empty
+func loadActionCatalog(path string) (actionCatalog, error) {
+	data := embeddedActions
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	catalog := actionCatalog{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		action := strings.TrimSpace(scanner.Text())
+		if action == "" || strings.HasPrefix(action, "#") {
+			continue
+		}
+		service, _, ok := strings.Cut(strings.ToLower(action), ":")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected service:Action, got %s", catalogName(path), line, action)
+		}
+		catalog[service] = append(catalog[service], strings.ToLower(action))
+	}
+	return catalog, scanner.Err()
+}
+
+func catalogName(path string) string {
+	if path == "" {
+		return "actions.txt"
+	}
+	return path
+}
+
+// lintActions returns a check that every Action and NotAction entry is well formed and, for services
+// in the catalog, matches at least one known action
+func lintActions(catalog actionCatalog) func(map[string]interface{}) []string {
+	return func(content map[string]interface{}) []string {
+		var messages []string
+		for _, key := range []string{"Action", "NotAction"} {
+			value, ok := content[key]
+			if !ok {
+				continue
+			}
+			for _, action := range actionList(value) {
+				if message := catalog.check(action); message != "" {
+					messages = append(messages, fmt.Sprintf("%s %s", key, message))
+				}
+			}
+		}
+		return messages
+	}
+}
+
+// check describes what is wrong with an action, or returns "" if it is fine
+func (c actionCatalog) check(action string) string {
+	if action == "*" {
+		return ""
+	}
+	if !actionShape.MatchString(action) {
+		return fmt.Sprintf("%q is not in the form service:Action", action)
+	}
+
+	service, _, _ := strings.Cut(strings.ToLower(action), ":")
+	known, ok := c[service]
+	if !ok {
+		return "" // nothing to compare against
+	}
+	for _, candidate := range known {
+		if coversAction(action, candidate) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%q is not a known %s action", action, service)
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+func TestLintActions(t *testing.T) {
+	catalog, err := loadActionCatalog("")
+	if err != nil {
+		t.Fatalf("Failed to load embedded catalog: %v", err)
+	}
+	lint := lintActions(catalog)
+
+	tests := []struct {
+		name     string
+		content  map[string]interface{}
+		expected []string
+	}{
+		{
+			name:    "valid actions",
+			content: map[string]interface{}{"Action": []interface{}{"s3:GetObject", "iam:PassRole", "S3:getobject"}},
+		},
+		{
+			name:    "wildcards",
+			content: map[string]interface{}{"Action": []interface{}{"*", "s3:*", "s3:Get*", "iam:Get?ole"}},
+		},
+		{
+			name:    "service not in catalog",
+			content: map[string]interface{}{"Action": "lambda:InvokeFunction"},
+		},
+		{
+			name:     "typo",
+			content:  map[string]interface{}{"Action": []interface{}{"s3:GetObject", "s3:GetObjcet"}},
+			expected: []string{`Action "s3:GetObjcet" is not a known s3 action`},
+		},
+		{
+			name:     "wildcard matching nothing",
+			content:  map[string]interface{}{"NotAction": "s3:Got*"},
+			expected: []string{`NotAction "s3:Got*" is not a known s3 action`},
+		},
+		{
+			name:     "bad shape",
+			content:  map[string]interface{}{"Action": []interface{}{"s3GetObject", "s3:Get Object"}},
+			expected: []string{`Action "s3GetObject" is not in the form service:Action`, `Action "s3:Get Object" is not in the form service:Action`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := lint(tt.content)
+			if strings.Join(messages, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Expected %v, got %v", tt.expected, messages)
+			}
+		})
+	}
+}
+
+func TestLoadActionCatalogFile(t *testing.T) {
+	tempDir := t.TempDir()
+	catalogFile := filepath.Join(tempDir, "actions.txt")
+	if err := os.WriteFile(catalogFile, []byte("# custom\nlambda:InvokeFunction\n\nlambda:GetFunction\n"), 0644); err != nil {
+		t.Fatalf("Failed to write catalog: %v", err)
+	}
+
+	catalog, err := loadActionCatalog(catalogFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if message := catalog.check("lambda:InvokeFunction"); message != "" {
+		t.Errorf("Expected lambda:InvokeFunction to be known, got %s", message)
+	}
+	if message := catalog.check("lambda:InvokeFunctoin"); message == "" {
+		t.Error("Expected lambda:InvokeFunctoin to be unknown")
+	}
+
+	badFile := filepath.Join(tempDir, "bad.txt")
+	if err := os.WriteFile(badFile, []byte("lambda:InvokeFunction\nnot an action\n"), 0644); err != nil {
+		t.Fatalf("Failed to write catalog: %v", err)
+	}
+	if _, err := loadActionCatalog(badFile); err == nil || !strings.Contains(err.Error(), "bad.txt:2") {
+		t.Errorf("Expected an error naming line 2, got %v", err)
+	}
+}
+
+func TestValidateFilesLintActions(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "policy.json")
+	content := `{"Version": "2012-10-17", "Statement": [
+		{"Effect": "Deny", "Action": "s3:GetObject", "Resource": "*"},
+		{"Effect": "Deny", "Action": ["s3:*", "s3:GetObjcet"], "Resource": "*"}
+	]}`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	violations, err := ValidateFiles(inputs.UserInput{LintActions: true}, []string{testFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Index != 1 {
+		t.Errorf("Expected one violation for Statement[1], got %v", violations)
+	}
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// writeManifest records the statements packed into each output file
+func writeManifest(filename string, packedFiles [][]Statement, results []WriteResult) error {
+	manifest := buildManifest(packedFiles, results)
+	err := writeFileAtomic(filename, func(w io.Writer) error {
+		_, err := w.Write(marshalJSON(manifest, "", "  "))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", filename, err)
+	}
+	return nil
+}
+
+func buildManifest(packedFiles [][]Statement, results []WriteResult) Manifest {
+	manifest := Manifest{Files: []ManifestFile{}}
+	for i, result := range results {
+		file := ManifestFile{
+			File:       filepath.Base(result.Filename),
+			Size:       result.Size,
+			Statements: []ManifestStatement{},
+		}
+		for _, stmt := range packedFiles[i] {
+			entry := ManifestStatement{Source: filepath.Base(stmt.Source), Index: stmt.Index}
+			if sid, ok := stmt.Content["Sid"].(string); ok {
+				entry.Sid = sid
+			}
+			file.Statements = append(file.Statements, entry)
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+	return manifest
+}
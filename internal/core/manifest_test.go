@@ -0,0 +1,95 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+func TestProcessFilesManifest(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "policy.json")
+
+	var statements []map[string]interface{}
+	for i := 0; i < 40; i++ {
+		statements = append(statements, map[string]interface{}{
+			"Sid":      fmt.Sprintf("Deny%d", i),
+			"Effect":   "Deny",
+			"Action":   []string{"s3:DeleteBucket", "s3:PutBucketPolicy", "s3:PutLifecycleConfiguration"},
+			"Resource": fmt.Sprintf("arn:aws:s3:::a-long-bucket-name-to-fill-the-policy-%d/*", i),
+		})
+	}
+	// one statement without a Sid is listed by index
+	statements = append(statements, map[string]interface{}{"Effect": "Deny", "Action": "iam:*", "Resource": "*"})
+	policy := map[string]interface{}{"Version": config.SCPVersion, "Statement": statements}
+	if err := os.WriteFile(testFile, []byte(mustMarshal(t, policy)), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	userInput := inputs.UserInput{
+		Target:   testFile,
+		MaxFiles: config.DefaultMaxFiles,
+		Manifest: true,
+	}
+	summary, err := ProcessFiles(userInput, []string{testFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	results := summary.Files
+	if len(results) < 2 {
+		t.Fatalf("Expected the statements to be split across files, got %d", len(results))
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, config.ManifestFilename))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Manifest is not valid JSON: %v", err)
+	}
+	if len(manifest.Files) != len(results) {
+		t.Fatalf("Expected %d manifest files, got %d", len(results), len(manifest.Files))
+	}
+
+	listed := 0
+	for i, file := range manifest.Files {
+		if file.File != filepath.Base(results[i].Filename) || file.Size != results[i].Size {
+			t.Errorf("Expected %s (%d characters), got %s (%d characters)",
+				filepath.Base(results[i].Filename), results[i].Size, file.File, file.Size)
+		}
+
+		// each listed statement is the one written at the same position
+		var written rawPolicy
+		output, err := os.ReadFile(results[i].Filename)
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		if err := json.Unmarshal(output, &written); err != nil {
+			t.Fatalf("Output is not valid JSON: %v", err)
+		}
+		if len(written.Statement) != len(file.Statements) {
+			t.Fatalf("Expected %d statements listed for %s, got %d", len(written.Statement), file.File, len(file.Statements))
+		}
+		for j, raw := range written.Statement {
+			var content map[string]interface{}
+			json.Unmarshal(raw, &content)
+			sid, _ := content["Sid"].(string)
+			if file.Statements[j].Sid != sid {
+				t.Errorf("Expected %s statement %d to be %q, got %q", file.File, j, sid, file.Statements[j].Sid)
+			}
+			if sid == "" && file.Statements[j].Index != len(statements)-1 {
+				t.Errorf("Expected the statement without a Sid at index %d, got %d", len(statements)-1, file.Statements[j].Index)
+			}
+		}
+		listed += len(file.Statements)
+	}
+	if listed != len(statements) {
+		t.Errorf("Expected %d statements listed, got %d", len(statements), listed)
+	}
+}
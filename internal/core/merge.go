@@ -0,0 +1,92 @@
+package core
+
+import (
+	"encoding/json"
+)
+
+// mergeStatements combines statements that differ only in Action (or Sid), keeping the first Sid.
+// NotAction statements are left alone, combining them would change their meaning.
+func mergeStatements(statements []Statement) []Statement {
+	var merged []Statement
+	groups := make(map[string]int) // merge key -> index in merged
+
+	for _, stmt := range statements {
+		key, ok := mergeKey(stmt.Content)
+		if !ok {
+			merged = append(merged, stmt)
+			continue
+		}
+
+		i, exists := groups[key]
+		if !exists {
+			groups[key] = len(merged)
+			merged = append(merged, stmt)
+			continue
+		}
+
+		content := make(map[string]interface{}, len(merged[i].Content))
+		for k, v := range merged[i].Content {
+			content[k] = v
+		}
+		content["Action"] = combineActions(merged[i].Content["Action"], stmt.Content["Action"])
+		resized := newStatement(content)
+		resized.Source, resized.Index = merged[i].Source, merged[i].Index
+		merged[i] = resized
+	}
+
+	return merged
+}
+
+// mergeKey identifies a statement by everything except Action and Sid
+func mergeKey(content map[string]interface{}) (string, bool) {
+	if _, ok := content["Action"]; !ok {
+		return "", false
+	}
+	if _, ok := content["NotAction"]; ok {
+		return "", false
+	}
+
+	rest := make(map[string]interface{}, len(content))
+	for k, v := range content {
+		if k != "Action" && k != "Sid" {
+			rest[k] = v
+		}
+	}
+	key, err := json.Marshal(rest) // map keys are marshaled in sorted order
+	if err != nil {
+		return "", false
+	}
+	return string(key), true
+}
+
+// combineActions joins two Action values, dropping duplicates while preserving order
+func combineActions(a, b interface{}) []interface{} {
+	seen := make(map[string]bool)
+	var actions []interface{}
+	for _, action := range append(actionList(a), actionList(b)...) {
+		if !seen[action] {
+			seen[action] = true
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// actionList normalizes a string or array Action value into a slice of strings
+func actionList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var actions []string
+		for _, item := range v {
+			if action, ok := item.(string); ok {
+				actions = append(actions, action)
+			}
+		}
+		return actions
+	case []string:
+		return v
+	}
+	return nil
+}
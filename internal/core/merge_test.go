@@ -0,0 +1,81 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeStatements(t *testing.T) {
+	tests := []struct {
+		name            string
+		statements      []map[string]interface{}
+		expectedCount   int
+		expectedActions []interface{} // actions of the first statement after merging
+	}{
+		{
+			name: "three mergeable statements",
+			statements: []map[string]interface{}{
+				{"Sid": "DenyS3", "Effect": "Deny", "Action": "s3:DeleteBucket", "Resource": "*"},
+				{"Sid": "DenyS3Policy", "Effect": "Deny", "Action": []interface{}{"s3:PutBucketPolicy", "s3:DeleteBucket"}, "Resource": "*"},
+				{"Effect": "Deny", "Action": []interface{}{"s3:PutBucketAcl"}, "Resource": "*"},
+			},
+			expectedCount:   1,
+			expectedActions: []interface{}{"s3:DeleteBucket", "s3:PutBucketPolicy", "s3:PutBucketAcl"},
+		},
+		{
+			name: "different resources",
+			statements: []map[string]interface{}{
+				{"Effect": "Deny", "Action": "s3:DeleteBucket", "Resource": "arn:aws:s3:::a"},
+				{"Effect": "Deny", "Action": "s3:PutBucketPolicy", "Resource": "arn:aws:s3:::b"},
+			},
+			expectedCount:   2,
+			expectedActions: nil,
+		},
+		{
+			name: "different conditions",
+			statements: []map[string]interface{}{
+				{"Effect": "Deny", "Action": "s3:DeleteBucket", "Resource": "*",
+					"Condition": map[string]interface{}{"Bool": map[string]interface{}{"aws:SecureTransport": "false"}}},
+				{"Effect": "Deny", "Action": "s3:PutBucketPolicy", "Resource": "*"},
+			},
+			expectedCount:   2,
+			expectedActions: nil,
+		},
+		{
+			name: "NotAction is not merged",
+			statements: []map[string]interface{}{
+				{"Effect": "Deny", "NotAction": "iam:*", "Resource": "*"},
+				{"Effect": "Deny", "NotAction": "s3:*", "Resource": "*"},
+			},
+			expectedCount:   2,
+			expectedActions: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var statements []Statement
+			for _, content := range tt.statements {
+				statements = append(statements, newStatement(content))
+			}
+
+			result := mergeStatements(statements)
+
+			if len(result) != tt.expectedCount {
+				t.Fatalf("Expected %d statements, got %d", tt.expectedCount, len(result))
+			}
+
+			if tt.expectedActions != nil {
+				if !reflect.DeepEqual(result[0].Content["Action"], tt.expectedActions) {
+					t.Errorf("Expected actions %v, got %v", tt.expectedActions, result[0].Content["Action"])
+				}
+				if result[0].Content["Sid"] != "DenyS3" {
+					t.Errorf("Expected first Sid to be kept, got %v", result[0].Content["Sid"])
+				}
+				if result[0].Size != newStatement(result[0].Content).Size {
+					t.Errorf("Merged statement size was not recalculated")
+				}
+			}
+		})
+	}
+}
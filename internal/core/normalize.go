@@ -0,0 +1,200 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+)
+
+// normalizeStatement tidies statement content in place before it is sized, reporting whether it changed
+func normalizeStatement(content map[string]interface{}) bool {
+	changed := false
+	for _, key := range []string{"Action", "NotAction"} {
+		if actions, ok := content[key].([]interface{}); ok {
+			deduped := dedupeValues(actions)
+			if len(deduped) != len(actions) {
+				content[key] = deduped
+				changed = true
+			}
+		}
+	}
+	if dropEmptyConditions(content) {
+		changed = true
+	}
+	return changed
+}
+
+// dropEmptyConditions removes condition operators with no keys, then the Condition itself if nothing is left.
+// An empty block matches every request, so removing it keeps the meaning and saves its characters.
+func dropEmptyConditions(content map[string]interface{}) bool {
+	condition, ok := content["Condition"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	changed := false
+	for operator, value := range condition {
+		if keys, ok := value.(map[string]interface{}); ok && len(keys) == 0 {
+			delete(condition, operator)
+			changed = true
+		}
+	}
+	if len(condition) == 0 {
+		delete(content, "Condition")
+		changed = true
+	}
+	return changed
+}
+
+// reportEmptyElements warns about statements with an empty Action or Resource, which AWS rejects
+func reportEmptyElements(statements []Statement) {
+	for _, message := range findEmptyElements(statements) {
+		slog.Warn(message)
+	}
+}
+
+// findEmptyElements returns a message for each empty string or array in an action or resource element
+func findEmptyElements(statements []Statement) []string {
+	var messages []string
+	for _, stmt := range statements {
+		for _, element := range []string{"Action", "NotAction", "Resource", "NotResource"} {
+			value, ok := stmt.Content[element]
+			if !ok {
+				continue
+			}
+			if values, isList := value.([]interface{}); (isList && len(values) == 0) || value == "" {
+				messages = append(messages, fmt.Sprintf("%s: Statement[%d] has an empty %s, which AWS will reject",
+					filepath.Base(stmt.Source), stmt.Index, element))
+			}
+		}
+	}
+	return messages
+}
+
+// dedupeValues removes exact duplicates from a list, preserving order
+func dedupeValues(values []interface{}) []interface{} {
+	seen := make(map[interface{}]bool)
+	deduped := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		key, ok := value.(string)
+		if !ok {
+			// leave non-string entries for AWS to reject
+			deduped = append(deduped, value)
+			continue
+		}
+		if !seen[key] {
+			seen[key] = true
+			deduped = append(deduped, value)
+		}
+	}
+	return deduped
+}
+
+// rewriteStatements applies a rewrite to each statement's content, re-sizing the statements it changes
+func rewriteStatements(statements []Statement, rewrite func(map[string]interface{}) (map[string]interface{}, bool)) []Statement {
+	rewritten := make([]Statement, len(statements))
+	for i, stmt := range statements {
+		content, changed := rewrite(stmt.Content)
+		if !changed {
+			rewritten[i] = stmt
+			continue
+		}
+		resized := newStatement(content)
+		resized.Source, resized.Index = stmt.Source, stmt.Index
+		rewritten[i] = resized
+	}
+	return rewritten
+}
+
+// sortElements returns a copy of the content with Action and Resource arrays in alphabetical order.
+// Both are unordered sets, so sorting keeps their meaning while giving stable output. Strings are left alone.
+func sortElements(content map[string]interface{}) (map[string]interface{}, bool) {
+	var sorted map[string]interface{}
+	for _, key := range []string{"Action", "NotAction", "Resource", "NotResource"} {
+		values, ok := content[key].([]interface{})
+		if !ok {
+			continue
+		}
+		strs := make([]string, 0, len(values))
+		for _, value := range values {
+			if str, ok := value.(string); ok {
+				strs = append(strs, str)
+			}
+		}
+		if len(strs) != len(values) || sort.StringsAreSorted(strs) {
+			continue // leave non-string entries for AWS to reject
+		}
+
+		sort.Strings(strs)
+		ordered := make([]interface{}, len(strs))
+		for i, str := range strs {
+			ordered[i] = str
+		}
+		if sorted == nil {
+			sorted = make(map[string]interface{}, len(content))
+			for k, v := range content {
+				sorted[k] = v
+			}
+		}
+		sorted[key] = ordered
+	}
+	if sorted == nil {
+		return content, false
+	}
+	return sorted, true
+}
+
+// collapseArrays returns a copy of the content with single-element Action, Resource and Principal arrays
+// written as plain strings, which AWS treats identically and costs two fewer characters
+func collapseArrays(content map[string]interface{}) (map[string]interface{}, bool) {
+	collapsed := make(map[string]interface{}, len(content))
+	changed := false
+	for k, v := range content {
+		collapsed[k] = v
+		switch k {
+		case "Action", "NotAction", "Resource", "NotResource":
+			if single, ok := singleString(v); ok {
+				collapsed[k] = single
+				changed = true
+			}
+		case "Principal", "NotPrincipal":
+			// principals are keyed by type, e.g. {"AWS": ["arn:..."]}
+			principals, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var collapsedPrincipals map[string]interface{}
+			for principalType, value := range principals {
+				single, ok := singleString(value)
+				if !ok {
+					continue
+				}
+				if collapsedPrincipals == nil {
+					collapsedPrincipals = make(map[string]interface{}, len(principals))
+					for t, p := range principals {
+						collapsedPrincipals[t] = p
+					}
+				}
+				collapsedPrincipals[principalType] = single
+			}
+			if collapsedPrincipals != nil {
+				collapsed[k] = collapsedPrincipals
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return content, false
+	}
+	return collapsed, true
+}
+
+// singleString returns the only entry of a one element string array
+func singleString(value interface{}) (string, bool) {
+	values, ok := value.([]interface{})
+	if !ok || len(values) != 1 {
+		return "", false
+	}
+	single, ok := values[0].(string)
+	return single, ok
+}
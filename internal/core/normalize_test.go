@@ -0,0 +1,236 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeStatementDedupeActions(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name: "duplicated actions",
+			content: map[string]interface{}{
+				"Effect": "Deny",
+				"Action": []interface{}{"s3:GetObject", "s3:PutObject", "s3:GetObject"},
+			},
+			expected: map[string]interface{}{
+				"Effect": "Deny",
+				"Action": []interface{}{"s3:GetObject", "s3:PutObject"},
+			},
+		},
+		{
+			name: "duplicated not actions",
+			content: map[string]interface{}{
+				"Effect":    "Deny",
+				"NotAction": []interface{}{"iam:*", "iam:*", "sts:*"},
+			},
+			expected: map[string]interface{}{
+				"Effect":    "Deny",
+				"NotAction": []interface{}{"iam:*", "sts:*"},
+			},
+		},
+		{
+			name: "string action untouched",
+			content: map[string]interface{}{
+				"Effect": "Deny",
+				"Action": "s3:GetObject",
+			},
+			expected: map[string]interface{}{
+				"Effect": "Deny",
+				"Action": "s3:GetObject",
+			},
+		},
+		{
+			name: "no duplicates keeps order",
+			content: map[string]interface{}{
+				"Effect": "Deny",
+				"Action": []interface{}{"s3:PutObject", "s3:GetObject"},
+			},
+			expected: map[string]interface{}{
+				"Effect": "Deny",
+				"Action": []interface{}{"s3:PutObject", "s3:GetObject"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalizeStatement(tt.content)
+			if !reflect.DeepEqual(tt.content, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, tt.content)
+			}
+		})
+	}
+}
+
+func TestNormalizeStatementReducesSize(t *testing.T) {
+	content := map[string]interface{}{
+		"Effect":   "Deny",
+		"Action":   []interface{}{"s3:GetObject", "s3:GetObject"},
+		"Resource": "*",
+	}
+	before := newStatement(content).Size
+	if !normalizeStatement(content) {
+		t.Error("Expected normalization to report a change")
+	}
+	after := newStatement(content).Size
+
+	if after >= before {
+		t.Errorf("Expected size to shrink, got %d before and %d after", before, after)
+	}
+}
+
+func TestNormalizeStatementDropsEmptyConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name: "empty condition",
+			content: map[string]interface{}{
+				"Effect":    "Deny",
+				"Action":    "s3:*",
+				"Condition": map[string]interface{}{},
+			},
+			expected: map[string]interface{}{
+				"Effect": "Deny",
+				"Action": "s3:*",
+			},
+		},
+		{
+			name: "empty operator dropped, others kept",
+			content: map[string]interface{}{
+				"Effect": "Deny",
+				"Condition": map[string]interface{}{
+					"StringEquals": map[string]interface{}{},
+					"Bool":         map[string]interface{}{"aws:SecureTransport": "false"},
+				},
+			},
+			expected: map[string]interface{}{
+				"Effect": "Deny",
+				"Condition": map[string]interface{}{
+					"Bool": map[string]interface{}{"aws:SecureTransport": "false"},
+				},
+			},
+		},
+		{
+			name: "only empty operators",
+			content: map[string]interface{}{
+				"Effect":    "Deny",
+				"Condition": map[string]interface{}{"StringEquals": map[string]interface{}{}},
+			},
+			expected: map[string]interface{}{
+				"Effect": "Deny",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !normalizeStatement(tt.content) {
+				t.Error("Expected normalization to report a change")
+			}
+			if !reflect.DeepEqual(tt.content, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, tt.content)
+			}
+		})
+	}
+}
+
+func TestFindEmptyElements(t *testing.T) {
+	statements := []Statement{
+		{Source: "/tmp/a.json", Index: 0, Content: map[string]interface{}{"Effect": "Deny", "Action": []interface{}{}, "Resource": "*"}},
+		{Source: "/tmp/a.json", Index: 1, Content: map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": ""}},
+		{Source: "/tmp/a.json", Index: 2, Content: map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}},
+	}
+
+	expected := []string{
+		"a.json: Statement[0] has an empty Action, which AWS will reject",
+		"a.json: Statement[1] has an empty Resource, which AWS will reject",
+	}
+	if result := findEmptyElements(statements); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestSortElements(t *testing.T) {
+	content := map[string]interface{}{
+		"Effect":    "Deny",
+		"Action":    []interface{}{"s3:PutObject", "ec2:RunInstances", "s3:GetObject"},
+		"Resource":  "arn:aws:s3:::b",
+		"Condition": map[string]interface{}{"StringEquals": map[string]interface{}{"aws:PrincipalTag/team": []interface{}{"z", "a"}}},
+	}
+
+	sorted, changed := sortElements(content)
+
+	if !changed {
+		t.Fatal("Expected content to change")
+	}
+	if !reflect.DeepEqual(sorted["Action"], []interface{}{"ec2:RunInstances", "s3:GetObject", "s3:PutObject"}) {
+		t.Errorf("Expected sorted actions, got %v", sorted["Action"])
+	}
+	if sorted["Resource"] != "arn:aws:s3:::b" {
+		t.Errorf("Expected single string Resource to be untouched, got %v", sorted["Resource"])
+	}
+	if !reflect.DeepEqual(sorted["Condition"], content["Condition"]) {
+		t.Errorf("Expected Condition values to be untouched, got %v", sorted["Condition"])
+	}
+	if content["Action"].([]interface{})[0] != "s3:PutObject" {
+		t.Error("Expected input content to be left unmodified")
+	}
+
+	if _, changed := sortElements(sorted); changed {
+		t.Error("Expected already sorted content to be unchanged")
+	}
+}
+
+func TestCollapseArrays(t *testing.T) {
+	content := map[string]interface{}{
+		"Effect":    "Deny",
+		"Action":    []interface{}{"s3:*"},
+		"Resource":  []interface{}{"arn:aws:s3:::a", "arn:aws:s3:::b"},
+		"Principal": map[string]interface{}{"AWS": []interface{}{"arn:aws:iam::123456789012:root"}, "Service": []interface{}{"a", "b"}},
+	}
+
+	collapsed, changed := collapseArrays(content)
+
+	if !changed {
+		t.Fatal("Expected content to change")
+	}
+	if collapsed["Action"] != "s3:*" {
+		t.Errorf("Expected single Action to collapse, got %v", collapsed["Action"])
+	}
+	if !reflect.DeepEqual(collapsed["Resource"], content["Resource"]) {
+		t.Errorf("Expected multi-element Resource to be left alone, got %v", collapsed["Resource"])
+	}
+	principal := collapsed["Principal"].(map[string]interface{})
+	if principal["AWS"] != "arn:aws:iam::123456789012:root" {
+		t.Errorf("Expected single AWS principal to collapse, got %v", principal["AWS"])
+	}
+	if !reflect.DeepEqual(principal["Service"], []interface{}{"a", "b"}) {
+		t.Errorf("Expected multi-element Service principal to be left alone, got %v", principal["Service"])
+	}
+	if _, ok := content["Action"].([]interface{}); !ok {
+		t.Error("Expected input content to be left unmodified")
+	}
+
+	unchanged := map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": []interface{}{"a", "b"}}
+	if _, changed := collapseArrays(unchanged); changed {
+		t.Error("Expected content without single-element arrays to be unchanged")
+	}
+}
+
+func TestCollapseArraysSize(t *testing.T) {
+	statements := []Statement{newStatement(map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": []interface{}{"*"}})}
+
+	collapsed := rewriteStatements(statements, collapseArrays)
+
+	if collapsed[0].Size != statements[0].Size-2 {
+		t.Errorf("Expected size to shrink by 2, got %d from %d", collapsed[0].Size, statements[0].Size)
+	}
+}
@@ -0,0 +1,91 @@
+package core
+
+import "strings"
+
+// optimizeStatements drops actions already covered by a wildcard in the same statement, then merges
+// statements that differ only in a condition's values, re-sizing any that change
+func optimizeStatements(statements []Statement) []Statement {
+	return mergeConditions(rewriteStatements(statements, optimizeStatement))
+}
+
+// optimizeStatement returns a copy of the content with covered actions removed, reporting whether any were.
+// NotAction is a union of patterns too, so covered entries can be dropped there without changing its meaning.
+func optimizeStatement(content map[string]interface{}) (map[string]interface{}, bool) {
+	var optimized map[string]interface{}
+	for _, key := range []string{"Action", "NotAction"} {
+		actions, ok := content[key].([]interface{})
+		if !ok {
+			continue
+		}
+		consolidated := consolidateActions(actions)
+		if len(consolidated) == len(actions) {
+			continue
+		}
+		if optimized == nil {
+			optimized = make(map[string]interface{}, len(content))
+			for k, v := range content {
+				optimized[k] = v
+			}
+		}
+		optimized[key] = consolidated
+	}
+	if optimized == nil {
+		return content, false
+	}
+	return optimized, true
+}
+
+// consolidateActions removes actions matched by another entry, keeping the first of any equivalent patterns
+func consolidateActions(actions []interface{}) []interface{} {
+	var consolidated []interface{}
+	for i, action := range actions {
+		pattern, ok := action.(string)
+		if !ok {
+			consolidated = append(consolidated, action)
+			continue
+		}
+
+		covered := false
+		for j, other := range actions {
+			otherPattern, ok := other.(string)
+			if !ok || i == j || !coversAction(otherPattern, pattern) {
+				continue
+			}
+			// equivalent patterns cover each other, only the later one is dropped
+			if !coversAction(pattern, otherPattern) || j < i {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			consolidated = append(consolidated, action)
+		}
+	}
+	return consolidated
+}
+
+// coversAction reports whether every action matched by pattern is also matched by wildcard.
+// Matching follows IAM: * matches any run of characters, ? exactly one, case-insensitively.
+func coversAction(wildcard, pattern string) bool {
+	return coversFrom(strings.ToLower(wildcard), strings.ToLower(pattern))
+}
+
+func coversFrom(wildcard, pattern string) bool {
+	if wildcard == "" {
+		return pattern == ""
+	}
+
+	switch wildcard[0] {
+	case '*':
+		// * absorbs nothing, or the next character of the pattern whatever it is
+		if coversFrom(wildcard[1:], pattern) {
+			return true
+		}
+		return pattern != "" && coversFrom(wildcard, pattern[1:])
+	case '?':
+		// ? stands in for one literal or ?, but not a * that may match several characters
+		return pattern != "" && pattern[0] != '*' && coversFrom(wildcard[1:], pattern[1:])
+	default:
+		return pattern != "" && pattern[0] == wildcard[0] && coversFrom(wildcard[1:], pattern[1:])
+	}
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCoversAction(t *testing.T) {
+	tests := []struct {
+		wildcard string
+		pattern  string
+		expected bool
+	}{
+		{"s3:*", "s3:GetObject", true},
+		{"s3:Get*", "s3:GetObject", true},
+		{"s3:Get*", "s3:GetObject*", true},
+		{"s3:Get*", "s3:Put*", false},
+		{"s3:Get*", "s3:*", false},
+		{"s3:*", "ec2:DescribeInstances", false},
+		{"*", "ec2:DescribeInstances", true},
+		{"s3:get*", "S3:GetObject", true},
+		{"s3:GetObjec?", "s3:GetObject", true},
+		{"s3:GetObjec?", "s3:GetObjec?", true},
+		{"s3:GetObjec?", "s3:GetObjec*", false},
+		{"s3:Get?bject", "s3:GetObject", true},
+		{"s3:Get?", "s3:GetObject", false},
+		{"s3:GetObject", "s3:GetObject", true},
+		{"s3:GetObject", "s3:Get*", false},
+		{"s3:*Object", "s3:GetObject", true},
+		{"s3:*Object", "s3:GetObjectAcl", false},
+	}
+
+	for _, tt := range tests {
+		if result := coversAction(tt.wildcard, tt.pattern); result != tt.expected {
+			t.Errorf("coversAction(%s, %s): expected %v, got %v", tt.wildcard, tt.pattern, tt.expected, result)
+		}
+	}
+}
+
+func TestConsolidateActions(t *testing.T) {
+	tests := []struct {
+		name     string
+		actions  []interface{}
+		expected []interface{}
+	}{
+		{
+			name:     "nothing covered",
+			actions:  []interface{}{"s3:GetObject", "s3:PutObject"},
+			expected: []interface{}{"s3:GetObject", "s3:PutObject"},
+		},
+		{
+			name:     "prefix wildcard",
+			actions:  []interface{}{"s3:Get*", "s3:GetObject", "s3:PutObject"},
+			expected: []interface{}{"s3:Get*", "s3:PutObject"},
+		},
+		{
+			name:     "service wildcard",
+			actions:  []interface{}{"s3:GetObject", "s3:*", "s3:Put*", "ec2:DescribeInstances"},
+			expected: []interface{}{"s3:*", "ec2:DescribeInstances"},
+		},
+		{
+			name:     "other services untouched",
+			actions:  []interface{}{"s3:*", "s3express:CreateSession"},
+			expected: []interface{}{"s3:*", "s3express:CreateSession"},
+		},
+		{
+			name:     "equivalent patterns keep the first",
+			actions:  []interface{}{"s3:get*", "ec2:*", "S3:Get*"},
+			expected: []interface{}{"s3:get*", "ec2:*"},
+		},
+		{
+			name:     "single character wildcard",
+			actions:  []interface{}{"iam:GetRole", "iam:GetRol?", "iam:GetRolePolicy"},
+			expected: []interface{}{"iam:GetRol?", "iam:GetRolePolicy"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := consolidateActions(tt.actions)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestOptimizeStatements(t *testing.T) {
+	statements := []Statement{
+		newStatement(map[string]interface{}{
+			"Effect":   "Deny",
+			"Action":   []interface{}{"s3:*", "s3:GetObject", "s3:DeleteBucket"},
+			"Resource": "*",
+		}),
+		newStatement(map[string]interface{}{
+			"Effect":   "Deny",
+			"Action":   "s3:*",
+			"Resource": "*",
+		}),
+	}
+	statements[0].Source, statements[0].Index = "policy.json", 3
+	original := statements[0].Size
+
+	optimized := optimizeStatements(statements)
+
+	if !reflect.DeepEqual(optimized[0].Content["Action"], []interface{}{"s3:*"}) {
+		t.Errorf("Expected only s3:* to remain, got %v", optimized[0].Content["Action"])
+	}
+	if optimized[0].Size >= original {
+		t.Errorf("Expected optimized statement to shrink from %d, got %d", original, optimized[0].Size)
+	}
+	if optimized[0].Source != "policy.json" || optimized[0].Index != 3 {
+		t.Errorf("Expected source to be kept, got %s[%d]", optimized[0].Source, optimized[0].Index)
+	}
+	if !reflect.DeepEqual(optimized[1], statements[1]) {
+		t.Errorf("Expected unchanged statement to be kept as is")
+	}
+	if len(statements[0].Content["Action"].([]interface{})) != 3 {
+		t.Error("Expected input content to be left unmodified")
+	}
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// statementKeyOrder is the conventional AWS ordering of statement keys, anything else follows alphabetically
+var statementKeyOrder = []string{
+	"Sid",
+	"Effect",
+	"Principal",
+	"NotPrincipal",
+	"Action",
+	"NotAction",
+	"Resource",
+	"NotResource",
+	"Condition",
+}
+
+// orderedStatement marshals statement content with keys in canonical order
+type orderedStatement map[string]interface{}
+
+func (s orderedStatement) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range orderedKeys(s) {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(marshalJSON(key, "", ""))
+		buf.WriteByte(':')
+		buf.Write(marshalJSON(s[key], "", ""))
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// orderedKeys lists conventional keys first, then the remainder alphabetically
+func orderedKeys(content map[string]interface{}) []string {
+	var keys []string
+	known := make(map[string]bool, len(statementKeyOrder))
+	for _, key := range statementKeyOrder {
+		known[key] = true
+		if _, ok := content[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+
+	var rest []string
+	for key := range content {
+		if !known[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+// rawJSON returns the statement as written, falling back to canonical key order when no raw JSON is held
+func (s Statement) rawJSON() json.RawMessage {
+	if s.Raw != nil {
+		return s.Raw
+	}
+	raw, _ := orderedStatement(s.Content).MarshalJSON()
+	return raw
+}
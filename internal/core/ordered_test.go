@@ -0,0 +1,75 @@
+package core
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+func TestOrderedStatementMarshalJSON(t *testing.T) {
+	content := orderedStatement{
+		"Resource":  "*",
+		"Condition": map[string]interface{}{"Bool": map[string]interface{}{"aws:SecureTransport": "false"}},
+		"Zeta":      "z",
+		"Action":    []interface{}{"s3:GetObject"},
+		"Alpha":     "a",
+		"Effect":    "Deny",
+		"Sid":       "DenyInsecure",
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		t.Fatalf("Failed to marshal statement: %v", err)
+	}
+
+	expected := `{"Sid":"DenyInsecure","Effect":"Deny","Action":["s3:GetObject"],"Resource":"*",` +
+		`"Condition":{"Bool":{"aws:SecureTransport":"false"}},"Alpha":"a","Zeta":"z"}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestOrderedKeys(t *testing.T) {
+	content := map[string]interface{}{
+		"NotResource": "*",
+		"NotAction":   "iam:*",
+		"Effect":      "Deny",
+		"Principal":   "*",
+	}
+
+	expected := []string{"Effect", "Principal", "NotAction", "NotResource"}
+	if result := orderedKeys(content); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestWriteJSONKeyOrder(t *testing.T) {
+	statements := []Statement{
+		{Content: map[string]interface{}{"Resource": "*", "Action": "s3:*", "Effect": "Deny", "Sid": "DenyS3"}},
+	}
+
+	minified := string(writeJSON(inputs.UserInput{}, Header{Version: config.SCPVersion}, statements))
+	expected := `{"Version":"2012-10-17","Statement":[{"Sid":"DenyS3","Effect":"Deny","Action":"s3:*","Resource":"*"}]}`
+	if minified != expected {
+		t.Errorf("Expected %s, got %s", expected, minified)
+	}
+
+	indented := string(writeJSON(inputs.UserInput{Whitespace: true}, Header{Version: config.SCPVersion}, statements))
+	expectedIndented := `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "DenyS3",
+      "Effect": "Deny",
+      "Action": "s3:*",
+      "Resource": "*"
+    }
+  ]
+}`
+	if indented != expectedIndented {
+		t.Errorf("Expected %s, got %s", expectedIndented, indented)
+	}
+}
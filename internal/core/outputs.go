@@ -1,66 +1,165 @@
 package core
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/jakebark/corset/internal/config"
 	"github.com/jakebark/corset/internal/inputs"
 )
 
-func buildOutput(userInput inputs.UserInput, packedFiles [][]Statement, inputFiles []string) {
+// buildOutput writes the packed files, summarizing them against the size and statement count that were read
+func buildOutput(userInput inputs.UserInput, header Header, packedFiles [][]Statement, inputFiles []string, statementsRead int) (ResultsSummary, error) {
 	var outputDir string
-	if userInput.IsDirectory {
+	switch {
+	case userInput.IsDirectory:
 		// For directory replacement, output to the target directory itself
 		outputDir = userInput.Target
-	} else {
+	case userInput.IsArchive:
+		// For an archive, output alongside it and leave the archive in place
+		outputDir = filepath.Dir(userInput.Target)
+	default:
 		// For single file replacement, output to the same directory as the input file
-		outputDir = filepath.Dir(inputFiles[0])
+		outputDir = filepath.Dir(sourcePath(inputFiles[0]))
 	}
 
-	if !userInput.IsDirectory && len(inputFiles) == 1 {
-		// single file replacement, overwrite
-		results := orchestrateOutputFiles(userInput, packedFiles, outputDir, inputFiles)
-		reportResults(results)
-	} else {
-		// directory replacement
-		results := orchestrateOutputFiles(userInput, packedFiles, outputDir, inputFiles)
-		reportResults(results)
-		replaceInputFiles(userInput, inputFiles)
+	// measure inputs before they are overwritten or replaced
+	inputSize := totalFileSize(inputFiles)
+
+	// a template is written alongside the inputs, which are left in place
+	if isCloudFormation(userInput) {
+		results, err := writeTemplate(userInput, header, packedFiles, outputDir, inputFiles)
+		if err != nil {
+			return ResultsSummary{}, err
+		}
+		return newResultsSummary(inputFiles, results, inputSize, statementsRead), nil
+	}
+
+	results, err := orchestrateOutputFiles(userInput, header, packedFiles, outputDir, inputFiles)
+	if err != nil {
+		return ResultsSummary{}, err
+	}
+	// before any inputs are removed, so a mismatch loses nothing
+	if userInput.Verify {
+		if err := verifyOutputs(results, packedFiles); err != nil {
+			return newResultsSummary(inputFiles, results, inputSize, statementsRead), err
+		}
+	}
+	// directory replacement, inputs are only removed once every output is written.
+	// Single file replacement overwrites, and a named output leaves other inputs in place
+	if !userInput.IsArchive && userInput.Output == "" && (userInput.IsDirectory || len(inputFiles) > 1) {
+		replaceInputFiles(userInput, inputFiles, results)
 	}
+	return newResultsSummary(inputFiles, results, inputSize, statementsRead), nil
 }
 
-func orchestrateOutputFiles(userInput inputs.UserInput, packedFiles [][]Statement, outputDir string, inputFiles []string) []WriteResult {
-	var results []WriteResult
+// orchestrateOutputFiles writes the packed files concurrently, returning their results in file order.
+// Every write is attempted, and the errors of any that fail are joined.
+func orchestrateOutputFiles(userInput inputs.UserInput, header Header, packedFiles [][]Statement, outputDir string, inputFiles []string) ([]WriteResult, error) {
+	results := make([]WriteResult, len(packedFiles))
+	errs := make([]error, len(packedFiles))
+	var wg sync.WaitGroup
 	for i, statements := range packedFiles {
-		filename := generateOutputFilename(userInput, outputDir, i+1, inputFiles)
-		size := writeOutputFile(userInput, filename, statements)
-		results = append(results, WriteResult{
-			Filename:   filename,
-			Size:       size,
-			Statements: len(statements),
-		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			filename := generateOutputFilename(userInput, outputDir, i+1, inputFiles)
+			result, err := writeOutputFile(userInput, header, filename, statements, inputFiles)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i] = result
+			if userInput.Gzip {
+				if info, err := os.Stat(filename); err == nil {
+					results[i].Compressed = int(info.Size())
+				}
+			}
+		}()
 	}
-	return results
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		var written []WriteResult
+		for i, result := range results {
+			if errs[i] == nil {
+				written = append(written, result)
+			}
+		}
+		return written, err
+	}
+
+	if userInput.Manifest {
+		if err := writeManifest(filepath.Join(outputDir, config.ManifestFilename), packedFiles, results); err != nil {
+			return results, err
+		}
+	}
+	if userInput.Report == config.ReportCSV {
+		if err := writeCSVReport(filepath.Join(outputDir, config.ReportFilename), results, maxPolicySize(userInput)); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
 }
 
 func generateOutputFilename(userInput inputs.UserInput, outputDir string, fileNum int, inputFiles []string) string {
+	// written exactly as named, so --gzip doesn't add .gz
+	if userInput.Output != "" {
+		return userInput.Output
+	}
+	filename := outputFilename(userInput, outputDir, fileNum, inputFiles)
+	if userInput.Gzip {
+		return filename + ".gz"
+	}
+	return filename
+}
+
+func outputFilename(userInput inputs.UserInput, outputDir string, fileNum int, inputFiles []string) string {
+	if userInput.NameTemplate != "" {
+		base, ext := outputBase(userInput, inputFiles)
+		return filepath.Join(outputDir, expandNameTemplate(userInput.NameTemplate, base, fileNum, ext))
+	}
+
+	if userInput.IsArchive {
+		// use the archive as base name, add numeric suffix for splits
+		baseName := strings.TrimSuffix(filepath.Base(userInput.Target), ".zip")
+		if fileNum == 1 {
+			return filepath.Join(outputDir, baseName+".json")
+		}
+		return filepath.Join(outputDir, fmt.Sprintf("%s-%d.json", baseName, fileNum))
+	}
+
 	if !userInput.IsDirectory && len(inputFiles) == 1 {
 		// single file, use original name
-		originalFile := inputFiles[0]
+		// output is uncompressed, write alongside the gzip source
+		originalFile := strings.TrimSuffix(inputFiles[0], ".gz")
+		ext := filepath.Ext(originalFile)
+		nameWithoutExt := originalFile[:len(originalFile)-len(ext)]
+		if isYAMLFile(originalFile) || isJSONLFile(originalFile) {
+			// output is always a JSON policy, write alongside the source
+			originalFile = nameWithoutExt + ".json"
+			ext = ".json"
+		}
 		if fileNum == 1 {
 			return originalFile
 		}
 		// add numeric suffix for splits
-		ext := filepath.Ext(originalFile)
-		nameWithoutExt := originalFile[:len(originalFile)-len(ext)]
 		return fmt.Sprintf("%s-%d%s", nameWithoutExt, fileNum, ext)
 
 	} else if userInput.IsDirectory {
-		// use target as base name, add numeric suffix for splits
-		baseName := filepath.Base(userInput.Target)
+		// use target, or the prefix group, as base name, add numeric suffix for splits
+		baseName := directoryBase(userInput)
 		if fileNum == 1 {
 			return filepath.Join(outputDir, baseName+".json")
 		}
@@ -71,40 +170,453 @@ func generateOutputFilename(userInput inputs.UserInput, outputDir string, fileNu
 	return filepath.Join(outputDir, fmt.Sprintf("corset%d.json", fileNum))
 }
 
-func writeOutputFile(userInput inputs.UserInput, filename string, statements []Statement) int {
-	data := writeJSON(userInput, statements)
-	os.WriteFile(filename, data, 0644)
-	return len(data)
+// outputBase returns the {base} and {ext} of a --name-template, matching the default naming
+func outputBase(userInput inputs.UserInput, inputFiles []string) (string, string) {
+	switch {
+	case userInput.IsArchive:
+		return strings.TrimSuffix(filepath.Base(userInput.Target), ".zip"), ".json"
+	case !userInput.IsDirectory && len(inputFiles) == 1:
+		originalFile := filepath.Base(strings.TrimSuffix(inputFiles[0], ".gz"))
+		ext := filepath.Ext(originalFile)
+		base := originalFile[:len(originalFile)-len(ext)]
+		if isYAMLFile(originalFile) || isJSONLFile(originalFile) {
+			ext = ".json"
+		}
+		return base, ext
+	case userInput.IsDirectory:
+		return directoryBase(userInput), ".json"
+	}
+	return "corset", ".json"
 }
 
-func writeJSON(userInput inputs.UserInput, statements []Statement) []byte {
-	policy := Policy{
-		Version:   config.SCPVersion,
-		Statement: make([]map[string]interface{}, len(statements)),
+// directoryBase names a directory's outputs after it, or after the --group-by-prefix group being packed
+func directoryBase(userInput inputs.UserInput) string {
+	if userInput.Group != "" {
+		return userInput.Group
 	}
+	return filepath.Base(userInput.Target)
+}
 
-	for i, stmt := range statements {
-		policy.Statement[i] = stmt.Content
+// expandNameTemplate fills the {base}, {index} and {ext} placeholders
+func expandNameTemplate(template, base string, fileNum int, ext string) string {
+	return strings.NewReplacer(
+		"{base}", base,
+		"{index}", strconv.Itoa(fileNum),
+		"{ext}", ext,
+	).Replace(template)
+}
+
+// writeOutputFile returns the file's result, sized as AWS counts it even when the file is indented or gzipped.
+// A file already holding exactly this content is left untouched, and marked unchanged.
+// An existing file is only overwritten when it is one of the inputs being replaced, or with --force.
+func writeOutputFile(userInput inputs.UserInput, header Header, filename string, statements []Statement, inputFiles []string) (WriteResult, error) {
+	if err := checkOverwrite(userInput, filename, inputFiles); err != nil {
+		return WriteResult{}, err
 	}
 
+	data := writeJSON(userInput, header, statements)
+	result := WriteResult{
+		Filename:   filename,
+		Size:       effectiveSize(userInput, header, statements, data),
+		Statements: len(statements),
+	}
 	if userInput.Whitespace {
-		data, _ := json.MarshalIndent(policy, "", "  ")
-		return data
+		result.OnDisk = utf8.RuneCount(data)
+	}
+
+	contents := data
+	if userInput.Gzip {
+		contents = gzipData(data)
+	}
+	// rewriting identical content would only bump the modification time
+	if existing, err := os.ReadFile(filename); err == nil && bytes.Equal(existing, contents) {
+		result.Unchanged = true
+		return result, nil
+	}
+	err := writeFileAtomic(filename, func(w io.Writer) error {
+		_, err := w.Write(contents)
+		return err
+	})
+	if err != nil {
+		return WriteResult{}, fmt.Errorf("writing %s: %w", filename, err)
+	}
+	return result, nil
+}
+
+// effectiveSize returns the characters AWS counts for a policy written as data. AWS ignores insignificant
+// whitespace, so indented output is measured minified.
+func effectiveSize(userInput inputs.UserInput, header Header, statements []Statement, data []byte) int {
+	if !userInput.Whitespace {
+		return utf8.RuneCount(data)
+	}
+	userInput.Whitespace = false
+	return utf8.RuneCount(writeJSON(userInput, header, statements))
+}
+
+// checkOverwrite errors when filename exists, unless it is an input being replaced, an output the last
+// --watch run wrote, or --force is set
+func checkOverwrite(userInput inputs.UserInput, filename string, inputFiles []string) error {
+	if userInput.Force || isInputFile(filename, inputFiles) || isInputFile(filename, userInput.Replaceable) {
+		return nil
+	}
+	if _, err := os.Lstat(filename); err == nil {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", filename)
+	}
+	return nil
+}
+
+func isInputFile(filename string, inputFiles []string) bool {
+	for _, inputFile := range inputFiles {
+		// inputs found in a directory are absolute, while outputs follow the target as given
+		if absPath(inputFile) == absPath(filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFileAtomic writes to a temp file in the same directory and renames it into place,
+// so an existing file is never left partially written. A replaced file keeps its mode and, where
+// permitted, its owner and group.
+func writeFileAtomic(filename string, write func(io.Writer) error) error {
+	mode := os.FileMode(0644)
+	info, statErr := os.Stat(filename)
+	if statErr == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	// a no-op once the rename has succeeded
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if statErr == nil {
+		preserveOwner(tmp, info)
+	}
+	// flush to disk before the rename, so a crash can't leave the file renamed into place but empty
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+func gzipData(data []byte) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write(data)
+	writer.Close()
+	return buf.Bytes()
+}
+
+// writeJSON assembles the policy from each statement's stored JSON, so no statement is marshaled again.
+// Only the header strings are encoded, and whitespace output is indented in a single pass.
+func writeJSON(userInput inputs.UserInput, header Header, statements []Statement) []byte {
+	size := len(`{"Version":"","Id":"","Statement":[]}`) + len(header.Version) + len(header.Id)
+	for _, stmt := range statements {
+		size += len(stmt.rawJSON()) + 1
+	}
+	var buf bytes.Buffer
+	buf.Grow(size)
+	buf.WriteString(`{"Version":`)
+	writeJSONString(&buf, header.Version)
+	if header.Id != "" {
+		buf.WriteString(`,"Id":`)
+		writeJSONString(&buf, header.Id)
+	}
+	buf.WriteString(`,"Statement":[`)
+	for i, stmt := range statements {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(stmt.rawJSON())
+	}
+	buf.WriteString("]}")
+
+	if !userInput.Whitespace {
+		return buf.Bytes()
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, buf.Bytes(), "", indentString(userInput)); err != nil {
+		// only malformed raw statements fail to indent, and the minified bytes still hold them as read
+		logger(userInput).Warn("could not indent the policy, writing it minified", "error", err)
+		return buf.Bytes()
+	}
+	return indented.Bytes()
+}
+
+// indentString returns the indent for whitespace output
+func indentString(userInput inputs.UserInput) string {
+	if userInput.Indent == "" {
+		return config.DefaultIndent
+	}
+	return userInput.Indent
+}
+
+// writeJSONString writes a JSON string, only going through the encoder when it needs escaping
+func writeJSONString(buf *bytes.Buffer, s string) {
+	for _, r := range s {
+		if r < 0x20 || r == '"' || r == '\\' || r == '\u2028' || r == '\u2029' || r == utf8.RuneError {
+			buf.Write(marshalJSON(s, "", ""))
+			return
+		}
+	}
+	buf.WriteByte('"')
+	buf.WriteString(s)
+	buf.WriteByte('"')
+}
+
+// marshalJSON encodes without HTML escaping, so <, > and & are kept literally and count as one character.
+// An empty indent produces minified output.
+func marshalJSON(v interface{}, prefix, indent string) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if indent != "" {
+		encoder.SetIndent(prefix, indent)
+	}
+	encoder.Encode(v)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// ReportResults prints the summary of a run to stderr, or the summary as JSON on stdout with --json
+func ReportResults(userInput inputs.UserInput, summary ResultsSummary) {
+	if len(summary.Files) == 0 {
+		return
+	}
+	if userInput.JSON {
+		fmt.Println(string(resultsJSON(summary)))
+		return
+	}
+	if userInput.Quiet {
+		return
+	}
+
+	color := useColor(userInput)
+	if isCloudFormation(userInput) {
+		fmt.Fprintf(os.Stderr, "Wrote %d policies to %s:\n", len(summary.Files), filepath.Base(summary.Files[0].Filename))
+		for _, result := range summary.Files {
+			fmt.Fprintf(os.Stderr, "- %s (%s, %s, %d statements)\n", result.Resource,
+				formatFullness(result.Size, maxPolicySize(userInput), color), formatRemaining(result.Size, packingLimit(userInput)), result.Statements)
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "Split into %d files:\n", len(summary.Files))
+		for _, result := range summary.Files {
+			fmt.Fprintln(os.Stderr, formatResult(userInput, result, color))
+		}
+	}
+	fmt.Fprintln(os.Stderr, savingsSummary(summary.InputSize, summary.Files))
+	fmt.Fprintln(os.Stderr, statementSummary(summary.StatementsRead, summary.Files))
+}
+
+// formatResult describes one written file, coloring its size by how close it is to the limit
+func formatResult(userInput inputs.UserInput, result WriteResult, color bool) string {
+	details := fmt.Sprintf("%s, %s, %d statements",
+		formatFullness(result.Size, maxPolicySize(userInput), color), formatRemaining(result.Size, packingLimit(userInput)), result.Statements)
+	if result.OnDisk > 0 {
+		details += fmt.Sprintf(", %s chars on disk", formatCount(result.OnDisk))
+	}
+	if result.Compressed > 0 {
+		details += fmt.Sprintf(", %d bytes gzipped", result.Compressed)
+	}
+	if result.Unchanged {
+		details += ", no changes"
+	}
+	return fmt.Sprintf("- %s (%s)", filepath.Base(result.Filename), details)
+}
+
+// formatRemaining shows the characters left before the limit, less any --headroom, e.g. 140 remaining
+func formatRemaining(size, limit int) string {
+	if size > limit {
+		return fmt.Sprintf("%s over", formatCount(size-limit))
+	}
+	return fmt.Sprintf("%s remaining", formatCount(limit-size))
+}
+
+// formatFullness shows a size against the limit, e.g. 4,980/5,120 chars, 97%
+func formatFullness(size, limit int, color bool) string {
+	fullness := fmt.Sprintf("%s/%s chars, %d%%", formatCount(size), formatCount(limit), fullnessPercent(size, limit))
+	if color {
+		return colorize(sizeColor(size, limit), fullness)
+	}
+	return fullness
+}
+
+// fullnessPercent is how much of the limit a size uses, rounded down so a file is only 100% when full
+func fullnessPercent(size, limit int) int {
+	if limit <= 0 {
+		return 0
+	}
+	return size * 100 / limit
+}
+
+// newResultsSummary totals the results written from the input files
+func newResultsSummary(inputFiles []string, results []WriteResult, inputSize, statementsRead int) ResultsSummary {
+	return ResultsSummary{
+		Inputs:            inputFiles,
+		Files:             results,
+		InputSize:         inputSize,
+		OutputSize:        totalOutputSize(results),
+		StatementsRead:    statementsRead,
+		StatementsWritten: totalStatements(results),
 	}
-	data, _ := json.Marshal(policy)
-	return data
 }
 
-func reportResults(results []WriteResult) {
-	fmt.Printf("Split into %d files:\n", len(results))
+// add totals another group's summary into this one
+func (s *ResultsSummary) add(other ResultsSummary) {
+	s.Inputs = append(s.Inputs, other.Inputs...)
+	s.Files = append(s.Files, other.Files...)
+	s.InputSize += other.InputSize
+	s.OutputSize += other.OutputSize
+	s.StatementsRead += other.StatementsRead
+	s.StatementsWritten += other.StatementsWritten
+}
+
+// resultsJSON returns the summary as a single line of JSON, for scripts to parse
+func resultsJSON(summary ResultsSummary) []byte {
+	if summary.Inputs == nil {
+		summary.Inputs = []string{}
+	}
+	if summary.Files == nil {
+		summary.Files = []WriteResult{}
+	}
+	return marshalJSON(summary, "", "")
+}
+
+// totalOutputSize returns the characters written, on disk like the input size it is compared with
+func totalOutputSize(results []WriteResult) int {
+	outputSize := 0
+	for _, result := range results {
+		if result.OnDisk > 0 {
+			outputSize += result.OnDisk
+			continue
+		}
+		outputSize += result.Size
+	}
+	return outputSize
+}
+
+func totalStatements(results []WriteResult) int {
+	statements := 0
 	for _, result := range results {
-		fmt.Printf("- %s (%d characters, %d statements)\n",
-			filepath.Base(result.Filename), result.Size, result.Statements)
+		statements += result.Statements
+	}
+	return statements
+}
+
+// statementSummary compares the statements read against those written, which only differ when
+// --dedupe, --merge or --optimize removed or combined some
+func statementSummary(statementsRead int, results []WriteResult) string {
+	written := totalStatements(results)
+	if written == statementsRead {
+		return fmt.Sprintf("Statements: %d in, %d out", statementsRead, written)
+	}
+	return fmt.Sprintf("Statements: %d in, %d out (%d removed or merged)", statementsRead, written, statementsRead-written)
+}
+
+// savingsSummary compares total input characters against total output characters
+func savingsSummary(inputSize int, results []WriteResult) string {
+	outputSize := totalOutputSize(results)
+
+	if inputSize == 0 {
+		return fmt.Sprintf("Wrote %s chars", formatCount(outputSize))
 	}
+	if outputSize > inputSize {
+		percent := (outputSize - inputSize) * 100 / inputSize
+		return fmt.Sprintf("Increased %s -> %s chars (%d%% larger)",
+			formatCount(inputSize), formatCount(outputSize), percent)
+	}
+	percent := (inputSize - outputSize) * 100 / inputSize
+	return fmt.Sprintf("Reduced %s -> %s chars (%d%% smaller)",
+		formatCount(inputSize), formatCount(outputSize), percent)
 }
 
-func replaceInputFiles(userInput inputs.UserInput, inputFiles []string) {
+// formatCount renders a number with thousands separators, e.g. 12,340
+func formatCount(n int) string {
+	if n < 0 {
+		return "-" + formatCount(-n)
+	}
+	digits := strconv.Itoa(n)
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return digits
+}
+
+// totalFileSize sums the character count of the given files, decompressed
+func totalFileSize(files []string) int {
+	total := 0
+	for _, file := range files {
+		if chars, err := fileChars(file); err == nil {
+			total += chars
+		}
+	}
+	return total
+}
+
+// fileChars counts the characters of a file, decompressed, a buffer at a time rather than reading it whole
+func fileChars(filename string) (int, error) {
+	// archive entries are read whole, as they are for their statements
+	if _, _, inArchive := splitArchivePath(filename); inArchive {
+		data, err := ReadPolicyFile(filename)
+		return utf8.RuneCount(data), err
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	if magic, _ := reader.Peek(len(gzipMagic)); isGzipFile(filename) || bytes.Equal(magic, gzipMagic) {
+		decompressed, err := gzip.NewReader(reader)
+		if err != nil {
+			return 0, err
+		}
+		defer decompressed.Close()
+		reader = bufio.NewReader(decompressed)
+	}
+	chars := 0
+	for {
+		// invalid UTF-8 reads as one character a byte, as utf8.RuneCount counts it
+		if _, _, err := reader.ReadRune(); err != nil {
+			if err == io.EOF {
+				return chars, nil
+			}
+			return chars, err
+		}
+		chars++
+	}
+}
+
+// replaceInputFiles removes the inputs once every output is written, keeping any an output was written over
+func replaceInputFiles(userInput inputs.UserInput, inputFiles []string, results []WriteResult) {
+	written := make([]string, len(results))
+	for i, result := range results {
+		written[i] = result.Filename
+	}
 	for _, inputFile := range inputFiles {
+		// an input can share its name with an output, e.g. teamA-2.json with --group-by-prefix
+		if isInputFile(inputFile, written) {
+			continue
+		}
+		// an archive's entries are read in place, and never removed
+		if _, _, inArchive := splitArchivePath(inputFile); inArchive {
+			continue
+		}
 		os.Remove(inputFile)
 	}
 }
@@ -1,8 +1,12 @@
 package core
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -90,7 +94,7 @@ func TestCreatePolicyJSON(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			data := writeJSON(tt.userInput, tt.statements)
+			data := writeJSON(tt.userInput, Header{Version: config.SCPVersion}, tt.statements)
 
 			// Verify it's valid JSON
 			var policy Policy
@@ -189,7 +193,10 @@ func TestWriteOutputFile(t *testing.T) {
 			tempDir := t.TempDir()
 			outputFile := filepath.Join(tempDir, tt.filename)
 
-			size := writeOutputFile(tt.userInput, outputFile, tt.statements)
+			result, err := writeOutputFile(tt.userInput, Header{Version: config.SCPVersion}, outputFile, tt.statements, nil)
+			if err != nil {
+				t.Fatalf("writeOutputFile failed: %v", err)
+			}
 
 			// Verify file was created
 			if _, err := os.Stat(outputFile); os.IsNotExist(err) {
@@ -202,9 +209,18 @@ func TestWriteOutputFile(t *testing.T) {
 				t.Fatalf("Failed to read output file: %v", err)
 			}
 
-			// Verify size matches
-			if size != len(data) {
-				t.Errorf("Expected size %d, got %d", len(data), size)
+			// Verify size matches, measured minified as AWS counts it
+			var minified bytes.Buffer
+			json.Compact(&minified, data)
+			if result.Size != len([]rune(minified.String())) {
+				t.Errorf("Expected size %d, got %d", len([]rune(minified.String())), result.Size)
+			}
+			onDisk := 0
+			if tt.userInput.Whitespace {
+				onDisk = len([]rune(string(data)))
+			}
+			if result.OnDisk != onDisk {
+				t.Errorf("Expected %d characters on disk, got %d", onDisk, result.OnDisk)
 			}
 
 			// Verify it's valid JSON
@@ -296,7 +312,10 @@ func TestWriteAllPolicyFiles(t *testing.T) {
 
 			// Create mock input files for testing
 			inputFiles := []string{filepath.Join(outputDir, "input.json")}
-			results := orchestrateOutputFiles(tt.userInput, tt.packedFiles, outputDir, inputFiles)
+			results, err := orchestrateOutputFiles(tt.userInput, Header{Version: config.SCPVersion}, tt.packedFiles, outputDir, inputFiles)
+			if err != nil {
+				t.Fatalf("orchestrateOutputFiles failed: %v", err)
+			}
 
 			if len(results) != tt.expected {
 				t.Errorf("Expected %d results, got %d", tt.expected, len(results))
@@ -339,6 +358,71 @@ func TestWriteAllPolicyFiles(t *testing.T) {
 	}
 }
 
+func TestWriteAllPolicyFilesConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	inputFiles := []string{filepath.Join(dir, "input.json")}
+	var packedFiles [][]Statement
+	for i := 0; i < 20; i++ {
+		action := fmt.Sprintf("service%d:*", i+1)
+		packedFiles = append(packedFiles, []Statement{{Content: map[string]interface{}{"Effect": "Deny", "Action": action, "Resource": "*"}}})
+	}
+
+	results, err := orchestrateOutputFiles(inputs.UserInput{}, Header{Version: config.SCPVersion}, packedFiles, dir, inputFiles)
+	if err != nil {
+		t.Fatalf("orchestrateOutputFiles failed: %v", err)
+	}
+	if len(results) != len(packedFiles) {
+		t.Fatalf("Expected %d results, got %d", len(packedFiles), len(results))
+	}
+	for i, result := range results {
+		expected := generateOutputFilename(inputs.UserInput{}, dir, i+1, inputFiles)
+		if result.Filename != expected {
+			t.Errorf("Result %d: expected %s, got %s", i, expected, result.Filename)
+		}
+		data, err := os.ReadFile(result.Filename)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", result.Filename, err)
+		}
+		if action := fmt.Sprintf(`"service%d:*"`, i+1); !strings.Contains(string(data), action) {
+			t.Errorf("Expected %s to contain %s, got %s", result.Filename, action, data)
+		}
+	}
+}
+
+func TestWriteAllPolicyFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	inputFiles := []string{filepath.Join(dir, "input.json")}
+	var packedFiles [][]Statement
+	for i := 0; i < 4; i++ {
+		packedFiles = append(packedFiles, []Statement{{Content: map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}}})
+	}
+
+	// files 2 and 4 already exist and are not inputs, so both are refused
+	for _, name := range []string{"input-2.json", "input-4.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(`{}`), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	results, err := orchestrateOutputFiles(inputs.UserInput{}, Header{Version: config.SCPVersion}, packedFiles, dir, inputFiles)
+	if err == nil {
+		t.Fatal("Expected an error for the existing files")
+	}
+	for _, name := range []string{"input-2.json", "input-4.json"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected the error to name %s, got %v", name, err)
+		}
+	}
+
+	var written []string
+	for _, result := range results {
+		written = append(written, filepath.Base(result.Filename))
+	}
+	if strings.Join(written, ",") != "input.json,input-3.json" {
+		t.Errorf("Expected results for input.json and input-3.json, got %v", written)
+	}
+}
+
 func TestReportResults(t *testing.T) {
 	// This is a bit tricky to test since it prints to stdout
 	// We'll test that it doesn't panic and basic validation
@@ -382,15 +466,94 @@ func TestReportResults(t *testing.T) {
 			// Just verify it doesn't panic
 			defer func() {
 				if r := recover(); r != nil {
-					t.Errorf("reportResults panicked: %v", r)
+					t.Errorf("ReportResults panicked: %v", r)
 				}
 			}()
 
-			reportResults(tt.results)
+			ReportResults(inputs.UserInput{}, newResultsSummary(nil, tt.results, 500, 0))
 		})
 	}
 }
 
+func TestResultsJSON(t *testing.T) {
+	results := []WriteResult{
+		{Filename: "/tmp/corset1.json", Size: 150, Statements: 2},
+		{Filename: "/tmp/corset2.json.gz", Size: 100, Statements: 1, Compressed: 80},
+	}
+
+	var summary ResultsSummary
+	if err := json.Unmarshal(resultsJSON(newResultsSummary([]string{"/tmp/policy.json"}, results, 500, 4)), &summary); err != nil {
+		t.Fatalf("Expected valid JSON: %v", err)
+	}
+	if len(summary.Inputs) != 1 || summary.Inputs[0] != "/tmp/policy.json" {
+		t.Errorf("Expected the input file, got %v", summary.Inputs)
+	}
+	if len(summary.Files) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(summary.Files))
+	}
+	for i, result := range results {
+		if summary.Files[i] != result {
+			t.Errorf("Expected file %d to be %+v, got %+v", i, result, summary.Files[i])
+		}
+	}
+	if summary.InputSize != 500 || summary.OutputSize != 250 {
+		t.Errorf("Expected sizes 500 -> 250, got %d -> %d", summary.InputSize, summary.OutputSize)
+	}
+	if summary.StatementsRead != 4 || summary.StatementsWritten != 3 {
+		t.Errorf("Expected statements 4 -> 3, got %d -> %d", summary.StatementsRead, summary.StatementsWritten)
+	}
+
+	// no results is an empty list rather than null
+	if data := string(resultsJSON(ResultsSummary{})); !strings.Contains(data, `"files":[]`) {
+		t.Errorf("Expected an empty files list, got %s", data)
+	}
+}
+
+func TestStatementSummary(t *testing.T) {
+	results := []WriteResult{{Statements: 2}, {Statements: 1}}
+
+	tests := []struct {
+		name     string
+		read     int
+		expected string
+	}{
+		{name: "all written", read: 3, expected: "Statements: 3 in, 3 out"},
+		{name: "some removed", read: 5, expected: "Statements: 5 in, 3 out (2 removed or merged)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := statementSummary(tt.read, results); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestProcessFilesStatementCounts(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "policy.json")
+	policy := `{"Version":"2012-10-17","Statement":[
+		{"Effect":"Deny","Action":"s3:*","Resource":"*"},
+		{"Sid":"Copy","Effect":"Deny","Action":"s3:*","Resource":"*"},
+		{"Effect":"Deny","Action":"ec2:*","Resource":"*"}]}`
+	if err := os.WriteFile(testFile, []byte(policy), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	userInput := inputs.UserInput{Target: testFile, MaxFiles: config.DefaultMaxFiles, Dedupe: true}
+	summary, err := ProcessFiles(userInput, []string{testFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	results := summary.Files
+
+	// three read, the duplicate removed
+	expected := "Statements: 3 in, 2 out (1 removed or merged)"
+	if summary := statementSummary(3, results); summary != expected {
+		t.Errorf("Expected %q, got %q", expected, summary)
+	}
+}
+
 func TestReplaceInputFiles(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -424,7 +587,7 @@ func TestReplaceInputFiles(t *testing.T) {
 			inputFiles := []string{testFile}
 
 			// Test the function - should always delete files
-			replaceInputFiles(tt.userInput, inputFiles)
+			replaceInputFiles(tt.userInput, inputFiles, nil)
 
 			// Check if file was deleted (replacement is now automatic)
 			_, err = os.Stat(testFile)
@@ -479,7 +642,7 @@ func TestWriteOutputFiles(t *testing.T) {
 				}
 			}()
 
-			buildOutput(tt.userInput, tt.packedFiles, tt.inputFiles)
+			buildOutput(tt.userInput, Header{Version: config.SCPVersion}, tt.packedFiles, tt.inputFiles, 0)
 
 			// Verify output files were created - with automatic replacement, check the input file was replaced
 			if _, err := os.Stat(inputFile); os.IsNotExist(err) {
@@ -519,6 +682,107 @@ func TestGenerateOutputFilename(t *testing.T) {
 			inputFiles: []string{"/path/to/policy.json"},
 			expected:   "/path/to/policy.json",
 		},
+		{
+			name: "single YAML file",
+			userInput: inputs.UserInput{
+				IsDirectory: false,
+				Target:      "/path/to/policy.yaml",
+			},
+			outputDir:  "/output",
+			fileNum:    1,
+			inputFiles: []string{"/path/to/policy.yaml"},
+			expected:   "/path/to/policy.json",
+		},
+		{
+			name: "single YAML file, second file",
+			userInput: inputs.UserInput{
+				IsDirectory: false,
+				Target:      "/path/to/policy.yml",
+			},
+			outputDir:  "/output",
+			fileNum:    2,
+			inputFiles: []string{"/path/to/policy.yml"},
+			expected:   "/path/to/policy-2.json",
+		},
+		{
+			name: "single gzip file",
+			userInput: inputs.UserInput{
+				IsDirectory: false,
+				Target:      "/path/to/policy.json.gz",
+			},
+			outputDir:  "/output",
+			fileNum:    1,
+			inputFiles: []string{"/path/to/policy.json.gz"},
+			expected:   "/path/to/policy.json",
+		},
+		{
+			name: "single gzip file, second file",
+			userInput: inputs.UserInput{
+				IsDirectory: false,
+				Target:      "/path/to/policy.json.gz",
+			},
+			outputDir:  "/output",
+			fileNum:    2,
+			inputFiles: []string{"/path/to/policy.json.gz"},
+			expected:   "/path/to/policy-2.json",
+		},
+		{
+			name: "single JSONL file",
+			userInput: inputs.UserInput{
+				IsDirectory: false,
+				Target:      "/path/to/statements.jsonl",
+			},
+			outputDir:  "/output",
+			fileNum:    1,
+			inputFiles: []string{"/path/to/statements.jsonl"},
+			expected:   "/path/to/statements.json",
+		},
+		{
+			name: "directory replacement, gzip",
+			userInput: inputs.UserInput{
+				IsDirectory: true,
+				Target:      "/path/to/organisation-scp",
+				Gzip:        true,
+			},
+			outputDir:  "/path/to/organisation-scp",
+			fileNum:    2,
+			inputFiles: []string{"/path/to/organisation-scp/policy1.json"},
+			expected:   "/path/to/organisation-scp/organisation-scp-2.json.gz",
+		},
+		{
+			name: "single file, name template",
+			userInput: inputs.UserInput{
+				Target:       "/path/to/policy.yaml",
+				NameTemplate: "{base}.part{index}{ext}",
+			},
+			outputDir:  "/path/to",
+			fileNum:    1,
+			inputFiles: []string{"/path/to/policy.yaml"},
+			expected:   "/path/to/policy.part1.json",
+		},
+		{
+			name: "directory, name template, gzip",
+			userInput: inputs.UserInput{
+				IsDirectory:  true,
+				Target:       "/path/to/organisation-scp",
+				NameTemplate: "scp-{index}-{base}.json",
+				Gzip:         true,
+			},
+			outputDir:  "/path/to/organisation-scp",
+			fileNum:    3,
+			inputFiles: []string{"/path/to/organisation-scp/policy1.json"},
+			expected:   "/path/to/organisation-scp/scp-3-organisation-scp.json.gz",
+		},
+		{
+			name: "several files, name template",
+			userInput: inputs.UserInput{
+				NameTemplate: "{base}{index}{ext}",
+			},
+			outputDir:  "/path/to",
+			fileNum:    2,
+			inputFiles: []string{"/path/to/a.json", "/path/to/b.json"},
+			expected:   "/path/to/corset2.json",
+		},
 		{
 			name: "directory replacement, first file",
 			userInput: inputs.UserInput{
@@ -552,3 +816,472 @@ func TestGenerateOutputFilename(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteJSONVersion(t *testing.T) {
+	statements := []Statement{
+		{Content: map[string]interface{}{"Effect": "Deny", "Action": "*", "Resource": "*"}, Size: 50},
+	}
+
+	data := writeJSON(inputs.UserInput{}, Header{Version: "2008-10-17"}, statements)
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		t.Fatalf("Generated invalid JSON: %v", err)
+	}
+	if policy.Version != "2008-10-17" {
+		t.Errorf("Expected version 2008-10-17, got %s", policy.Version)
+	}
+}
+
+func TestWriteJSONId(t *testing.T) {
+	statements := []Statement{
+		{Content: map[string]interface{}{"Effect": "Deny", "Action": "*", "Resource": "*"}, Size: 50},
+	}
+
+	withId := string(writeJSON(inputs.UserInput{}, Header{Version: config.SCPVersion, Id: "GuardRails"}, statements))
+	if !strings.Contains(withId, `"Id":"GuardRails"`) {
+		t.Errorf("Expected Id in output, got %s", withId)
+	}
+
+	withoutId := string(writeJSON(inputs.UserInput{}, Header{Version: config.SCPVersion}, statements))
+	if strings.Contains(withoutId, `"Id"`) {
+		t.Errorf("Expected no Id in output, got %s", withoutId)
+	}
+}
+
+func TestSavingsSummary(t *testing.T) {
+	tests := []struct {
+		name      string
+		inputSize int
+		results   []WriteResult
+		expected  string
+	}{
+		{
+			name:      "reduced",
+			inputSize: 12340,
+			results: []WriteResult{
+				{Filename: "corset1.json", Size: 5000, Statements: 10},
+				{Filename: "corset2.json", Size: 3102, Statements: 6},
+			},
+			expected: "Reduced 12,340 -> 8,102 chars (34% smaller)",
+		},
+		{
+			name:      "increased",
+			inputSize: 100,
+			results: []WriteResult{
+				{Filename: "corset1.json", Size: 150, Statements: 1},
+			},
+			expected: "Increased 100 -> 150 chars (50% larger)",
+		},
+		{
+			name:      "unchanged",
+			inputSize: 1000,
+			results: []WriteResult{
+				{Filename: "corset1.json", Size: 1000, Statements: 1},
+			},
+			expected: "Reduced 1,000 -> 1,000 chars (0% smaller)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := savingsSummary(tt.inputSize, tt.results)
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatResultRemaining(t *testing.T) {
+	result := WriteResult{Filename: "/tmp/policy.json", Size: 4000, Statements: 12}
+
+	tests := []struct {
+		name      string
+		userInput inputs.UserInput
+		expected  string
+	}{
+		{name: "default limit", expected: "- policy.json (4,000/5,120 chars, 78%, 1,120 remaining, 12 statements)"},
+		{name: "max-size", userInput: inputs.UserInput{MaxSize: 4500}, expected: "- policy.json (4,000/4,500 chars, 88%, 500 remaining, 12 statements)"},
+		{name: "headroom", userInput: inputs.UserInput{Headroom: 1000}, expected: "- policy.json (4,000/5,120 chars, 78%, 120 remaining, 12 statements)"},
+		{name: "iam", userInput: inputs.UserInput{PolicyType: config.PolicyTypeIAM}, expected: "- policy.json (4,000/6,144 chars, 65%, 2,144 remaining, 12 statements)"},
+		{name: "over", userInput: inputs.UserInput{MaxSize: 3500}, expected: "- policy.json (4,000/3,500 chars, 114%, 500 over, 12 statements)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if line := formatResult(tt.userInput, result, false); line != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, line)
+			}
+		})
+	}
+}
+
+func TestFormatFullness(t *testing.T) {
+	tests := []struct {
+		size     int
+		limit    int
+		expected string
+	}{
+		{size: 4980, limit: 5120, expected: "4,980/5,120 chars, 97%"},
+		{size: 5120, limit: 5120, expected: "5,120/5,120 chars, 100%"},
+		{size: 5119, limit: 5120, expected: "5,119/5,120 chars, 99%"},
+		{size: 0, limit: 5120, expected: "0/5,120 chars, 0%"},
+		{size: 3072, limit: 6144, expected: "3,072/6,144 chars, 50%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if result := formatFullness(tt.size, tt.limit, false); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	tests := map[int]string{
+		0:       "0",
+		999:     "999",
+		1000:    "1,000",
+		12340:   "12,340",
+		1234567: "1,234,567",
+	}
+	for n, expected := range tests {
+		if result := formatCount(n); result != expected {
+			t.Errorf("formatCount(%d): expected %s, got %s", n, expected, result)
+		}
+	}
+}
+
+func TestTotalFileSize(t *testing.T) {
+	tempDir := t.TempDir()
+	var files []string
+	for i, content := range []string{`{"a": 1}`, `{"Version": "2012-10-17"}`} {
+		filename := filepath.Join(tempDir, fmt.Sprintf("input%d.json", i))
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+		files = append(files, filename)
+	}
+
+	if size := totalFileSize(files); size != 33 {
+		t.Errorf("Expected total size 33, got %d", size)
+	}
+
+	// characters rather than bytes, and gzipped files by their decompressed size
+	unicode := filepath.Join(tempDir, "unicode.json")
+	if err := os.WriteFile(unicode, []byte(`{"Sid": "café"}`), 0644); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+	compressed := filepath.Join(tempDir, "input.json.gz")
+	if err := os.WriteFile(compressed, gzipData([]byte(`{"Version": "2012-10-17"}`)), 0644); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+	if size := totalFileSize([]string{unicode, compressed, filepath.Join(tempDir, "missing.json")}); size != 15+25 {
+		t.Errorf("Expected total size %d, got %d", 15+25, size)
+	}
+}
+
+func TestWriteJSONNoHTMLEscaping(t *testing.T) {
+	content := map[string]interface{}{
+		"Effect":   "Deny",
+		"Action":   "s3:*",
+		"Resource": "*",
+		"Condition": map[string]interface{}{
+			"StringLike": map[string]interface{}{"aws:userid": "a&b<c>"},
+		},
+	}
+	statements := []Statement{newStatement(content)}
+
+	for _, whitespace := range []bool{false, true} {
+		data := string(writeJSON(inputs.UserInput{Whitespace: whitespace}, Header{Version: config.SCPVersion}, statements))
+		if !strings.Contains(data, "a&b<c>") {
+			t.Errorf("Expected unescaped condition value, got %s", data)
+		}
+		if strings.Contains(data, `\u0026`) || strings.Contains(data, `\u003c`) {
+			t.Errorf("Expected no HTML escapes, got %s", data)
+		}
+		if strings.HasSuffix(data, "\n") {
+			t.Error("Expected no trailing newline")
+		}
+	}
+
+	// statement size should use the same unescaped encoding
+	escaped, _ := json.Marshal(content)
+	if statements[0].Size >= len(escaped) {
+		t.Errorf("Expected size %d to be smaller than escaped size %d", statements[0].Size, len(escaped))
+	}
+}
+
+func TestWriteOutputFileGzip(t *testing.T) {
+	statements := []Statement{
+		newStatement(map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}),
+		newStatement(map[string]interface{}{"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}),
+	}
+	userInput := inputs.UserInput{Gzip: true}
+	header := Header{Version: config.SCPVersion}
+	outputFile := filepath.Join(t.TempDir(), "corset.json.gz")
+
+	result, err := writeOutputFile(userInput, header, outputFile, statements, nil)
+	if err != nil {
+		t.Fatalf("writeOutputFile failed: %v", err)
+	}
+
+	// reported size is the uncompressed policy
+	if expected := len(writeJSON(userInput, header, statements)); result.Size != expected {
+		t.Errorf("Expected size %d, got %d", expected, result.Size)
+	}
+
+	roundTrip, roundTripHeader := extractIndividualStatements(outputFile)
+	if roundTripHeader.Version != config.SCPVersion {
+		t.Errorf("Expected version %s, got %s", config.SCPVersion, roundTripHeader.Version)
+	}
+	if len(roundTrip) != len(statements) {
+		t.Fatalf("Expected %d statements, got %d", len(statements), len(roundTrip))
+	}
+	for i := range statements {
+		if !mapsEqual(roundTrip[i].Content, statements[i].Content) {
+			t.Errorf("Statement %d: expected %v, got %v", i, statements[i].Content, roundTrip[i].Content)
+		}
+	}
+}
+
+func TestWriteOutputFileExisting(t *testing.T) {
+	statements := []Statement{{Content: map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}}}
+	header := Header{Version: config.SCPVersion}
+
+	tests := []struct {
+		name      string
+		force     bool
+		isInput   bool
+		expectErr bool
+	}{
+		{name: "unrelated file is blocked", expectErr: true},
+		{name: "unrelated file with force", force: true},
+		{name: "input being replaced", isInput: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputFile := filepath.Join(t.TempDir(), "corset1.json")
+			original := `{"unrelated": true}`
+			if err := os.WriteFile(outputFile, []byte(original), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+			var inputFiles []string
+			if tt.isInput {
+				inputFiles = []string{outputFile}
+			}
+
+			_, err := writeOutputFile(inputs.UserInput{Force: tt.force}, header, outputFile, statements, inputFiles)
+			data, readErr := os.ReadFile(outputFile)
+			if readErr != nil {
+				t.Fatalf("Failed to read output: %v", readErr)
+			}
+
+			if tt.expectErr {
+				if err == nil || !strings.Contains(err.Error(), "--force") {
+					t.Errorf("Expected an error suggesting --force, got %v", err)
+				}
+				if string(data) != original {
+					t.Errorf("Expected the existing file to be left alone, got %s", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(data) == original {
+				t.Error("Expected the file to be overwritten")
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	tempDir := t.TempDir()
+	filename := filepath.Join(tempDir, "policy.json")
+	original := `{"Version": "2012-10-17", "Statement": []}`
+	if err := os.WriteFile(filename, []byte(original), 0600); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// serialization fails part way through
+	err := writeFileAtomic(filename, func(w io.Writer) error {
+		w.Write([]byte(`{"Version": "2012`))
+		return errors.New("serialization failed")
+	})
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	if string(data) != original {
+		t.Errorf("Expected original file to be untouched, got %s", data)
+	}
+	entries, _ := os.ReadDir(tempDir)
+	if len(entries) != 1 {
+		t.Errorf("Expected no temp files to be left behind, got %d entries", len(entries))
+	}
+
+	// a successful replace keeps the original mode
+	err = writeFileAtomic(filename, func(w io.Writer) error {
+		_, err := w.Write([]byte("{}"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 to be preserved, got %v", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "{}" {
+		t.Errorf("Expected replaced content, got %s", data)
+	}
+}
+
+func TestWriteJSONIndent(t *testing.T) {
+	tests := []struct {
+		name   string
+		indent string
+	}{
+		{name: "two spaces", indent: ""},
+		{name: "four spaces", indent: "    "},
+		{name: "tab", indent: "\t"},
+	}
+
+	header := Header{Version: config.SCPVersion}
+	var statements []Statement
+	for _, content := range createLargeStatements(6) {
+		statements = append(statements, newStatement(content))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userInput := inputs.UserInput{Whitespace: true, Indent: tt.indent, MaxFiles: config.DefaultMaxFiles}
+			indent := indentString(userInput)
+
+			output := string(writeJSON(userInput, header, statements[:1]))
+			if !strings.Contains(output, "\n"+indent+`"Version"`) {
+				t.Errorf("Expected Version indented by %q, got %s", indent, output)
+			}
+			if !strings.Contains(output, "\n"+indent+indent+"{") {
+				t.Errorf("Expected statement indented by %q, got %s", indent+indent, output)
+			}
+
+			// packed sizes match what AWS counts, whatever the indent
+			result, err := packAllStatements(userInput, header, append([]Statement(nil), statements...))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for i, file := range result {
+				packed := packedSize(userInput, header, file)
+				if size := effectiveSize(userInput, header, file, writeJSON(userInput, header, file)); size != packed {
+					t.Errorf("File %d packed as %d characters but measures %d", i, packed, size)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteJSONIndentFails(t *testing.T) {
+	header := Header{Version: config.SCPVersion}
+	// a raw statement that is not JSON, which json.Indent rejects
+	statements := []Statement{{Raw: json.RawMessage(`{"Effect":`)}}
+	minified := writeJSON(inputs.UserInput{}, header, statements)
+
+	logs := captureLogs(t, slog.LevelInfo)
+	output := writeJSON(inputs.UserInput{Whitespace: true}, header, statements)
+	if !bytes.Equal(output, minified) {
+		t.Errorf("Expected the minified policy when indenting fails, got %s", output)
+	}
+	if !strings.Contains(logs.String(), "could not indent the policy") {
+		t.Errorf("Expected a warning, got %q", logs.String())
+	}
+}
+
+// encodedPolicy is how policies were written before writeJSON assembled them directly, kept as a reference
+type encodedPolicy struct {
+	Version   string            `json:"Version"`
+	Id        string            `json:"Id,omitempty"`
+	Statement []json.RawMessage `json:"Statement"`
+}
+
+func encodePolicy(userInput inputs.UserInput, header Header, statements []Statement) []byte {
+	policy := encodedPolicy{Version: header.Version, Id: header.Id, Statement: make([]json.RawMessage, len(statements))}
+	for i, stmt := range statements {
+		policy.Statement[i] = stmt.rawJSON()
+	}
+	if userInput.Whitespace {
+		return marshalJSON(policy, "", indentString(userInput))
+	}
+	return marshalJSON(policy, "", "")
+}
+
+func TestWriteJSONMatchesEncoder(t *testing.T) {
+	var statements []Statement
+	for _, content := range createLargeStatements(4) {
+		statements = append(statements, newStatement(content))
+	}
+	statements = append(statements,
+		newStatement(map[string]interface{}{"Sid": "Html<&>", "Effect": "Deny", "Action": "s3:*", "Resource": "arn:aws:s3:::bücket/*"}))
+
+	tests := []struct {
+		name       string
+		userInput  inputs.UserInput
+		header     Header
+		statements []Statement
+	}{
+		{name: "minified", header: Header{Version: config.SCPVersion}, statements: statements},
+		{name: "with id", header: Header{Version: config.SCPVersion, Id: `Guard "rails" <1>`}, statements: statements},
+		{name: "whitespace", userInput: inputs.UserInput{Whitespace: true}, header: Header{Version: config.SCPVersion}, statements: statements},
+		{name: "tab indent", userInput: inputs.UserInput{Whitespace: true, Indent: "\t"}, header: Header{Version: config.SCPVersion, Id: "Guardrails"}, statements: statements},
+		{name: "no statements", header: Header{Version: config.SCPVersion}, statements: []Statement{}},
+		{name: "no statements, whitespace", userInput: inputs.UserInput{Whitespace: true}, header: Header{Version: config.SCPVersion}, statements: []Statement{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := string(encodePolicy(tt.userInput, tt.header, tt.statements))
+			if result := string(writeJSON(tt.userInput, tt.header, tt.statements)); result != expected {
+				t.Errorf("Expected %s, got %s", expected, result)
+			}
+		})
+	}
+}
+
+func benchmarkStatements(b *testing.B) []Statement {
+	b.Helper()
+	var statements []Statement
+	for _, content := range createLargeStatements(20) {
+		statements = append(statements, newStatement(content))
+	}
+	return statements
+}
+
+// BenchmarkWriteJSON and BenchmarkEncodePolicy compare assembling a policy with encoding it, run with -benchmem
+func BenchmarkWriteJSON(b *testing.B) {
+	statements := benchmarkStatements(b)
+	header := Header{Version: config.SCPVersion}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		writeJSON(inputs.UserInput{}, header, statements)
+	}
+}
+
+func BenchmarkEncodePolicy(b *testing.B) {
+	statements := benchmarkStatements(b)
+	header := Header{Version: config.SCPVersion}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		encodePolicy(inputs.UserInput{}, header, statements)
+	}
+}
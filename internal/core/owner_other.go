@@ -0,0 +1,8 @@
+//go:build !unix
+
+package core
+
+import "os"
+
+// preserveOwner is a no-op where files have no uid and gid
+func preserveOwner(file *os.File, original os.FileInfo) {}
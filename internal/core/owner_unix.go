@@ -0,0 +1,20 @@
+//go:build unix
+
+package core
+
+import (
+	"os"
+	"syscall"
+)
+
+// preserveOwner gives file the uid and gid of the file it replaces. Only root can change the
+// owner, so without it just the group is kept, and failing that the writer's ownership.
+func preserveOwner(file *os.File, original os.FileInfo) {
+	stat, ok := original.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	if err := file.Chown(int(stat.Uid), int(stat.Gid)); err != nil {
+		file.Chown(-1, int(stat.Gid))
+	}
+}
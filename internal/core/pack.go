@@ -1,25 +1,311 @@
 package core
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
 	"sort"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/jakebark/corset/internal/config"
 	"github.com/jakebark/corset/internal/inputs"
 )
 
-func packAllStatements(userInput inputs.UserInput, statements []Statement) [][]Statement {
-	baseSize := config.SCPBaseSizeMinified
+func packAllStatements(userInput inputs.UserInput, header Header, statements []Statement) ([][]Statement, error) {
+	if err := validateMaxFiles(userInput); err != nil {
+		return nil, err
+	}
+
+	// AWS measures a policy minified, so whitespace output packs by its minified size and is never split for its indent
+	userInput.Whitespace = false
+
+	base := baseSize(userInput, header)
+	if userInput.Minimize {
+		return minimizeFiles(userInput, statements, base)
+	}
+
+	packedFiles, unplaced := packStatements(userInput, statements, base)
+	if len(unplaced) > 0 {
+		return nil, &PackError{MaxFiles: userInput.MaxFiles, Unplaced: unplaced}
+	}
+	return packedFiles, nil
+}
+
+// validateMaxFiles rejects a MaxFiles that would pack into no files, or into more SCPs or RCPs than
+// Organizations attaches to a target. IAM's own attachment quotas are not checked.
+func validateMaxFiles(userInput inputs.UserInput) error {
+	if userInput.MaxFiles < 1 {
+		return fmt.Errorf("invalid max files %d, must be 1 or more", userInput.MaxFiles)
+	}
+	if userInput.PolicyType != config.PolicyTypeIAM && userInput.MaxFiles > config.MaxAllowedFiles {
+		return fmt.Errorf("invalid max files %d, AWS allows at most %d %s policies attached to a target",
+			userInput.MaxFiles, config.MaxAllowedFiles, strings.ToUpper(policyType(userInput)))
+	}
+	return nil
+}
+
+func (e *PackError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "statements do not fit within %d files, %d could not be placed:", e.MaxFiles, len(e.Unplaced))
+	for _, stmt := range e.Unplaced {
+		fmt.Fprintf(&b, "\n- %s", describeStatement(stmt))
+	}
+	return b.String()
+}
+
+// describeStatement identifies a statement by its file, index and any Sid, with its size
+func describeStatement(stmt Statement) string {
+	description := fmt.Sprintf("%s Statement[%d]", filepath.Base(stmt.Source), stmt.Index)
+	if sid, ok := stmt.Content["Sid"].(string); ok && sid != "" {
+		description += " " + sid
+	}
+	return fmt.Sprintf("%s (%s characters)", description, formatCount(stmt.Size))
+}
+
+// minimizeBudget bounds the placements minimizeFiles tries, so a large input settles for the heuristic's files
+const minimizeBudget = 1000000
+
+// minimizeFiles packs into the fewest files that hold every statement, within MaxFiles. The heuristic's
+// packing bounds a search that tries each smaller file count in turn, placing the largest statements first
+// and backtracking. Should the search run out of budget, the heuristic's packing is kept.
+func minimizeFiles(userInput inputs.UserInput, statements []Statement, baseSize int) ([][]Statement, error) {
+	// sorts the statements largest first, the order the search places them in
+	heuristic, unplaced := packStatements(userInput, statements, baseSize)
+	upper := len(heuristic)
+	if len(unplaced) > 0 {
+		upper = userInput.MaxFiles + 1
+	}
+
+	// each statement after the first in a file adds a separator, counted here against every statement
+	separator := separatorSize(userInput)
+	capacity := packingLimit(userInput) - baseSize + separator
+	totalSize := 0
+	for _, stmt := range statements {
+		totalSize += stmt.Size
+	}
+	lower := max(1, (totalSize+len(statements)*separator+capacity-1)/capacity)
+
+	for files := lower; files < upper; files++ {
+		search := newFileSearch(userInput, statements, files, capacity, separator)
+		if search.place(0) {
+			packed := search.files()
+			if userInput.Strategy == config.StrategyBalance {
+				// spread the statements across the same number of files, where balancing alone finds a way
+				balanced := userInput
+				balanced.MaxFiles = files
+				if rebalanced, unplaced := placeStatements(balanced, statements, baseSize); len(unplaced) == 0 {
+					packed = rebalanced
+				}
+			}
+			return packed, nil
+		}
+		if search.steps >= minimizeBudget {
+			logger(userInput).Debug("minimize search ran out of budget", "files", files, "statements", len(statements))
+			break
+		}
+	}
+	if len(unplaced) == 0 {
+		return heuristic, nil
+	}
+
+	capacityChars := userInput.MaxFiles * (packingLimit(userInput) - baseSize)
+	return nil, fmt.Errorf("statements total %d characters, capacity of %d files is %d characters: %w",
+		totalSize, userInput.MaxFiles, capacityChars, &PackError{MaxFiles: userInput.MaxFiles, Unplaced: unplaced})
+}
+
+// fileSearch places statements into a fixed number of files by depth-first search
+type fileSearch struct {
+	statements    []Statement
+	sizes         []int // each statement's size and a separator
+	remaining     []int // the sizes of the statements from each index on
+	capacity      int
+	maxStatements int
+	loads         []int
+	assigned      []int // the file each statement is placed in
+	counts        []int
+	steps         int
+}
+
+func newFileSearch(userInput inputs.UserInput, statements []Statement, files, capacity, separator int) *fileSearch {
+	search := &fileSearch{
+		statements:    statements,
+		sizes:         make([]int, len(statements)),
+		remaining:     make([]int, len(statements)+1),
+		capacity:      capacity,
+		maxStatements: userInput.MaxStatements,
+		loads:         make([]int, files),
+		assigned:      make([]int, len(statements)),
+		counts:        make([]int, files),
+	}
+	for i := len(statements) - 1; i >= 0; i-- {
+		search.sizes[i] = statements[i].Size + separator
+		search.remaining[i] = search.remaining[i+1] + search.sizes[i]
+	}
+	return search
+}
+
+// place tries each file for the statement at index and those after it, reporting whether all of them fit
+func (s *fileSearch) place(index int) bool {
+	if index == len(s.sizes) {
+		return true
+	}
+	free := 0
+	for _, load := range s.loads {
+		free += s.capacity - load
+	}
+	if s.remaining[index] > free || s.steps >= minimizeBudget {
+		return false
+	}
+
+	for i := range s.loads {
+		if s.loads[i]+s.sizes[index] > s.capacity || (s.maxStatements > 0 && s.counts[i] >= s.maxStatements) {
+			continue
+		}
+		// a file in the same state as one already tried leads to the same outcome
+		if s.triedEquivalent(i) {
+			continue
+		}
+		s.steps++
+		s.loads[i] += s.sizes[index]
+		s.counts[i]++
+		s.assigned[index] = i
+		if s.place(index + 1) {
+			return true
+		}
+		s.loads[i] -= s.sizes[index]
+		s.counts[i]--
+	}
+	return false
+}
+
+func (s *fileSearch) triedEquivalent(file int) bool {
+	for i := 0; i < file; i++ {
+		if s.loads[i] == s.loads[file] && s.counts[i] == s.counts[file] {
+			return true
+		}
+	}
+	return false
+}
+
+// files returns the placed statements, in the order they were placed
+func (s *fileSearch) files() [][]Statement {
+	files := make([][]Statement, len(s.loads))
+	for i, stmt := range s.statements {
+		files[s.assigned[i]] = append(files[s.assigned[i]], stmt)
+	}
+	var result [][]Statement
+	for _, file := range files {
+		if len(file) > 0 {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// explodeStatements places each statement in a file of its own, in the order they were read, without packing.
+// Organizations attaches at most MaxAllowedFiles SCPs or RCPs to a target, so more than that is warned about.
+func explodeStatements(userInput inputs.UserInput, header Header, statements []Statement) ([][]Statement, error) {
+	userInput.Whitespace = false
+	base := baseSize(userInput, header)
+	limit := packingLimit(userInput)
+
+	var oversized []string
+	files := make([][]Statement, len(statements))
+	for i, stmt := range statements {
+		if base+stmt.Size > limit {
+			oversized = append(oversized, "\n- "+describeStatement(stmt))
+		}
+		files[i] = []Statement{stmt}
+	}
+	if len(oversized) > 0 {
+		return nil, fmt.Errorf("%d statements do not fit in a file of their own:%s", len(oversized), strings.Join(oversized, ""))
+	}
+
+	if userInput.PolicyType != config.PolicyTypeIAM && len(files) > config.MaxAllowedFiles {
+		slog.Warn("exploding into more files than AWS allows attached to a target", "files", len(files),
+			"limit", config.MaxAllowedFiles, "type", strings.ToUpper(policyType(userInput)))
+	}
+	return files, nil
+}
+
+// maxPolicySize returns the character limit per file, the AWS limit for the policy type unless set
+func maxPolicySize(userInput inputs.UserInput) int {
+	if userInput.MaxSize == 0 && userInput.PolicyType == config.PolicyTypeIAM {
+		return config.MaxIAMPolicySize
+	}
+	if userInput.MaxSize == 0 {
+		return config.MaxPolicySize
+	}
+	return userInput.MaxSize
+}
+
+// logger returns where packing logs, the library's own logger or otherwise the default
+func logger(userInput inputs.UserInput) *slog.Logger {
+	if userInput.Logger != nil {
+		return userInput.Logger
+	}
+	return slog.Default()
+}
+
+// packingLimit returns the characters statements are packed into, the limit less any --headroom
+func packingLimit(userInput inputs.UserInput) int {
+	return maxPolicySize(userInput) - userInput.Headroom
+}
+
+// baseSize returns the character overhead of the policy wrapper around its statements
+func baseSize(userInput inputs.UserInput, header Header) int {
+	// measure with a single empty statement so the array brackets and first statement's indent are counted
+	wrapper := writeJSON(userInput, header, []Statement{{Content: map[string]interface{}{}}})
+	return utf8.RuneCount(wrapper) - 2 // for {}
+}
+
+// separatorSize returns the characters between consecutive statements
+func separatorSize(userInput inputs.UserInput) int {
 	if userInput.Whitespace {
-		baseSize = config.SCPBaseSizeWithWS
+		// comma, newline and indent nested within the Statement array
+		return len(",\n") + 2*len(indentString(userInput))
 	}
-	return packStatements(userInput, statements, baseSize)
+	return len(",")
+}
+
+// indentedSize returns a statement's size as written nested within an indented policy
+func indentedSize(stmt Statement, indent string) int {
+	var buf bytes.Buffer
+	json.Indent(&buf, stmt.rawJSON(), indent+indent, indent)
+	return utf8.RuneCount(buf.Bytes())
 }
 
-func packStatements(userInput inputs.UserInput, statements []Statement, baseSize int) [][]Statement {
+// packStatements returns the packed files, or nil files and every statement that could not be placed
+func packStatements(userInput inputs.UserInput, statements []Statement, baseSize int) ([][]Statement, []Statement) {
+	// equal sizes are ordered by their JSON, so the same statements always pack the same way
 	sort.Slice(statements, func(i, j int) bool {
-		return statements[i].Size > statements[j].Size
+		if statements[i].Size != statements[j].Size {
+			return statements[i].Size > statements[j].Size
+		}
+		return bytes.Compare(statements[i].rawJSON(), statements[j].rawJSON()) < 0
 	})
 
+	if userInput.Strategy != config.StrategyBalance {
+		return placeStatements(userInput, statements, baseSize)
+	}
+	// balance across the fewest files that hold everything, rather than thinly across all of them
+	unplaced := statements
+	for maxFiles := 1; maxFiles <= userInput.MaxFiles; maxFiles++ {
+		balanced := userInput
+		balanced.MaxFiles = maxFiles
+		var files [][]Statement
+		if files, unplaced = placeStatements(balanced, statements, baseSize); len(unplaced) == 0 {
+			return files, nil
+		}
+	}
+	return nil, unplaced
+}
+
+// placeStatements places sorted statements into files in turn, choosing a file by the strategy
+func placeStatements(userInput inputs.UserInput, statements []Statement, baseSize int) ([][]Statement, []Statement) {
 	files := make([][]Statement, userInput.MaxFiles)
 	fileSizes := make([]int, userInput.MaxFiles)
 
@@ -28,27 +314,57 @@ func packStatements(userInput inputs.UserInput, statements []Statement, baseSize
 		fileSizes[i] = baseSize
 	}
 
+	maxSize := packingLimit(userInput)
+	logger(userInput).Debug("placing statements", "statements", len(statements), "files", userInput.MaxFiles,
+		"strategy", userInput.Strategy, "limit", maxSize)
+	var unplaced []Statement
 	for _, stmt := range statements {
-		placed := false
+		target := -1
+		targetSize := 0
 
 		for i := 0; i < userInput.MaxFiles; i++ {
-			// account for comma separator (except for first statement)
+			if userInput.MaxStatements > 0 && len(files[i]) >= userInput.MaxStatements {
+				continue
+			}
+
+			// account for separator (except for first statement)
 			separator := 0
 			if len(files[i]) > 0 {
-				separator = 1 // for comma
+				separator = separatorSize(userInput)
 			}
 
-			if fileSizes[i]+stmt.Size+separator <= config.MaxPolicySize {
-				files[i] = append(files[i], stmt)
-				fileSizes[i] += stmt.Size + separator
-				placed = true
+			newSize := fileSizes[i] + stmt.Size + separator
+			if newSize > maxSize {
+				continue
+			}
+
+			// first fit takes the first file with room, best fit the fullest
+			if userInput.Strategy != config.StrategyBestFit && userInput.Strategy != config.StrategyBalance {
+				target, targetSize = i, newSize
 				break
 			}
+			// balance takes the emptiest, largest statements first, as in LPT scheduling
+			if target == -1 ||
+				(userInput.Strategy == config.StrategyBestFit && newSize > targetSize) ||
+				(userInput.Strategy == config.StrategyBalance && newSize < targetSize) {
+				target, targetSize = i, newSize
+			}
 		}
 
-		if !placed {
-			return nil // Cannot fit all policies
+		if target == -1 {
+			// keep packing so every statement that cannot fit is reported
+			logger(userInput).Debug("statement does not fit", "source", filepath.Base(stmt.Source), "index", stmt.Index, "size", stmt.Size)
+			unplaced = append(unplaced, stmt)
+			continue
 		}
+		logger(userInput).Debug("placed statement", "source", filepath.Base(stmt.Source), "index", stmt.Index, "size", stmt.Size,
+			"file", target+1, "file_size", targetSize)
+		files[target] = append(files[target], stmt)
+		fileSizes[target] = targetSize
+	}
+
+	if len(unplaced) > 0 {
+		return nil, unplaced
 	}
 
 	// remove empty files
@@ -64,5 +380,5 @@ func packStatements(userInput inputs.UserInput, statements []Statement, baseSize
 		result = [][]Statement{}
 	}
 
-	return result
+	return result, nil
 }
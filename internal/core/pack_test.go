@@ -1,6 +1,10 @@
 package core
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/jakebark/corset/internal/config"
@@ -69,7 +73,10 @@ func TestPackAllStatements(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := packAllStatements(tt.userInput, tt.statements)
+			result, err := packAllStatements(tt.userInput, Header{Version: config.SCPVersion}, tt.statements)
+			if err != nil && !tt.expectNil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
 
 			if tt.expectNil && result != nil {
 				t.Errorf("Expected nil result, got %v", result)
@@ -179,7 +186,7 @@ func TestPackPolicies(t *testing.T) {
 				MaxFiles: tt.maxFiles,
 			}
 
-			result := packStatements(userInput, tt.statements, tt.baseSize)
+			result, _ := packStatements(userInput, tt.statements, tt.baseSize)
 
 			if tt.expectNil && result != nil {
 				t.Errorf("Expected nil result, got %v", result)
@@ -237,7 +244,7 @@ func TestPackPoliciesSorting(t *testing.T) {
 		MaxFiles: 5,
 	}
 
-	result := packStatements(userInput, statements, 50)
+	result, _ := packStatements(userInput, statements, 50)
 
 	if len(result) == 0 {
 		t.Fatal("Expected at least one file")
@@ -268,7 +275,7 @@ func TestPackPoliciesBinPacking(t *testing.T) {
 		MaxFiles: 5,
 	}
 
-	result := packStatements(userInput, statements, 100)
+	result, _ := packStatements(userInput, statements, 100)
 
 	if len(result) != 2 {
 		t.Errorf("Expected optimal packing into 2 files, got %d", len(result))
@@ -304,3 +311,530 @@ func TestPackPoliciesBinPacking(t *testing.T) {
 	}
 }
 
+func TestBaseSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		userInput inputs.UserInput
+		header    Header
+		expected  int
+	}{
+		{
+			name:      "minified",
+			userInput: inputs.UserInput{Whitespace: false},
+			header:    Header{Version: config.SCPVersion},
+			expected:  39, // {"Version":"2012-10-17","Statement":[]}
+		},
+		{
+			name:      "with whitespace",
+			userInput: inputs.UserInput{Whitespace: true},
+			header:    Header{Version: config.SCPVersion},
+			expected:  56, // includes the first statement's indent and the closing ] indent
+		},
+		{
+			name:      "with id",
+			userInput: inputs.UserInput{Whitespace: false},
+			header:    Header{Version: config.SCPVersion, Id: "GuardRails"},
+			expected:  57, // adds "Id":"GuardRails",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := baseSize(tt.userInput, tt.header)
+			if result != tt.expected {
+				t.Errorf("Expected base size %d, got %d", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPackStatementsBestFit(t *testing.T) {
+	sizes := []int{3750, 2500, 1500, 1000, 750, 500}
+
+	tests := []struct {
+		name          string
+		strategy      string
+		expectedFiles int
+	}{
+		{
+			name:          "first fit",
+			strategy:      config.StrategyFirstFit,
+			expectedFiles: 3,
+		},
+		{
+			name:          "best fit",
+			strategy:      config.StrategyBestFit,
+			expectedFiles: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var statements []Statement
+			for _, size := range sizes {
+				statements = append(statements, Statement{Content: map[string]interface{}{"Effect": "Deny"}, Size: size})
+			}
+
+			userInput := inputs.UserInput{
+				MaxFiles: 5,
+				Strategy: tt.strategy,
+			}
+
+			result, _ := packStatements(userInput, statements, 50)
+
+			if len(result) != tt.expectedFiles {
+				t.Errorf("Expected %d files, got %d", tt.expectedFiles, len(result))
+			}
+
+			totalStatements := 0
+			for _, file := range result {
+				totalStatements += len(file)
+			}
+			if totalStatements != len(sizes) {
+				t.Errorf("Expected %d total statements, got %d", len(sizes), totalStatements)
+			}
+		})
+	}
+}
+
+func TestPackStatementsBalance(t *testing.T) {
+	// a representative mix of large and small statements, 11,680 characters in all
+	sizes := []int{1400, 1150, 900, 880, 760, 700, 640, 610, 560, 520, 480, 450, 400, 380, 350, 300, 280, 240, 180, 150, 120, 90, 80, 60}
+	var statements []Statement
+	for _, size := range sizes {
+		statements = append(statements, Statement{Content: map[string]interface{}{"Effect": "Deny"}, Size: size})
+	}
+
+	spread := func(files [][]Statement) int {
+		minSize, maxSize := -1, 0
+		for _, file := range files {
+			size := 0
+			for _, stmt := range file {
+				size += stmt.Size + 1
+			}
+			if minSize == -1 || size < minSize {
+				minSize = size
+			}
+			if size > maxSize {
+				maxSize = size
+			}
+		}
+		return maxSize - minSize
+	}
+
+	firstFit, unplaced := packStatements(inputs.UserInput{MaxFiles: 5}, statements, 50)
+	if len(unplaced) != 0 {
+		t.Fatalf("Expected every statement to be placed, got %d unplaced", len(unplaced))
+	}
+	balanced, unplaced := packStatements(inputs.UserInput{MaxFiles: 5, Strategy: config.StrategyBalance}, statements, 50)
+	if len(unplaced) != 0 {
+		t.Fatalf("Expected every statement to be placed, got %d unplaced", len(unplaced))
+	}
+
+	// as few files as first fit, but with the size spread within 2% of the limit
+	if len(balanced) != len(firstFit) {
+		t.Errorf("Expected %d files, got %d", len(firstFit), len(balanced))
+	}
+	if got := spread(balanced); got > config.MaxPolicySize/50 {
+		t.Errorf("Expected a size spread under %d characters, got %d (first fit %d)", config.MaxPolicySize/50, got, spread(firstFit))
+	}
+}
+
+func TestPackAllStatementsMinimize(t *testing.T) {
+	tests := []struct {
+		name          string
+		sizes         []int
+		expectedFiles int
+		expectErr     bool
+	}{
+		{
+			name:          "fits in one file",
+			sizes:         []int{1000, 1000, 1000},
+			expectedFiles: 1,
+		},
+		{
+			name:          "needs two files",
+			sizes:         []int{3000, 3000, 1000},
+			expectedFiles: 2,
+		},
+		{
+			name:      "exceeds capacity",
+			sizes:     []int{5000, 5000, 5000, 5000, 5000, 5000},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var statements []Statement
+			for _, size := range tt.sizes {
+				statements = append(statements, Statement{Content: map[string]interface{}{"Effect": "Deny"}, Size: size})
+			}
+
+			userInput := inputs.UserInput{
+				MaxFiles: config.DefaultMaxFiles,
+				Minimize: true,
+			}
+
+			result, err := packAllStatements(userInput, Header{Version: config.SCPVersion}, statements)
+
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				if !strings.Contains(err.Error(), "30000 characters") {
+					t.Errorf("Expected error to name the total size, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(result) != tt.expectedFiles {
+				t.Errorf("Expected %d files, got %d", tt.expectedFiles, len(result))
+			}
+		})
+	}
+}
+
+func TestPackAllStatementsMinimizeBeatsFirstFit(t *testing.T) {
+	header := Header{Version: config.SCPVersion}
+	var statements []Statement
+	// with their separators these fill files of 100 characters, first-fit needs three: 50+40, 30+30+25, 25
+	for i, size := range []int{49, 39, 29, 29, 24, 24} {
+		statements = append(statements, Statement{Content: map[string]interface{}{"Sid": strconv.Itoa(i)}, Size: size})
+	}
+	userInput := inputs.UserInput{MaxFiles: config.DefaultMaxFiles}
+	userInput.MaxSize = baseSize(userInput, header) + 99
+
+	firstFit, err := packAllStatements(userInput, header, append([]Statement(nil), statements...))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(firstFit) != 3 {
+		t.Fatalf("Expected first-fit to need 3 files, got %d", len(firstFit))
+	}
+
+	// two files are only enough with minimize, and it holds to MaxFiles rather than AWS's limit
+	userInput.MaxFiles = 2
+	if _, err := packAllStatements(userInput, header, append([]Statement(nil), statements...)); err == nil {
+		t.Fatal("Expected first-fit to fail within 2 files")
+	}
+	userInput.Minimize = true
+	minimized, err := packAllStatements(userInput, header, append([]Statement(nil), statements...))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(minimized) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(minimized))
+	}
+	placed := 0
+	for _, file := range minimized {
+		placed += len(file)
+		size := baseSize(userInput, header) + (len(file)-1)*separatorSize(userInput)
+		for _, stmt := range file {
+			size += stmt.Size
+		}
+		if size > userInput.MaxSize {
+			t.Errorf("Expected each file within %d characters, got %d", userInput.MaxSize, size)
+		}
+	}
+	if placed != len(statements) {
+		t.Errorf("Expected %d statements placed, got %d", len(statements), placed)
+	}
+
+	userInput.MaxFiles = 1
+	if _, err := packAllStatements(userInput, header, append([]Statement(nil), statements...)); err == nil {
+		t.Error("Expected an error when the statements need more than MaxFiles")
+	}
+}
+
+func TestPackAllStatementsWhitespaceMinifiedSize(t *testing.T) {
+	var statements []Statement
+	for _, content := range createLargeStatements(20) {
+		statements = append(statements, newStatement(content))
+	}
+	header := Header{Version: config.SCPVersion}
+
+	minified, err := packAllStatements(inputs.UserInput{MaxFiles: config.DefaultMaxFiles}, header, statements)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	userInput := inputs.UserInput{Whitespace: true, MaxFiles: config.DefaultMaxFiles}
+	indented, err := packAllStatements(userInput, header, statements)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// the indent never causes a split AWS wouldn't need
+	if len(indented) != len(minified) {
+		t.Fatalf("Expected whitespace output to pack into %d files like minified output, got %d", len(minified), len(indented))
+	}
+	overLimit := false
+	for i, file := range indented {
+		data := writeJSON(userInput, header, file)
+		size := effectiveSize(userInput, header, file, data)
+		if size > config.MaxPolicySize {
+			t.Errorf("File %d is %d characters to AWS, over the %d limit", i, size, config.MaxPolicySize)
+		}
+		if packed := packedSize(userInput, header, file); packed != size {
+			t.Errorf("File %d packed as %d characters but measures %d", i, packed, size)
+		}
+		overLimit = overLimit || len([]rune(string(data))) > config.MaxPolicySize
+	}
+	if !overLimit {
+		t.Error("Expected at least one file to be over the limit on disk, where the indent is counted")
+	}
+}
+
+func TestSeparatorSize(t *testing.T) {
+	if size := separatorSize(inputs.UserInput{}); size != 1 {
+		t.Errorf("Expected minified separator size 1, got %d", size)
+	}
+
+	// the separator is whatever sits between two statements once written
+	userInput := inputs.UserInput{Whitespace: true}
+	header := Header{Version: config.SCPVersion}
+	empty := Statement{Content: map[string]interface{}{}}
+	one := len(writeJSON(userInput, header, []Statement{empty}))
+	two := len(writeJSON(userInput, header, []Statement{empty, empty}))
+	if size := separatorSize(userInput); size != two-one-2 {
+		t.Errorf("Expected indented separator size %d, got %d", two-one-2, size)
+	}
+}
+
+func TestPackStatementsSeparatorOverflow(t *testing.T) {
+	// two statements that fit minified but overflow once the indented separator is counted
+	statements := []Statement{
+		{Content: map[string]interface{}{"Effect": "Allow"}, Size: 2500},
+		{Content: map[string]interface{}{"Effect": "Deny"}, Size: 2500},
+	}
+	base := config.MaxPolicySize - 5000 - 3
+
+	minified, _ := packStatements(inputs.UserInput{MaxFiles: 5}, statements, base)
+	if len(minified) != 1 {
+		t.Errorf("Expected minified statements to share 1 file, got %d", len(minified))
+	}
+
+	indented, _ := packStatements(inputs.UserInput{MaxFiles: 5, Whitespace: true}, statements, base)
+	if len(indented) != 2 {
+		t.Errorf("Expected indented statements to need 2 files, got %d", len(indented))
+	}
+}
+
+func TestPackAllStatementsUnplaced(t *testing.T) {
+	var statements []Statement
+	for i := 0; i < 6; i++ {
+		statements = append(statements, Statement{
+			Content: map[string]interface{}{"Sid": fmt.Sprintf("Deny%d", i), "Effect": "Deny"},
+			Size:    5000,
+			Source:  "/policies/large.json",
+			Index:   i,
+		})
+	}
+	statements = append(statements, Statement{
+		Content: map[string]interface{}{"Effect": "Deny"},
+		Size:    6000,
+		Source:  "/policies/huge.json",
+		Index:   0,
+	})
+
+	userInput := inputs.UserInput{MaxFiles: config.DefaultMaxFiles}
+	_, err := packAllStatements(userInput, Header{Version: config.SCPVersion}, statements)
+
+	var packErr *PackError
+	if !errors.As(err, &packErr) {
+		t.Fatalf("Expected a PackError, got %v", err)
+	}
+	if len(packErr.Unplaced) != 2 {
+		t.Fatalf("Expected 2 unplaced statements, got %d", len(packErr.Unplaced))
+	}
+	if packErr.Unplaced[0].Size != 6000 {
+		t.Errorf("Expected the oversized statement to be unplaced, got size %d", packErr.Unplaced[0].Size)
+	}
+
+	message := err.Error()
+	for _, expected := range []string{"within 5 files", "huge.json Statement[0] (6,000 characters)", "(5,000 characters)"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Expected error to contain %q, got %s", expected, message)
+		}
+	}
+}
+
+func TestPackAllStatementsMaxFilesBounds(t *testing.T) {
+	statements := []Statement{newStatement(map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": "*"})}
+
+	tests := []struct {
+		name      string
+		userInput inputs.UserInput
+		expected  string
+	}{
+		{name: "zero", userInput: inputs.UserInput{MaxFiles: 0}, expected: "invalid max files 0, must be 1 or more"},
+		{name: "negative", userInput: inputs.UserInput{MaxFiles: -1}, expected: "invalid max files -1, must be 1 or more"},
+		{name: "over the limit", userInput: inputs.UserInput{MaxFiles: config.MaxAllowedFiles + 1}, expected: "at most 5 SCP policies"},
+		{name: "rcp over the limit", userInput: inputs.UserInput{MaxFiles: config.MaxAllowedFiles + 1, PolicyType: config.PolicyTypeRCP}, expected: "at most 5 RCP policies"},
+		{name: "at the limit", userInput: inputs.UserInput{MaxFiles: config.MaxAllowedFiles}},
+		{name: "iam over the limit", userInput: inputs.UserInput{MaxFiles: config.MaxAllowedFiles + 1, PolicyType: config.PolicyTypeIAM}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packedFiles, err := packAllStatements(tt.userInput, Header{Version: config.SCPVersion}, statements)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if len(packedFiles) != 1 {
+					t.Errorf("Expected one file, got %d", len(packedFiles))
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestPackStatementsMaxStatements(t *testing.T) {
+	var statements []Statement
+	for i := 0; i < 5; i++ {
+		statements = append(statements, Statement{Content: map[string]interface{}{"Effect": "Deny"}, Size: 100})
+	}
+
+	// all five fit in one file by size, the cap splits them
+	uncapped, _ := packStatements(inputs.UserInput{MaxFiles: 5}, statements, 50)
+	if len(uncapped) != 1 {
+		t.Fatalf("Expected 1 file without a cap, got %d", len(uncapped))
+	}
+
+	for _, strategy := range []string{config.StrategyFirstFit, config.StrategyBestFit, config.StrategyBalance} {
+		t.Run(strategy, func(t *testing.T) {
+			userInput := inputs.UserInput{MaxFiles: 5, MaxStatements: 2, Strategy: strategy}
+			capped, unplaced := packStatements(userInput, statements, 50)
+			if len(unplaced) != 0 {
+				t.Fatalf("Expected every statement to be placed, got %d unplaced", len(unplaced))
+			}
+			if len(capped) != 3 {
+				t.Fatalf("Expected 3 files with a cap of 2, got %d", len(capped))
+			}
+			for i, file := range capped {
+				if len(file) > 2 {
+					t.Errorf("File %d has %d statements, cap is 2", i, len(file))
+				}
+			}
+		})
+	}
+
+	// the cap can make statements unplaceable within MaxFiles
+	_, unplaced := packStatements(inputs.UserInput{MaxFiles: 2, MaxStatements: 2}, statements, 50)
+	if len(unplaced) != 1 {
+		t.Errorf("Expected 1 unplaced statement, got %d", len(unplaced))
+	}
+}
+
+func TestPackStatementsMaxSize(t *testing.T) {
+	var statements []Statement
+	for i := 0; i < 4; i++ {
+		statements = append(statements, Statement{Content: map[string]interface{}{"Effect": "Deny"}, Size: 400})
+	}
+
+	// all four fit in one file at the default limit
+	unlimited, _ := packStatements(inputs.UserInput{MaxFiles: 5}, statements, 50)
+	if len(unlimited) != 1 {
+		t.Fatalf("Expected 1 file at the default limit, got %d", len(unlimited))
+	}
+
+	for _, strategy := range []string{config.StrategyFirstFit, config.StrategyBestFit, config.StrategyBalance} {
+		t.Run(strategy, func(t *testing.T) {
+			// two statements and a separator fit within 1000, three do not
+			userInput := inputs.UserInput{MaxFiles: 5, MaxSize: 1000, Strategy: strategy}
+			packed, unplaced := packStatements(userInput, statements, 50)
+			if len(unplaced) != 0 {
+				t.Fatalf("Expected every statement to be placed, got %d unplaced", len(unplaced))
+			}
+			if len(packed) != 2 {
+				t.Fatalf("Expected 2 files within 1000 characters, got %d", len(packed))
+			}
+		})
+	}
+
+	// a statement larger than the limit cannot be placed
+	_, unplaced := packStatements(inputs.UserInput{MaxFiles: 5, MaxSize: 300}, statements, 50)
+	if len(unplaced) != len(statements) {
+		t.Errorf("Expected %d unplaced statements, got %d", len(statements), len(unplaced))
+	}
+}
+
+func TestPackStatementsHeadroom(t *testing.T) {
+	var statements []Statement
+	for i := 0; i < 12; i++ {
+		statements = append(statements, Statement{Content: map[string]interface{}{"Effect": "Deny"}, Size: 400})
+	}
+
+	// twelve statements fill one file to 4,861 characters, within the limit but not the headroom
+	full, _ := packStatements(inputs.UserInput{MaxFiles: 5}, statements, 50)
+	if len(full) != 1 {
+		t.Fatalf("Expected 1 file without headroom, got %d", len(full))
+	}
+
+	for _, strategy := range []string{config.StrategyFirstFit, config.StrategyBestFit, config.StrategyBalance} {
+		t.Run(strategy, func(t *testing.T) {
+			userInput := inputs.UserInput{MaxFiles: 5, Headroom: 1024, Strategy: strategy}
+			packed, unplaced := packStatements(userInput, statements, 50)
+			if len(unplaced) != 0 {
+				t.Fatalf("Expected every statement to be placed, got %d unplaced", len(unplaced))
+			}
+			if len(packed) != 2 {
+				t.Fatalf("Expected 2 files with headroom, got %d", len(packed))
+			}
+			for i, file := range packed {
+				size := 50 + len(file) - 1
+				for _, stmt := range file {
+					size += stmt.Size
+				}
+				if limit := config.MaxPolicySize - userInput.Headroom; size > limit {
+					t.Errorf("File %d is %d characters, over the %d left by the headroom", i, size, limit)
+				}
+			}
+		})
+	}
+}
+
+func TestPackStatementsDeterministic(t *testing.T) {
+	var statements []Statement
+	for _, service := range []string{"s3", "ec2", "iam", "kms", "sns", "sqs", "rds", "efs"} {
+		statements = append(statements, newStatement(map[string]interface{}{
+			"Effect":   "Deny",
+			"Action":   service + ":*",
+			"Resource": "*",
+		}))
+	}
+
+	pack := func(order []int) string {
+		shuffled := make([]Statement, len(statements))
+		for i, j := range order {
+			shuffled[i] = statements[j]
+		}
+		// a small limit spreads the equal-sized statements across files
+		packed, unplaced := packStatements(inputs.UserInput{MaxFiles: 5, MaxSize: 200}, shuffled, 50)
+		if len(unplaced) != 0 {
+			t.Fatalf("Expected every statement to be placed, got %d unplaced", len(unplaced))
+		}
+		var b strings.Builder
+		for _, file := range packed {
+			for _, stmt := range file {
+				b.Write(stmt.rawJSON())
+			}
+			b.WriteString("\n")
+		}
+		return b.String()
+	}
+
+	expected := pack([]int{0, 1, 2, 3, 4, 5, 6, 7})
+	for _, order := range [][]int{{7, 6, 5, 4, 3, 2, 1, 0}, {3, 0, 6, 1, 7, 2, 5, 4}, {1, 3, 5, 7, 0, 2, 4, 6}} {
+		if result := pack(order); result != expected {
+			t.Errorf("Expected the same packing for input order %v, got\n%s\nwant\n%s", order, result, expected)
+		}
+	}
+}
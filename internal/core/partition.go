@@ -0,0 +1,48 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+func (m PartitionMismatch) String() string {
+	return fmt.Sprintf("%s: Statement[%d] resource %s uses partition %s",
+		filepath.Base(m.Statement.Source), m.Statement.Index, m.ARN, m.Partition)
+}
+
+// reportPartitionMismatches warns about each resource ARN outside the partition
+func reportPartitionMismatches(statements []Statement, partition string) {
+	for _, mismatch := range findPartitionMismatches(statements, partition) {
+		slog.Warn("resource uses another partition", "file", filepath.Base(mismatch.Statement.Source),
+			"index", mismatch.Statement.Index, "resource", mismatch.ARN, "partition", mismatch.Partition, "expected", partition)
+	}
+}
+
+// findPartitionMismatches returns every Resource and NotResource ARN naming another partition.
+// A wildcard partition (arn:*: or arn:aws*:) matches any, and non-ARN resources such as "*" are skipped.
+func findPartitionMismatches(statements []Statement, partition string) []PartitionMismatch {
+	var mismatches []PartitionMismatch
+	for _, stmt := range statements {
+		for _, element := range []string{"Resource", "NotResource"} {
+			for _, resource := range actionList(stmt.Content[element]) {
+				arnPartition, ok := partitionOf(resource)
+				if !ok || arnPartition == partition || strings.Contains(arnPartition, "*") {
+					continue
+				}
+				mismatches = append(mismatches, PartitionMismatch{Statement: stmt, ARN: resource, Partition: arnPartition})
+			}
+		}
+	}
+	return mismatches
+}
+
+// partitionOf returns the partition field of an ARN, arn:partition:service:...
+func partitionOf(resource string) (string, bool) {
+	fields := strings.SplitN(resource, ":", 3)
+	if len(fields) < 3 || fields[0] != "arn" {
+		return "", false
+	}
+	return fields[1], true
+}
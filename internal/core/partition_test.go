@@ -0,0 +1,63 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+)
+
+func TestFindPartitionMismatches(t *testing.T) {
+	statements := []Statement{
+		{Index: 0, Content: map[string]interface{}{
+			"Effect":   "Deny",
+			"Action":   "s3:*",
+			"Resource": []interface{}{"arn:aws-cn:s3:::bucket", "arn:aws:s3:::bucket"},
+		}},
+		{Index: 1, Content: map[string]interface{}{
+			"Effect":      "Deny",
+			"Action":      "iam:*",
+			"NotResource": "arn:aws-us-gov:iam::*:role/admin",
+		}},
+		{Index: 2, Content: map[string]interface{}{
+			"Effect":   "Deny",
+			"Action":   "ec2:*",
+			"Resource": []interface{}{"*", "arn:*:ec2:*:*:instance/*", "arn:aws*:ec2:*:*:volume/*"},
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		partition string
+		expected  []string // mismatched ARNs, in order
+	}{
+		{
+			name:      "china",
+			partition: config.PartitionChina,
+			expected:  []string{"arn:aws:s3:::bucket", "arn:aws-us-gov:iam::*:role/admin"},
+		},
+		{
+			name:      "govcloud",
+			partition: config.PartitionGovCloud,
+			expected:  []string{"arn:aws-cn:s3:::bucket", "arn:aws:s3:::bucket"},
+		},
+		{
+			name:      "standard",
+			partition: config.PartitionAWS,
+			expected:  []string{"arn:aws-cn:s3:::bucket", "arn:aws-us-gov:iam::*:role/admin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := findPartitionMismatches(statements, tt.partition)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %d mismatches, got %v", len(tt.expected), result)
+			}
+			for i, expected := range tt.expected {
+				if result[i].ARN != expected {
+					t.Errorf("Expected mismatch %d to be %s, got %s", i, expected, result[i].ARN)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// reportPermissive warns about each Allow broad enough that it is probably a mistake
+func reportPermissive(statements []Statement) {
+	for _, violation := range validateStatements(statements, lintPermissive) {
+		slog.Warn(violation.String())
+	}
+}
+
+// lintPermissive flags an Allow that applies to every resource with every action, every action of a
+// service, or everything but a NotAction list. A Condition is taken as scoping the statement deliberately.
+func lintPermissive(content map[string]interface{}) []string {
+	if content["Effect"] != "Allow" || content["Condition"] != nil || !containsWildcard(content["Resource"]) {
+		return nil
+	}
+
+	if _, ok := content["NotAction"]; ok {
+		return []string{"Allow with NotAction on every resource grants every action not listed"}
+	}
+
+	var messages []string
+	for _, action := range actionList(content["Action"]) {
+		switch {
+		case action == "*" || action == "*:*":
+			messages = append(messages, "Allow grants every action on every resource")
+		case strings.HasSuffix(action, ":*"):
+			messages = append(messages, fmt.Sprintf("Allow grants every %s action on every resource", strings.TrimSuffix(action, ":*")))
+		}
+	}
+	return messages
+}
+
+func containsWildcard(value interface{}) bool {
+	for _, entry := range actionList(value) {
+		if entry == "*" {
+			return true
+		}
+	}
+	return false
+}
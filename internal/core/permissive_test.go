@@ -0,0 +1,68 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintPermissive(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  map[string]interface{}
+		expected []string // substrings expected in the warnings, in order
+	}{
+		{
+			name:     "allow everything",
+			content:  map[string]interface{}{"Effect": "Allow", "Action": "*", "Resource": "*"},
+			expected: []string{"every action on every resource"},
+		},
+		{
+			name:     "allow everything in arrays",
+			content:  map[string]interface{}{"Effect": "Allow", "Action": []interface{}{"*:*"}, "Resource": []interface{}{"*"}},
+			expected: []string{"every action on every resource"},
+		},
+		{
+			name:     "allow a whole service",
+			content:  map[string]interface{}{"Effect": "Allow", "Action": []interface{}{"s3:GetObject", "iam:*"}, "Resource": "*"},
+			expected: []string{"every iam action"},
+		},
+		{
+			name:     "allow with not action",
+			content:  map[string]interface{}{"Effect": "Allow", "NotAction": "iam:*", "Resource": "*"},
+			expected: []string{"NotAction"},
+		},
+		{
+			name:    "scoped allow",
+			content: map[string]interface{}{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"},
+		},
+		{
+			name:    "wildcard action on a scoped resource",
+			content: map[string]interface{}{"Effect": "Allow", "Action": "*", "Resource": "arn:aws:s3:::bucket/*"},
+		},
+		{
+			name: "conditional allow",
+			content: map[string]interface{}{
+				"Effect": "Allow", "Action": "*", "Resource": "*",
+				"Condition": map[string]interface{}{"StringEquals": map[string]interface{}{"aws:RequestedRegion": "eu-west-1"}},
+			},
+		},
+		{
+			name:    "deny everything",
+			content: map[string]interface{}{"Effect": "Deny", "Action": "*", "Resource": "*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := lintPermissive(tt.content)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %d warnings, got %v", len(tt.expected), result)
+			}
+			for i, expected := range tt.expected {
+				if !strings.Contains(result[i], expected) {
+					t.Errorf("Expected warning %d to contain %q, got %q", i, expected, result[i])
+				}
+			}
+		})
+	}
+}
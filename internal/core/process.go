@@ -1,17 +1,380 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jakebark/corset/internal/config"
 	"github.com/jakebark/corset/internal/inputs"
 )
 
-func ProcessFiles(userInput inputs.UserInput, files []string) {
-	allStatements := extractAllStatements(files)
+// ErrNoStatements is returned when the input files contain no policy statements
+var ErrNoStatements = errors.New("no policy statements found")
+
+// ProcessFiles packs the statements into output files, returning a summary of what was written
+// without printing it. On an error, the summary holds any files that were written before it.
+func ProcessFiles(userInput inputs.UserInput, files []string) (ResultsSummary, error) {
+	summary, err := processGroups(userInput, files)
+	if err != nil {
+		return summary, err
+	}
+
+	// the files are written either way, --fail-on-threshold only sets the exit code
+	names := make([]string, len(summary.Files))
+	sizes := make([]int, len(summary.Files))
+	for i, result := range summary.Files {
+		names[i], sizes[i] = filepath.Base(result.Filename), result.Size
+		if result.Resource != "" {
+			names[i] = result.Resource
+		}
+	}
+	return summary, reportThreshold(userInput, names, sizes)
+}
+
+// processGroups packs the files together, on their own with --no-combine or by prefix with --group-by-prefix
+func processGroups(userInput inputs.UserInput, files []string) (ResultsSummary, error) {
+	// each group records its files' policies as it reads them, to compare them all once they are packed
+	policies := &filePolicies{}
+	defer reportDuplicateFiles(policies)
+
+	var groups []fileGroup
+	switch {
+	case userInput.NoCombine:
+		// each file is packed on its own and written back under its own name
+		userInput.IsDirectory = false
+		for _, file := range files {
+			groups = append(groups, fileGroup{name: file, files: []string{file}})
+		}
+	case userInput.GroupBy != "":
+		groups = prefixGroups(files, userInput.GroupBy)
+	default:
+		return processGroup(userInput, files, policies)
+	}
+
+	var summary ResultsSummary
+	var failed FileErrors
+	for _, group := range groups {
+		if userInput.GroupBy != "" {
+			userInput.Group = group.name
+		}
+		groupSummary, err := processGroup(userInput, group.files, policies)
+		if errors.Is(err, ErrNoStatements) {
+			if !userInput.Quiet {
+				slog.Warn(err.Error(), "group", group.name)
+			}
+			continue
+		}
+		summary.add(groupSummary)
+		if err == nil {
+			continue
+		}
+		// --continue-on-error keeps what the group wrote and moves on to the next
+		if groupFailed, ok := err.(FileErrors); ok {
+			failed = append(failed, groupFailed...)
+			continue
+		}
+		if !userInput.ContinueOnErr {
+			return summary, fmt.Errorf("%s: %w", group.name, err)
+		}
+		failed = append(failed, &FileError{File: group.name, Err: err})
+	}
+	if len(summary.Files) == 0 && len(failed) == 0 {
+		return ResultsSummary{}, ErrNoStatements
+	}
+	return summary, failed.err()
+}
+
+// fileGroup is a set of files packed together, apart from the others
+type fileGroup struct {
+	name  string
+	files []string
+}
+
+// prefixGroups groups the files by their name up to the delimiter, or their whole name without one,
+// in the order each prefix is first seen
+func prefixGroups(files []string, delimiter string) []fileGroup {
+	var groups []fileGroup
+	index := make(map[string]int)
+	for _, file := range files {
+		prefix := filepath.Base(strings.TrimSuffix(file, ".gz"))
+		prefix = strings.TrimSuffix(prefix, filepath.Ext(prefix))
+		if i := strings.Index(prefix, delimiter); i > 0 {
+			prefix = prefix[:i]
+		}
+		if i, ok := index[prefix]; ok {
+			groups[i].files = append(groups[i].files, file)
+			continue
+		}
+		index[prefix] = len(groups)
+		groups = append(groups, fileGroup{name: prefix, files: []string{file}})
+	}
+	return groups
+}
+
+// processGroup pools the statements of the files and packs them together
+func processGroup(userInput inputs.UserInput, files []string, policies *filePolicies) (ResultsSummary, error) {
+	allStatements, header, fileHeaders, failed, err := readFiles(userInput, files)
+	if err != nil {
+		return ResultsSummary{}, err
+	}
+	policies.add(files, fileHeaders, allStatements)
+	if len(allStatements) == 0 {
+		return ResultsSummary{}, failed.or(ErrNoStatements)
+	}
+	// files that failed are left in place, rather than replaced by the outputs
+	files = failed.exclude(files)
+	violations, err := validateSchema(userInput, files, fileHeaders, allStatements)
+	if err != nil {
+		return ResultsSummary{}, err
+	}
+	if err := reportSchemaViolations(userInput, violations); err != nil {
+		return ResultsSummary{}, err
+	}
+
+	statementsRead := len(allStatements)
+	packedFiles, err := packGroup(userInput, header, allStatements)
+	if userInput.Check {
+		if err := checkFit(userInput, header, packedFiles, err); err != nil {
+			return ResultsSummary{}, err
+		}
+		return ResultsSummary{}, failed.err()
+	}
+	if err != nil {
+		return ResultsSummary{}, err
+	}
+	summary, err := buildOutput(userInput, header, packedFiles, files, statementsRead)
+	if err != nil {
+		return summary, errors.Join(err, failed.err())
+	}
+	return summary, failed.err()
+}
+
+// packGroup checks and rewrites the statements, then packs them into files, or into one for merge
+func packGroup(userInput inputs.UserInput, header Header, allStatements []Statement) ([][]Statement, error) {
+	// invalid effects, and principals in an SCP, are always rejected. --validate runs the full checks
+	check := basicCheck(userInput)
+	if userInput.Validate {
+		check = structuralCheck(userInput)
+	}
+	check, err := statementCheck(userInput, check)
+	if err != nil {
+		return nil, err
+	}
+	if violations := validateStatements(allStatements, check); len(violations) > 0 {
+		for _, violation := range violations {
+			slog.Error(violation.String())
+		}
+		return nil, fmt.Errorf("%d invalid statements", len(violations))
+	}
+	if userInput.Validate {
+		reportConflicts(allStatements)
+	} else {
+		// --validate already rejects these as errors
+		reportEmptyElements(allStatements)
+	}
+	if userInput.Partition != "" {
+		reportPartitionMismatches(allStatements, userInput.Partition)
+	}
+	if userInput.Lint {
+		reportPermissive(allStatements)
+	}
+
+	allStatements, removed := transformStatements(userInput, allStatements)
+	if userInput.Dedupe && !userInput.JSON && !userInput.Quiet {
+		slog.Info("removed duplicate statements", "count", removed)
+	}
+
+	// merge combines everything into one file, ignoring the size limit
+	if userInput.Command == config.CommandMerge {
+		return [][]Statement{allStatements}, nil
+	}
+
+	if userInput.Explode {
+		return explodeStatements(userInput, header, allStatements)
+	}
+
+	packedFiles, err := packAllStatements(userInput, header, allStatements)
+	if userInput.Output != "" {
+		err = singleFileError(userInput, header, allStatements, err)
+	}
+	if err == nil && userInput.Verify {
+		err = verifyPacked(allStatements, packedFiles)
+	}
+	return packedFiles, err
+}
+
+// checkFit reports whether packing succeeded for --check, including how many characters could not be placed
+func checkFit(userInput inputs.UserInput, header Header, packedFiles [][]Statement, err error) error {
+	var packErr *PackError
+	if errors.As(err, &packErr) {
+		shortfall := 0
+		for _, stmt := range packErr.Unplaced {
+			shortfall += stmt.Size
+		}
+		return fmt.Errorf("check failed, %s characters could not be placed: %w", formatCount(shortfall), err)
+	}
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(packedFiles))
+	sizes := make([]int, len(packedFiles))
+	for i, file := range packedFiles {
+		names[i], sizes[i] = fmt.Sprintf("file %d", i+1), packedSize(userInput, header, file)
+	}
+	if err := reportThreshold(userInput, names, sizes); err != nil {
+		return fmt.Errorf("check failed, %w", err)
+	}
+
+	if userInput.Quiet {
+		return nil
+	}
+	statements := 0
+	for _, file := range packedFiles {
+		statements += len(file)
+	}
+	fmt.Fprintf(os.Stderr, "Check passed, %d statements fit in %d of %d files\n", statements, len(packedFiles), userInput.MaxFiles)
+	return nil
+}
+
+// reportThreshold warns about each file filled past --warn-threshold, failing with --fail-on-threshold
+func reportThreshold(userInput inputs.UserInput, names []string, sizes []int) error {
+	if userInput.WarnThreshold == 0 {
+		return nil
+	}
+	limit := maxPolicySize(userInput)
+	over := 0
+	for i, size := range sizes {
+		if size*100 > limit*userInput.WarnThreshold {
+			slog.Warn("file is over the warning threshold, adding to it may force a split", "file", names[i], "size", size,
+				"full", fmt.Sprintf("%d%%", fullnessPercent(size, limit)), "threshold", fmt.Sprintf("%d%%", userInput.WarnThreshold))
+			over++
+		}
+	}
+	if over > 0 && userInput.FailThreshold {
+		return fmt.Errorf("%d files are over the %d%% warning threshold", over, userInput.WarnThreshold)
+	}
+	return nil
+}
+
+// packedSize returns the characters AWS counts for a packed file's statements, measured minified as they are packed
+func packedSize(userInput inputs.UserInput, header Header, statements []Statement) int {
+	userInput.Whitespace = false
+	size := baseSize(userInput, header)
+	for i, stmt := range statements {
+		if i > 0 {
+			size += separatorSize(userInput)
+		}
+		size += stmt.Size
+	}
+	return size
+}
+
+// singleFileError replaces a PackError for --output, where giving the number of files that
+// didn't fit is less useful than how far over a single policy's limits the statements are
+func singleFileError(userInput inputs.UserInput, header Header, statements []Statement, err error) error {
+	var packErr *PackError
+	if !errors.As(err, &packErr) {
+		return err
+	}
+
+	limit := packingLimit(userInput)
+	size := packedSize(userInput, header, statements)
+	if size > limit {
+		return fmt.Errorf("statements do not fit in %s, %s characters is %s over the %s character limit of a single policy",
+			userInput.Output, formatCount(size), formatCount(size-limit), formatCount(limit))
+	}
+	return fmt.Errorf("statements do not fit in %s, %d statements is over --max-statements %d",
+		userInput.Output, len(statements), userInput.MaxStatements)
+}
+
+// PackData packs JSON policies held in memory, without reading or writing files or printing anything.
+// What packing logs goes to userInput.Logger, or the default logger without one.
+// Statements run through the same rewrites and packing as ProcessFiles. A policy that can't be parsed
+// is an error naming it, and invalid effects are rejected with an error listing each one.
+func PackData(userInput inputs.UserInput, policies [][]byte) ([]PackedPolicy, error) {
+	var allStatements []Statement
+	var header Header
+	var versions []string
+	seen := make(map[string]bool)
+	for i, data := range policies {
+		name := fmt.Sprintf("policies[%d].json", i)
+		statements, policyHeader, err := decodeStatements(name, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		allStatements = append(allStatements, statements...)
+
+		if version := policyHeader.Version; version != "" && !seen[version] {
+			seen[version] = true
+			versions = append(versions, version)
+		}
+		// first declared value wins
+		if header.Id == "" {
+			header.Id = policyHeader.Id
+		}
+	}
 	if len(allStatements) == 0 {
-		fmt.Println("No policy statements found")
-		return
+		return nil, ErrNoStatements
+	}
+	header.Version = resolveVersion(versions)
+
+	if violations := validateStatements(allStatements, validateEffect); len(violations) > 0 {
+		messages := make([]string, len(violations))
+		for i, violation := range violations {
+			messages[i] = violation.String()
+		}
+		return nil, fmt.Errorf("%d invalid statements:\n%s", len(violations), strings.Join(messages, "\n"))
+	}
+
+	allStatements, _ = transformStatements(userInput, allStatements)
+	packedFiles, err := packAllStatements(userInput, header, allStatements)
+	if err != nil {
+		return nil, err
 	}
 
-	packedFiles := packAllStatements(userInput, allStatements)
-	buildOutput(userInput, packedFiles, files)
+	packed := make([]PackedPolicy, len(packedFiles))
+	for i, statements := range packedFiles {
+		data := writeJSON(userInput, header, statements)
+		packed[i] = PackedPolicy{
+			Data:       data,
+			Size:       effectiveSize(userInput, header, statements, data),
+			Statements: len(statements),
+		}
+	}
+	return packed, nil
+}
+
+// transformStatements applies the flag-driven rewrites in order, returning how many duplicates were removed
+func transformStatements(userInput inputs.UserInput, statements []Statement) ([]Statement, int) {
+	// strip first, so dedupe and merge work on the statements as they will be written
+	if userInput.StripSid {
+		statements = stripSids(statements)
+	}
+	if userInput.SortActions {
+		statements = rewriteStatements(statements, sortElements)
+	}
+	if userInput.Collapse {
+		statements = rewriteStatements(statements, collapseArrays)
+	}
+	removed := 0
+	if userInput.Dedupe {
+		statements, removed = dedupeStatements(statements)
+	}
+	if userInput.Merge {
+		statements = mergeStatements(statements)
+	}
+	// after merging, which can bring a wildcard and the actions it covers together
+	if userInput.Optimize {
+		statements = optimizeStatements(statements)
+	}
+	// before packing, so the generated Sids are counted in each file's size
+	if userInput.Sid {
+		statements = assignSids(statements)
+	}
+	return statements, removed
 }
@@ -1,13 +1,23 @@
 package core
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jakebark/corset/internal/config"
 	"github.com/jakebark/corset/internal/inputs"
+	"github.com/jakebark/corset/internal/logging"
 )
 
 func TestProcessFiles(t *testing.T) {
@@ -317,6 +327,88 @@ func TestProcessFilesWithReplacement(t *testing.T) {
 	}
 }
 
+func TestProcessFilesReplacementKeepsMode(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.json")
+	// not minified, so the file is rewritten
+	policy := `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`
+	if err := os.WriteFile(testFile, []byte(policy), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	// set after writing so the umask doesn't clear the group write bit
+	if err := os.Chmod(testFile, 0664); err != nil {
+		t.Fatalf("Failed to chmod test file: %v", err)
+	}
+	before, err := os.Stat(testFile)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+
+	userInput := inputs.UserInput{
+		Target:   testFile,
+		MaxFiles: config.DefaultMaxFiles,
+	}
+	if _, err := ProcessFiles(userInput, []string{testFile}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	after, err := os.Stat(testFile)
+	if err != nil {
+		t.Fatalf("Failed to stat replaced file: %v", err)
+	}
+	if after.Mode().Perm() != 0664 {
+		t.Errorf("Expected mode 0664 to survive the replace, got %v", after.Mode().Perm())
+	}
+	if os.SameFile(before, after) {
+		t.Error("Expected the file to be replaced by a rename, not rewritten in place")
+	}
+}
+
+func TestProcessFilesOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	for i, action := range []string{"s3:*", "ec2:*"} {
+		policy := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"%s","Resource":"*"}]}`, action)
+		if err := os.WriteFile(filepath.Join(tempDir, fmt.Sprintf("policy%d.json", i)), []byte(policy), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+	files := FindJSONFilesInDirectory(inputs.UserInput{}, tempDir)
+
+	t.Run("fits", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "guardrails.json")
+		userInput := inputs.UserInput{Target: tempDir, IsDirectory: true, MaxFiles: 1, Output: output}
+		summary, err := ProcessFiles(userInput, files)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		results := summary.Files
+		if len(results) != 1 || results[0].Filename != output || results[0].Statements != 2 {
+			t.Errorf("Expected both statements in %s, got %+v", output, results)
+		}
+		// the inputs are left in place
+		for _, file := range files {
+			if _, err := os.Stat(file); err != nil {
+				t.Errorf("Expected input %s to remain: %v", file, err)
+			}
+		}
+	})
+
+	t.Run("does not fit", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "guardrails.json")
+		userInput := inputs.UserInput{Target: tempDir, IsDirectory: true, MaxFiles: 1, MaxSize: 100, Output: output}
+		_, err := ProcessFiles(userInput, files)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+		if !strings.Contains(err.Error(), "over the 100 character limit of a single policy") {
+			t.Errorf("Expected a single policy error, got %v", err)
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Error("Expected no output file to be written")
+		}
+	})
+}
+
 // Helper function to create large statements for testing
 func createLargeStatements(count int) []map[string]interface{} {
 	statements := make([]map[string]interface{}, count)
@@ -428,6 +520,75 @@ func TestDirectoryReplacement(t *testing.T) {
 	}
 }
 
+func TestDirectoryReplacementIdempotent(t *testing.T) {
+	targetDir := filepath.Join(t.TempDir(), "organisation-scp")
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatalf("Failed to create target directory: %v", err)
+	}
+	for i, action := range []string{"s3:*", "ec2:*"} {
+		policy := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"%s","Resource":"*"}]}`, action)
+		if err := os.WriteFile(filepath.Join(targetDir, fmt.Sprintf("policy%d.json", i)), []byte(policy), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	userInput := inputs.UserInput{
+		Target:      targetDir,
+		Targets:     []string{targetDir},
+		IsDirectory: true,
+		MaxFiles:    config.DefaultMaxFiles,
+	}
+	snapshot := func() map[string]string {
+		contents := map[string]string{}
+		entries, err := os.ReadDir(targetDir)
+		if err != nil {
+			t.Fatalf("Failed to read directory: %v", err)
+		}
+		for _, entry := range entries {
+			data, err := os.ReadFile(filepath.Join(targetDir, entry.Name()))
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", entry.Name(), err)
+			}
+			contents[entry.Name()] = string(data)
+		}
+		return contents
+	}
+
+	if _, err := ProcessFiles(userInput, ResolveFiles(userInput)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	once := snapshot()
+	if _, ok := once["organisation-scp.json"]; !ok || len(once) != 1 {
+		t.Fatalf("Expected only organisation-scp.json after the first run, got %v", once)
+	}
+
+	// the output holds the only copy of the statements, so it is read back in and written unchanged
+	if _, err := ProcessFiles(userInput, ResolveFiles(userInput)); err != nil {
+		t.Fatalf("Expected the second run to succeed, got %v", err)
+	}
+	if twice := snapshot(); !reflect.DeepEqual(twice, once) {
+		t.Errorf("Expected the second run to leave %v, got %v", once, twice)
+	}
+
+	// a file added later is packed with the statements already there, without --force
+	added := `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"iam:*","Resource":"*"}]}`
+	if err := os.WriteFile(filepath.Join(targetDir, "new.json"), []byte(added), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if _, err := ProcessFiles(userInput, ResolveFiles(userInput)); err != nil {
+		t.Fatalf("Expected the added file to be packed, got %v", err)
+	}
+	after := snapshot()
+	if len(after) != 1 {
+		t.Fatalf("Expected only organisation-scp.json after adding a file, got %v", after)
+	}
+	for _, action := range []string{"s3:*", "ec2:*", "iam:*"} {
+		if !strings.Contains(after["organisation-scp.json"], action) {
+			t.Errorf("Expected %s to be kept in the output, got %s", action, after["organisation-scp.json"])
+		}
+	}
+}
+
 func TestDirectoryReplacementMultipleFiles(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -505,3 +666,1035 @@ func TestDirectoryReplacementMultipleFiles(t *testing.T) {
 		t.Error("Expected no corset1.json file for directory replacement")
 	}
 }
+
+func TestProcessFilesValidate(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "invalid.json")
+	original := `{"Version": "2012-10-17", "Statement": [{"Effect": "Alow", "Action": "s3:*", "Resource": "*"}]}`
+	err := os.WriteFile(testFile, []byte(original), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	userInput := inputs.UserInput{
+		Target:   testFile,
+		MaxFiles: config.DefaultMaxFiles,
+		Validate: true,
+	}
+
+	ProcessFiles(userInput, []string{testFile})
+
+	// invalid input must not be rewritten
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	if string(data) != original {
+		t.Errorf("Expected invalid file to be left untouched, got %s", data)
+	}
+}
+
+func TestProcessFilesNoCombine(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"s3.json":  `{"Version": "2012-10-17", "Statement": [{"Sid": "S3", "Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`,
+		"iam.json": `{"Version": "2012-10-17", "Statement": [{"Sid": "IAM", "Effect": "Deny", "Action": "iam:*", "Resource": "*"}]}`,
+	}
+	var paths []string
+	for filename, content := range files {
+		path := filepath.Join(tempDir, filename)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	userInput := inputs.UserInput{
+		Target:      tempDir,
+		IsDirectory: true,
+		MaxFiles:    config.DefaultMaxFiles,
+		NoCombine:   true,
+	}
+	summary, err := ProcessFiles(userInput, paths)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	results := summary.Files
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 outputs, got %d", len(results))
+	}
+
+	expected := map[string]string{
+		"s3.json":  `{"Version":"2012-10-17","Statement":[{"Sid":"S3","Effect":"Deny","Action":"s3:*","Resource":"*"}]}`,
+		"iam.json": `{"Version":"2012-10-17","Statement":[{"Sid":"IAM","Effect":"Deny","Action":"iam:*","Resource":"*"}]}`,
+	}
+	for filename, want := range expected {
+		data, err := os.ReadFile(filepath.Join(tempDir, filename))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", filename, err)
+		}
+		if string(data) != want {
+			t.Errorf("Expected %s to be minified on its own, got %s", filename, data)
+		}
+	}
+
+	// nothing is combined into a directory output
+	if _, err := os.Stat(filepath.Join(tempDir, filepath.Base(tempDir)+".json")); err == nil {
+		t.Error("Expected no combined directory output")
+	}
+}
+
+func TestProcessFilesCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxFiles  int
+		expectErr bool
+	}{
+		{name: "fits", maxFiles: config.DefaultMaxFiles},
+		{name: "does not fit", maxFiles: 1, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			testFile := filepath.Join(tempDir, "policy.json")
+			original := mustMarshal(t, Policy{Version: config.SCPVersion, Statement: createLargeStatements(20)})
+			if err := os.WriteFile(testFile, []byte(original), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			userInput := inputs.UserInput{
+				Target:   testFile,
+				MaxFiles: tt.maxFiles,
+				Check:    true,
+			}
+			summary, err := ProcessFiles(userInput, []string{testFile})
+			if tt.expectErr {
+				var packErr *PackError
+				if !errors.As(err, &packErr) {
+					t.Fatalf("Expected a PackError, got %v", err)
+				}
+				if !strings.Contains(err.Error(), "characters could not be placed") {
+					t.Errorf("Expected the shortfall to be reported, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if summary.Files != nil {
+				t.Errorf("Expected no results, got %v", summary.Files)
+			}
+
+			// nothing is written either way
+			entries, _ := os.ReadDir(tempDir)
+			if len(entries) != 1 {
+				t.Errorf("Expected only the input file, got %d entries", len(entries))
+			}
+			if data, _ := os.ReadFile(testFile); string(data) != original {
+				t.Error("Expected the input file to be left untouched")
+			}
+		})
+	}
+}
+
+func TestProcessFilesRCP(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "rcp.json")
+
+	// enough RCP statements to need a second file
+	var statements []map[string]interface{}
+	for i := 0; i < 60; i++ {
+		statements = append(statements, map[string]interface{}{
+			"Sid":       fmt.Sprintf("DenyExternalAccess%d", i),
+			"Effect":    "Deny",
+			"Principal": "*",
+			"Action":    []string{"s3:GetObject", "s3:PutObject"},
+			"Resource":  fmt.Sprintf("arn:aws:s3:::bucket-%d/*", i),
+			"Condition": map[string]interface{}{
+				"StringNotEqualsIfExists": map[string]interface{}{"aws:PrincipalOrgID": "o-example"},
+			},
+		})
+	}
+	policy := map[string]interface{}{"Version": config.SCPVersion, "Statement": statements}
+	if err := os.WriteFile(testFile, []byte(mustMarshal(t, policy)), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	userInput := inputs.UserInput{
+		Target:     testFile,
+		MaxFiles:   config.DefaultMaxFiles,
+		Validate:   true,
+		PolicyType: config.PolicyTypeRCP,
+	}
+	summary, err := ProcessFiles(userInput, []string{testFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	results := summary.Files
+	if len(results) < 2 {
+		t.Fatalf("Expected the statements to be split across files, got %d", len(results))
+	}
+
+	total := 0
+	for _, result := range results {
+		if result.Size > config.MaxPolicySize {
+			t.Errorf("Expected %s within %d characters, got %d", result.Filename, config.MaxPolicySize, result.Size)
+		}
+		total += result.Statements
+	}
+	if total != len(statements) {
+		t.Errorf("Expected %d statements packed, got %d", len(statements), total)
+	}
+}
+
+func TestProcessFilesInvalidEffect(t *testing.T) {
+	tests := []struct {
+		name   string
+		effect string
+	}{
+		{name: "lowercase", effect: `"Effect": "allow", `},
+		{name: "typo", effect: `"Effect": "Alow", `},
+		{name: "missing", effect: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "policy.json")
+			original := `{"Version": "2012-10-17", "Statement": [{` + tt.effect + `"Action": "s3:*", "Resource": "*"}]}`
+			err := os.WriteFile(testFile, []byte(original), 0644)
+			if err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			userInput := inputs.UserInput{
+				Target:   testFile,
+				MaxFiles: config.DefaultMaxFiles,
+			}
+
+			ProcessFiles(userInput, []string{testFile})
+
+			data, err := os.ReadFile(testFile)
+			if err != nil {
+				t.Fatalf("Failed to read test file: %v", err)
+			}
+			if string(data) != original {
+				t.Errorf("Expected file with invalid Effect to be left untouched, got %s", data)
+			}
+		})
+	}
+}
+
+func TestProcessFilesMergeCommand(t *testing.T) {
+	targetDir := filepath.Join(t.TempDir(), "combined")
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatalf("Failed to create target directory: %v", err)
+	}
+
+	// large enough that split would need several files
+	statements := createLargeStatements(20)
+	var inputFiles []string
+	for i := 0; i < 2; i++ {
+		filename := filepath.Join(targetDir, "policy-"+string(rune('a'+i))+".json")
+		data, err := json.Marshal(Policy{Version: config.SCPVersion, Statement: statements[i*10 : (i+1)*10]})
+		if err != nil {
+			t.Fatalf("Failed to marshal test policy %d: %v", i, err)
+		}
+		if err := os.WriteFile(filename, data, 0644); err != nil {
+			t.Fatalf("Failed to write test file %d: %v", i, err)
+		}
+		inputFiles = append(inputFiles, filename)
+	}
+
+	userInput := inputs.UserInput{
+		Command:     config.CommandMerge,
+		Target:      targetDir,
+		IsDirectory: true,
+		MaxFiles:    config.DefaultMaxFiles,
+	}
+
+	ProcessFiles(userInput, inputFiles)
+
+	outputs, _ := filepath.Glob(filepath.Join(targetDir, "*.json"))
+	if len(outputs) != 1 {
+		t.Fatalf("Expected a single merged file, got %v", outputs)
+	}
+
+	data, err := os.ReadFile(outputs[0])
+	if err != nil {
+		t.Fatalf("Failed to read merged file: %v", err)
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		t.Fatalf("Merged file contains invalid JSON: %v", err)
+	}
+	if len(policy.Statement) != 20 {
+		t.Errorf("Expected 20 merged statements, got %d", len(policy.Statement))
+	}
+}
+
+func TestProcessFilesErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		maxFiles    int
+		expectError bool
+		sentinel    error
+	}{
+		{
+			name:     "success",
+			content:  `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`,
+			maxFiles: config.DefaultMaxFiles,
+		},
+		{
+			name:        "no statements",
+			content:     `{"Version": "2012-10-17", "Statement": []}`,
+			maxFiles:    config.DefaultMaxFiles,
+			expectError: true,
+			sentinel:    ErrNoStatements,
+		},
+		{
+			name:        "invalid statement",
+			content:     `{"Version": "2012-10-17", "Statement": [{"Effect": "Alow", "Action": "s3:*", "Resource": "*"}]}`,
+			maxFiles:    config.DefaultMaxFiles,
+			expectError: true,
+		},
+		{
+			name:        "does not fit",
+			content:     mustMarshal(t, Policy{Version: config.SCPVersion, Statement: createLargeStatements(20)}),
+			maxFiles:    1,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(testFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			userInput := inputs.UserInput{Target: testFile, MaxFiles: tt.maxFiles}
+			_, err := ProcessFiles(userInput, []string{testFile})
+
+			if tt.expectError && err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tt.sentinel != nil && !errors.Is(err, tt.sentinel) {
+				t.Errorf("Expected %v, got %v", tt.sentinel, err)
+			}
+		})
+	}
+}
+
+func TestProcessFilesWriteError(t *testing.T) {
+	targetDir := filepath.Join(t.TempDir(), "policies")
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatalf("Failed to create target directory: %v", err)
+	}
+	inputFile := filepath.Join(targetDir, "policy.json")
+	content := `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`
+	if err := os.WriteFile(inputFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// a directory in place of the output file makes the write fail
+	if err := os.Mkdir(filepath.Join(targetDir, "policies.json"), 0755); err != nil {
+		t.Fatalf("Failed to create blocking directory: %v", err)
+	}
+
+	userInput := inputs.UserInput{Target: targetDir, IsDirectory: true, MaxFiles: config.DefaultMaxFiles}
+	if _, err := ProcessFiles(userInput, []string{inputFile}); err == nil {
+		t.Fatal("Expected a write error, got nil")
+	}
+
+	// inputs are kept when an output could not be written
+	if _, err := os.Stat(inputFile); err != nil {
+		t.Errorf("Expected input file to be kept, got %v", err)
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	return string(data)
+}
+
+func TestProcessFilesPrincipalInSCP(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "policy.json")
+	original := `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Principal": "*", "Action": "s3:*", "Resource": "*"}]}`
+	if err := os.WriteFile(testFile, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	userInput := inputs.UserInput{Target: testFile, MaxFiles: config.DefaultMaxFiles}
+	if _, err := ProcessFiles(userInput, []string{testFile}); err == nil {
+		t.Error("Expected an error for a Principal in an SCP, got nil")
+	}
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	if string(data) != original {
+		t.Errorf("Expected file to be left untouched, got %s", data)
+	}
+}
+
+func TestProcessFilesIAM(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "iam.json")
+
+	// over the SCP limit but within the IAM one, with a Principal an SCP would reject
+	var statements []map[string]interface{}
+	for i := 0; i < 42; i++ {
+		statements = append(statements, map[string]interface{}{
+			"Effect":    "Allow",
+			"Principal": map[string]interface{}{"AWS": "arn:aws:iam::111122223333:root"},
+			"Action":    "s3:GetObject",
+			"Resource":  fmt.Sprintf("arn:aws:s3:::bucket-%d/*", i),
+		})
+	}
+	policy := map[string]interface{}{"Version": config.SCPVersion, "Statement": statements}
+	if err := os.WriteFile(testFile, []byte(mustMarshal(t, policy)), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	userInput := inputs.UserInput{
+		Target:     testFile,
+		MaxFiles:   1,
+		Validate:   true,
+		PolicyType: config.PolicyTypeIAM,
+	}
+	summary, err := ProcessFiles(userInput, []string{testFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	results := summary.Files
+	if len(results) != 1 {
+		t.Fatalf("Expected one file, got %d", len(results))
+	}
+	if results[0].Size <= config.MaxPolicySize || results[0].Size > config.MaxIAMPolicySize {
+		t.Errorf("Expected a size between the SCP and IAM limits, got %d", results[0].Size)
+	}
+}
+
+// captureLogs records what is logged at level and above, as the CLI prints it, until the test ends
+func captureLogs(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var logs bytes.Buffer
+	previous, flags := slog.Default(), log.Flags()
+	slog.SetDefault(slog.New(logging.NewHandler(&logs, level, config.LogFormatText)))
+	t.Cleanup(func() {
+		// restoring the default logger leaves the log package writing to the captured handler
+		slog.SetDefault(previous)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	})
+	return &logs
+}
+
+// captureOutput returns what fn prints to file, os.Stdout or os.Stderr
+func captureOutput(t *testing.T, file **os.File, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	original := *file
+	*file = w
+	defer func() { *file = original }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-output
+}
+
+func TestProcessFilesQuiet(t *testing.T) {
+	tempDir := t.TempDir()
+	policy := `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}, {"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`
+	policyFile := filepath.Join(tempDir, "policy.json")
+	emptyFile := filepath.Join(tempDir, "empty.json")
+	if err := os.WriteFile(policyFile, []byte(policy), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(emptyFile, []byte(`{"Version": "2012-10-17", "Statement": []}`), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	logs := captureLogs(t, slog.LevelInfo)
+
+	for _, quiet := range []bool{false, true} {
+		userInput := inputs.UserInput{
+			Target:      tempDir,
+			IsDirectory: true,
+			MaxFiles:    config.DefaultMaxFiles,
+			NoCombine:   true,
+			Dedupe:      true,
+			Force:       true,
+			Quiet:       quiet,
+		}
+		logs.Reset()
+		var summary ResultsSummary
+		var err error
+		var stdout string
+		output := captureOutput(t, &os.Stderr, func() {
+			stdout = captureOutput(t, &os.Stdout, func() {
+				summary, err = ProcessFiles(userInput, []string{policyFile, emptyFile})
+				ReportResults(userInput, summary)
+			})
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(summary.Files) != 1 || summary.Files[0].Statements != 1 {
+			t.Fatalf("Expected one file with the deduplicated statement, got %+v", summary.Files)
+		}
+
+		if stdout != "" {
+			t.Errorf("Expected nothing on stdout, got %q", stdout)
+		}
+		if quiet {
+			if output != "" {
+				t.Errorf("Expected no output with --quiet, got %q", output)
+			}
+			if logs.Len() != 0 {
+				t.Errorf("Expected no notice of the empty file with --quiet, got %q", logs.String())
+			}
+		} else {
+			if !strings.Contains(output, "Split into 1 files") {
+				t.Errorf("Expected the summary without --quiet, got %q", output)
+			}
+			if !strings.Contains(logs.String(), "removed duplicate statements count=1") {
+				t.Errorf("Expected the duplicates removed to be logged, got %q", logs.String())
+			}
+			if !strings.Contains(logs.String(), ErrNoStatements.Error()) {
+				t.Errorf("Expected a notice of the empty file, got %q", logs.String())
+			}
+		}
+	}
+}
+
+func TestProcessFilesOutputStreams(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "policy.json")
+	policy := `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`
+
+	tests := []struct {
+		name       string
+		json       bool
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "summary on stderr", wantStderr: "Split into 1 files"},
+		{name: "json on stdout", json: true, wantStdout: `"statements_written":1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(testFile, []byte(policy), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+			userInput := inputs.UserInput{Target: testFile, MaxFiles: config.DefaultMaxFiles, JSON: tt.json}
+
+			var err error
+			var stdout string
+			stderr := captureOutput(t, &os.Stderr, func() {
+				stdout = captureOutput(t, &os.Stdout, func() {
+					var summary ResultsSummary
+					summary, err = ProcessFiles(userInput, []string{testFile})
+					ReportResults(userInput, summary)
+				})
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if tt.wantStdout == "" && stdout != "" {
+				t.Errorf("Expected nothing on stdout, got %q", stdout)
+			}
+			if !strings.Contains(stdout, tt.wantStdout) {
+				t.Errorf("Expected stdout to contain %q, got %q", tt.wantStdout, stdout)
+			}
+			if tt.wantStderr == "" && stderr != "" {
+				t.Errorf("Expected nothing on stderr, got %q", stderr)
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("Expected stderr to contain %q, got %q", tt.wantStderr, stderr)
+			}
+		})
+	}
+}
+
+func TestProcessFilesUnchanged(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "policy.json")
+	policy := `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`
+	if err := os.WriteFile(testFile, []byte(policy), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	userInput := inputs.UserInput{Target: testFile, MaxFiles: config.DefaultMaxFiles}
+
+	summary, err := ProcessFiles(userInput, []string{testFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if summary.Files[0].Unchanged {
+		t.Fatal("Expected the first run to minify the file")
+	}
+
+	// backdate the output, so a rewrite would show in the modification time
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(testFile, past, past); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+
+	stderr := captureOutput(t, &os.Stderr, func() {
+		summary, err = ProcessFiles(userInput, []string{testFile})
+		ReportResults(userInput, summary)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !summary.Files[0].Unchanged {
+		t.Error("Expected the second run to find no changes")
+	}
+	if !strings.Contains(stderr, "no changes") {
+		t.Errorf("Expected the summary to report no changes, got %q", stderr)
+	}
+	info, err := os.Stat(testFile)
+	if err != nil {
+		t.Fatalf("Failed to stat output: %v", err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("Expected the modification time to stay %v, got %v", past, info.ModTime())
+	}
+}
+
+func TestProcessFilesGroupByPrefix(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"teamA-1.json": `{"Version": "2012-10-17", "Statement": [{"Sid": "A1", "Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`,
+		"teamA-2.json": `{"Version": "2012-10-17", "Statement": [{"Sid": "A2", "Effect": "Deny", "Action": "ec2:*", "Resource": "*"}]}`,
+		"teamB-1.json": `{"Version": "2012-10-17", "Statement": [{"Sid": "B1", "Effect": "Deny", "Action": "iam:*", "Resource": "*"}]}`,
+	}
+	var paths []string
+	for _, name := range []string{"teamA-1.json", "teamA-2.json", "teamB-1.json"} {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	userInput := inputs.UserInput{
+		Target:      tempDir,
+		IsDirectory: true,
+		MaxFiles:    config.DefaultMaxFiles,
+		GroupBy:     config.DefaultGroupDelimiter,
+	}
+	summary, err := ProcessFiles(userInput, paths)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	results := summary.Files
+	if len(results) != 2 {
+		t.Fatalf("Expected one output per group, got %d", len(results))
+	}
+
+	expected := map[string]string{
+		"teamA.json": `{"Version":"2012-10-17","Statement":[{"Sid":"A2","Effect":"Deny","Action":"ec2:*","Resource":"*"},{"Sid":"A1","Effect":"Deny","Action":"s3:*","Resource":"*"}]}`,
+		"teamB.json": `{"Version":"2012-10-17","Statement":[{"Sid":"B1","Effect":"Deny","Action":"iam:*","Resource":"*"}]}`,
+	}
+	for i, name := range []string{"teamA.json", "teamB.json"} {
+		if results[i].Filename != filepath.Join(tempDir, name) {
+			t.Errorf("Expected result %d to be %s, got %s", i, name, results[i].Filename)
+		}
+		data, err := os.ReadFile(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(data) != expected[name] {
+			t.Errorf("Expected %s to hold only its group, got %s", name, data)
+		}
+	}
+
+	// the grouped inputs are replaced by their group's output
+	for _, path := range paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", path)
+		}
+	}
+}
+
+func TestProcessFilesSummary(t *testing.T) {
+	contents := []string{
+		`{"Version": "2012-10-17", "Statement": [{"Sid": "A1", "Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`,
+		`{"Version": "2012-10-17", "Statement": [
+			{"Sid": "A2", "Effect": "Deny", "Action": "ec2:*", "Resource": "*"},
+			{"Sid": "A3", "Effect": "Deny", "Action": "ec2:*", "Resource": "*"}
+		]}`,
+	}
+
+	for _, noCombine := range []bool{false, true} {
+		tempDir := t.TempDir()
+		var paths []string
+		inputSize := 0
+		for i, content := range contents {
+			path := filepath.Join(tempDir, fmt.Sprintf("policy-%d.json", i+1))
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+			paths = append(paths, path)
+			inputSize += len(content)
+		}
+
+		userInput := inputs.UserInput{
+			Target:      tempDir,
+			IsDirectory: true,
+			MaxFiles:    config.DefaultMaxFiles,
+			NoCombine:   noCombine,
+			Dedupe:      true,
+			Quiet:       true,
+		}
+		var summary ResultsSummary
+		var err error
+		output := captureOutput(t, &os.Stderr, func() {
+			summary, err = ProcessFiles(userInput, paths)
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if output != "" {
+			t.Errorf("Expected ProcessFiles to print nothing, got %q", output)
+		}
+
+		expectedFiles := 1
+		if noCombine {
+			expectedFiles = 2
+		}
+		if len(summary.Inputs) != 2 || summary.Inputs[0] != paths[0] || summary.Inputs[1] != paths[1] {
+			t.Errorf("Expected the inputs %v, got %v", paths, summary.Inputs)
+		}
+		if len(summary.Files) != expectedFiles {
+			t.Fatalf("Expected %d output files with no-combine %v, got %d", expectedFiles, noCombine, len(summary.Files))
+		}
+		if summary.InputSize != inputSize {
+			t.Errorf("Expected an input size of %d, got %d", inputSize, summary.InputSize)
+		}
+		outputSize := 0
+		for _, result := range summary.Files {
+			data, err := os.ReadFile(result.Filename)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", result.Filename, err)
+			}
+			outputSize += len(data)
+		}
+		if summary.OutputSize != outputSize {
+			t.Errorf("Expected an output size of %d, got %d", outputSize, summary.OutputSize)
+		}
+		if summary.StatementsRead != 3 || summary.StatementsWritten != 2 {
+			t.Errorf("Expected statements 3 -> 2 after dedupe, got %d -> %d", summary.StatementsRead, summary.StatementsWritten)
+		}
+	}
+}
+
+func TestProcessFilesExplode(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "policy.json")
+	policy := `{"Version": "2012-10-17", "Statement": [
+		{"Sid": "DenyS3", "Effect": "Deny", "Action": "s3:*", "Resource": "*"},
+		{"Sid": "DenyEC2", "Effect": "Deny", "Action": "ec2:*", "Resource": "*"},
+		{"Sid": "DenyIAM", "Effect": "Deny", "Action": "iam:*", "Resource": "*"}
+	]}`
+	if err := os.WriteFile(testFile, []byte(policy), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	userInput := inputs.UserInput{Target: testFile, MaxFiles: config.DefaultMaxFiles, Explode: true}
+	summary, err := ProcessFiles(userInput, []string{testFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		name string
+		sid  string
+	}{
+		{"policy.json", "DenyS3"},
+		{"policy-2.json", "DenyEC2"},
+		{"policy-3.json", "DenyIAM"},
+	}
+	if len(summary.Files) != len(expected) {
+		t.Fatalf("Expected %d files, got %d", len(expected), len(summary.Files))
+	}
+	for i, want := range expected {
+		if summary.Files[i].Filename != filepath.Join(tempDir, want.name) {
+			t.Errorf("Expected file %d to be %s, got %s", i+1, want.name, summary.Files[i].Filename)
+		}
+		statements, _ := extractAllStatements([]string{filepath.Join(tempDir, want.name)})
+		if len(statements) != 1 {
+			t.Fatalf("Expected %s to hold one statement, got %d", want.name, len(statements))
+		}
+		if sid := statements[0].Content["Sid"]; sid != want.sid {
+			t.Errorf("Expected %s to hold %s, got %v", want.name, want.sid, sid)
+		}
+	}
+}
+
+func TestExplodeStatementsWarning(t *testing.T) {
+	logs := captureLogs(t, slog.LevelInfo)
+
+	statements := largeStatements(t, config.MaxAllowedFiles+1)
+	files, err := explodeStatements(inputs.UserInput{MaxFiles: config.DefaultMaxFiles}, Header{Version: config.SCPVersion}, statements)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) != len(statements) {
+		t.Errorf("Expected %d files, got %d", len(statements), len(files))
+	}
+	if !strings.Contains(logs.String(), "limit=5 type=SCP") {
+		t.Errorf("Expected a warning about the attachment limit, got %q", logs.String())
+	}
+
+	logs.Reset()
+	if _, err := explodeStatements(inputs.UserInput{PolicyType: config.PolicyTypeIAM}, Header{Version: config.SCPVersion}, statements); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected no warning for IAM policies, got %q", logs.String())
+	}
+}
+
+func TestProcessFilesContinueOnError(t *testing.T) {
+	for _, continueOnErr := range []bool{false, true} {
+		t.Run(fmt.Sprintf("continue %v", continueOnErr), func(t *testing.T) {
+			tempDir := t.TempDir()
+			validFile := filepath.Join(tempDir, "valid.json")
+			malformedFile := filepath.Join(tempDir, "malformed.json")
+			valid := `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`
+			malformed := `{"Version": "2012-10-17", "Statement": [`
+			if err := os.WriteFile(validFile, []byte(valid), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+			if err := os.WriteFile(malformedFile, []byte(malformed), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			userInput := inputs.UserInput{
+				Target:        tempDir,
+				IsDirectory:   true,
+				MaxFiles:      config.DefaultMaxFiles,
+				ContinueOnErr: continueOnErr,
+			}
+			summary, err := ProcessFiles(userInput, []string{malformedFile, validFile})
+
+			var fileErr *FileError
+			if !errors.As(err, &fileErr) || fileErr.File != malformedFile {
+				t.Fatalf("Expected an error naming %s, got %v", malformedFile, err)
+			}
+			// the malformed file is never replaced, whether or not the run carries on
+			if data, _ := os.ReadFile(malformedFile); string(data) != malformed {
+				t.Errorf("Expected %s left in place, got %s", malformedFile, data)
+			}
+
+			if !continueOnErr {
+				if len(summary.Files) != 0 {
+					t.Errorf("Expected nothing written, got %+v", summary.Files)
+				}
+				if _, err := os.Stat(validFile); err != nil {
+					t.Errorf("Expected %s left in place: %v", validFile, err)
+				}
+				return
+			}
+
+			var failed FileErrors
+			if !errors.As(err, &failed) || len(failed) != 1 {
+				t.Fatalf("Expected the failures reported together, got %v", err)
+			}
+			if !strings.HasPrefix(err.Error(), "1 files failed:\n- "+malformedFile+": ") {
+				t.Errorf("Expected a summary of the failed files, got %q", err.Error())
+			}
+			if len(summary.Files) != 1 || summary.Files[0].Statements != 1 {
+				t.Fatalf("Expected the valid file's statement written, got %+v", summary.Files)
+			}
+			if !reflect.DeepEqual(summary.Inputs, []string{validFile}) {
+				t.Errorf("Expected only the valid file as an input, got %v", summary.Inputs)
+			}
+			if _, err := os.Stat(validFile); !os.IsNotExist(err) {
+				t.Errorf("Expected %s replaced by the output", validFile)
+			}
+		})
+	}
+}
+
+func TestProcessFilesContinueOnErrorGroups(t *testing.T) {
+	tempDir := t.TempDir()
+	validFile := filepath.Join(tempDir, "valid.json")
+	malformedFile := filepath.Join(tempDir, "malformed.json")
+	if err := os.WriteFile(validFile, []byte(`{"Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(malformedFile, []byte(`not json`), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	userInput := inputs.UserInput{
+		Target:        tempDir,
+		IsDirectory:   true,
+		MaxFiles:      config.DefaultMaxFiles,
+		NoCombine:     true,
+		Force:         true,
+		ContinueOnErr: true,
+	}
+	summary, err := ProcessFiles(userInput, []string{malformedFile, validFile})
+	var failed FileErrors
+	if !errors.As(err, &failed) || len(failed) != 1 || failed[0].File != malformedFile {
+		t.Fatalf("Expected %s reported as failed, got %v", malformedFile, err)
+	}
+	if len(summary.Files) != 1 || summary.Files[0].Filename != validFile {
+		t.Errorf("Expected %s still processed, got %+v", validFile, summary.Files)
+	}
+}
+
+func TestProcessFilesDebugLogging(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "policy.json")
+	policy := `{"Version": "2012-10-17", "Statement": [
+		{"Sid": "DenyS3", "Effect": "Deny", "Action": "s3:*", "Resource": "*"},
+		{"Sid": "DenyEC2Instances", "Effect": "Deny", "Action": "ec2:RunInstances", "Resource": "*"}
+	]}`
+	if err := os.WriteFile(testFile, []byte(policy), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	var logs bytes.Buffer
+	previous, flags := slog.Default(), log.Flags()
+	slog.SetDefault(slog.New(logging.NewHandler(&logs, slog.LevelDebug, config.LogFormatJSON)))
+	defer func() {
+		slog.SetDefault(previous)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	}()
+
+	userInput := inputs.UserInput{Target: testFile, MaxFiles: config.DefaultMaxFiles, Check: true, Quiet: true}
+	if _, err := ProcessFiles(userInput, []string{testFile}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var records []map[string]interface{}
+	decoder := json.NewDecoder(&logs)
+	for decoder.More() {
+		var record map[string]interface{}
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("Expected JSON log records: %v", err)
+		}
+		if record["level"] != "DEBUG" {
+			t.Errorf("Expected only debug records, got %v", record)
+		}
+		records = append(records, record)
+	}
+
+	var placed []map[string]interface{}
+	read := false
+	for _, record := range records {
+		switch record["msg"] {
+		case "read policy file":
+			read = record["file"] == testFile && record["statements"] == 2.0 && record["version"] == config.SCPVersion
+		case "placed statement":
+			placed = append(placed, record)
+		}
+	}
+	if !read {
+		t.Errorf("Expected a record of the file read with its 2 statements, got %v", records)
+	}
+
+	// largest first, both into the first file, which grows by each statement and a separator
+	if len(placed) != 2 {
+		t.Fatalf("Expected a placement record per statement, got %v", placed)
+	}
+	for i, index := range []float64{1, 0} {
+		if placed[i]["source"] != "policy.json" || placed[i]["index"] != index || placed[i]["file"] != 1.0 {
+			t.Errorf("Expected Statement[%v] placed in file 1, got %v", index, placed[i])
+		}
+	}
+	if placed[1]["file_size"].(float64) != placed[0]["file_size"].(float64)+placed[1]["size"].(float64)+1 {
+		t.Errorf("Expected the file to grow by the second statement and a comma, got %v", placed)
+	}
+}
+
+func TestPrefixGroups(t *testing.T) {
+	files := []string{"dir/teamA-1.json", "dir/teamB-1.json", "dir/teamA-2.json.gz", "dir/shared.json", "dir/-leading.json"}
+	groups := prefixGroups(files, "-")
+
+	expected := []fileGroup{
+		{name: "teamA", files: []string{"dir/teamA-1.json", "dir/teamA-2.json.gz"}},
+		{name: "teamB", files: []string{"dir/teamB-1.json"}},
+		{name: "shared", files: []string{"dir/shared.json"}},
+		{name: "-leading", files: []string{"dir/-leading.json"}},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, groups)
+	}
+}
+
+func TestProcessFilesWarnThreshold(t *testing.T) {
+	// minified to 87 characters
+	policy := `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`
+
+	tests := []struct {
+		name          string
+		maxSize       int
+		threshold     int
+		fail          bool
+		check         bool
+		expectErr     bool
+		expectWarning bool
+	}{
+		{name: "under the threshold", maxSize: 100, threshold: 90},
+		{name: "over the threshold", maxSize: 90, threshold: 90, expectWarning: true},
+		{name: "never warns at 0", maxSize: 90},
+		{name: "fail on threshold", maxSize: 90, threshold: 90, fail: true, expectErr: true, expectWarning: true},
+		{name: "check fails on threshold", maxSize: 90, threshold: 90, fail: true, check: true, expectErr: true, expectWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(testFile, []byte(policy), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+			userInput := inputs.UserInput{
+				Target:        testFile,
+				MaxFiles:      config.DefaultMaxFiles,
+				MaxSize:       tt.maxSize,
+				WarnThreshold: tt.threshold,
+				FailThreshold: tt.fail,
+				Check:         tt.check,
+			}
+
+			logs := captureLogs(t, slog.LevelInfo)
+			_, err := ProcessFiles(userInput, []string{testFile})
+
+			if tt.expectErr != (err != nil) {
+				t.Errorf("Expected error %v, got %v", tt.expectErr, err)
+			}
+			if got := strings.Contains(logs.String(), "full=96% threshold=90%"); got != tt.expectWarning {
+				t.Errorf("Expected warning %v, got %q", tt.expectWarning, logs.String())
+			}
+
+			// a failing threshold still writes the file
+			data, readErr := os.ReadFile(testFile)
+			if readErr != nil {
+				t.Fatalf("Failed to read output: %v", readErr)
+			}
+			if minified := !strings.Contains(string(data), " "); minified == tt.check {
+				t.Errorf("Expected the file to be written only without --check, got %s", data)
+			}
+		})
+	}
+}
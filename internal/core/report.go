@@ -0,0 +1,42 @@
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+)
+
+var csvHeader = []string{"file", "statements", "size", "percent_of_limit"}
+
+// writeCSVReport writes a row per output file, for review in a spreadsheet
+func writeCSVReport(filename string, results []WriteResult, limit int) error {
+	err := writeFileAtomic(filename, func(w io.Writer) error {
+		return writeCSV(w, results, limit)
+	})
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", filename, err)
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, results []WriteResult, limit int) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, result := range results {
+		err := writer.Write([]string{
+			filepath.Base(result.Filename),
+			strconv.Itoa(result.Statements),
+			strconv.Itoa(result.Size),
+			strconv.Itoa(fullnessPercent(result.Size, limit)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
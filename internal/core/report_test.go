@@ -0,0 +1,33 @@
+package core
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	results := []WriteResult{
+		{Filename: "/tmp/corset1.json", Size: 4980, Statements: 12},
+		{Filename: "/tmp/guardrails, eu.json", Size: 2560, Statements: 5},
+	}
+
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, results, 5120); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV: %v", err)
+	}
+	expected := [][]string{
+		{"file", "statements", "size", "percent_of_limit"},
+		{"corset1.json", "12", "4980", "97"},
+		{"guardrails, eu.json", "5", "2560", "50"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected %v, got %v", expected, rows)
+	}
+}
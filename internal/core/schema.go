@@ -0,0 +1,134 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jakebark/corset/internal/inputs"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// schemaPrinter renders schema violations in English
+var schemaPrinter = message.NewPrinter(language.English)
+
+// loadSchema compiles the JSON Schema file given to --schema
+func loadSchema(filename string) (*jsonschema.Schema, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	document, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %s, it is not well-formed JSON: %w", filename, err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(filename, document); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", filename, err)
+	}
+	schema, err := compiler.Compile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", filename, err)
+	}
+	return schema, nil
+}
+
+// validateSchema checks the policy of each file against the --schema, returning nothing without one. The
+// policies are rebuilt from the statements already read, by their Source, with the file's own header.
+// Files without a header were never read, and are skipped.
+func validateSchema(userInput inputs.UserInput, files []string, fileHeaders map[string]Header, statements []Statement) ([]Violation, error) {
+	if userInput.Schema == "" {
+		return nil, nil
+	}
+	schema, err := loadSchema(userInput.Schema)
+	if err != nil {
+		return nil, err
+	}
+	bySource := make(map[string][]Statement)
+	for _, stmt := range statements {
+		bySource[stmt.Source] = append(bySource[stmt.Source], stmt)
+	}
+	var violations []Violation
+	for _, file := range files {
+		header, ok := fileHeaders[file]
+		if !ok {
+			continue
+		}
+		violations = append(violations, schemaViolations(schema, file, header, bySource[file])...)
+	}
+	return violations, nil
+}
+
+// schemaViolations validates a policy as it was parsed, with Statement always an array, giving a violation
+// for each keyword it fails. Those within a statement have its index, those elsewhere in the policy -1.
+func schemaViolations(schema *jsonschema.Schema, file string, header Header, statements []Statement) []Violation {
+	contents := make([]interface{}, len(statements))
+	for i, stmt := range statements {
+		contents[i] = stmt.Content
+	}
+	policy := map[string]interface{}{"Statement": contents}
+	if header.Version != "" {
+		policy["Version"] = header.Version
+	}
+	if header.Id != "" {
+		policy["Id"] = header.Id
+	}
+
+	// round trip through JSON, so the values are the types the schema library expects
+	data, _ := json.Marshal(policy)
+	document, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(schema.Validate(document), &validationErr) {
+		return nil
+	}
+
+	var violations []Violation
+	for _, cause := range schemaFailures(validationErr) {
+		violation := Violation{File: file, Index: -1, Message: cause.ErrorKind.LocalizedString(schemaPrinter)}
+		location := cause.InstanceLocation
+		if len(location) >= 2 && location[0] == "Statement" {
+			if i, err := strconv.Atoi(location[1]); err == nil && i < len(statements) {
+				violation.Index = statements[i].Index
+				location = location[2:]
+			}
+		}
+		if len(location) > 0 {
+			violation.Message = strings.Join(location, ".") + ": " + violation.Message
+		}
+		violations = append(violations, violation)
+	}
+	return violations
+}
+
+// schemaFailures returns the innermost errors of a validation, one for each keyword that failed
+func schemaFailures(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var failures []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		failures = append(failures, schemaFailures(cause)...)
+	}
+	return failures
+}
+
+// reportSchemaViolations logs each violation of the --schema, failing when there are any
+func reportSchemaViolations(userInput inputs.UserInput, violations []Violation) error {
+	for _, violation := range violations {
+		slog.Error(violation.String())
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("%d violations of the schema %s", len(violations), userInput.Schema)
+	}
+	return nil
+}
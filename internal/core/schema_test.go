@@ -0,0 +1,182 @@
+package core
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+// sidSchema requires every statement to have a Sid starting Org
+const sidSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["Version", "Statement"],
+	"properties": {
+		"Statement": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"required": ["Sid"],
+				"properties": {"Sid": {"type": "string", "pattern": "^Org"}}
+			}
+		}
+	}
+}`
+
+func writeSchemaTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	tempDir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+	return tempDir
+}
+
+func TestValidateSchema(t *testing.T) {
+	tempDir := writeSchemaTestFiles(t, map[string]string{
+		"schema.json": sidSchema,
+		"pass.json":   `{"Version": "2012-10-17", "Statement": [{"Sid": "OrgDenyS3", "Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`,
+		"fail.json": `{"Statement": [
+			{"Sid": "OrgDenyS3", "Effect": "Deny", "Action": "s3:*", "Resource": "*"},
+			{"Effect": "Deny", "Action": "ec2:*", "Resource": "*"},
+			{"Sid": "DenyIAM", "Effect": "Deny", "Action": "iam:*", "Resource": "*"}
+		]}`,
+	})
+	pass, fail := filepath.Join(tempDir, "pass.json"), filepath.Join(tempDir, "fail.json")
+	userInput := inputs.UserInput{Schema: filepath.Join(tempDir, "schema.json")}
+
+	statements, _, fileHeaders, _ := extractWithProgress([]string{pass, fail}, nil)
+	violations, err := validateSchema(userInput, []string{pass}, fileHeaders, statements)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("Expected %s to match the schema, got %v", pass, violations)
+	}
+
+	violations, err = validateSchema(userInput, []string{pass, fail}, fileHeaders, statements)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var got []string
+	for _, violation := range violations {
+		got = append(got, violation.String())
+	}
+	expected := []string{
+		fail + ": missing property 'Version'",
+		fail + ": Statement[1]: missing property 'Sid'",
+		fail + ": Statement[2]: Sid: 'DenyIAM' does not match pattern '^Org'",
+	}
+	// the order the library reports keywords in is not fixed
+	for _, want := range expected {
+		found := false
+		for _, message := range got {
+			found = found || message == want
+		}
+		if !found {
+			t.Errorf("Expected violation %q, got %q", want, got)
+		}
+	}
+	if len(got) != len(expected) {
+		t.Errorf("Expected %d violations, got %q", len(expected), got)
+	}
+
+	if _, err := validateSchema(inputs.UserInput{}, []string{fail}, fileHeaders, statements); err != nil {
+		t.Errorf("Expected no check without a schema, got %v", err)
+	}
+}
+
+func TestLoadSchemaInvalid(t *testing.T) {
+	tempDir := writeSchemaTestFiles(t, map[string]string{
+		"malformed.json": `{"type": `,
+		"invalid.json":   `{"type": "policy"}`,
+	})
+	for _, name := range []string{"malformed.json", "invalid.json", "missing.json"} {
+		if _, err := loadSchema(filepath.Join(tempDir, name)); err == nil {
+			t.Errorf("Expected an error loading %s", name)
+		}
+	}
+}
+
+func TestProcessFilesSchema(t *testing.T) {
+	logs := captureLogs(t, slog.LevelInfo)
+	for _, sid := range []string{"OrgDenyS3", "DenyS3"} {
+		t.Run(sid, func(t *testing.T) {
+			logs.Reset()
+			tempDir := writeSchemaTestFiles(t, map[string]string{
+				"policy.json": `{"Version": "2012-10-17", "Statement": [{"Sid": "` + sid + `", "Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`,
+			})
+			schemaFile := filepath.Join(t.TempDir(), "schema.json")
+			if err := os.WriteFile(schemaFile, []byte(sidSchema), 0644); err != nil {
+				t.Fatalf("Failed to write schema: %v", err)
+			}
+			policyFile := filepath.Join(tempDir, "policy.json")
+			userInput := inputs.UserInput{Target: policyFile, MaxFiles: config.DefaultMaxFiles, Schema: schemaFile, Quiet: true}
+
+			summary, err := ProcessFiles(userInput, []string{policyFile})
+			if sid == "OrgDenyS3" {
+				if err != nil || len(summary.Files) != 1 {
+					t.Fatalf("Expected the policy packed, got %+v, %v", summary.Files, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "1 violations of the schema") {
+				t.Fatalf("Expected a schema error, got %v", err)
+			}
+			if !strings.Contains(logs.String(), "Error: "+policyFile+": Statement[0]: Sid: 'DenyS3' does not match pattern '^Org'") {
+				t.Errorf("Expected the violation logged, got %q", logs.String())
+			}
+			if entries, _ := os.ReadDir(tempDir); len(entries) != 1 {
+				t.Errorf("Expected nothing written, got %v", entries)
+			}
+		})
+	}
+}
+
+func TestValidateFilesSchema(t *testing.T) {
+	captureLogs(t, slog.LevelInfo)
+	tempDir := writeSchemaTestFiles(t, map[string]string{
+		"schema.json": sidSchema,
+		"policy.json": `{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`,
+	})
+	policyFile := filepath.Join(tempDir, "policy.json")
+	userInput := inputs.UserInput{Schema: filepath.Join(tempDir, "schema.json"), Quiet: true}
+
+	violations, err := ValidateFiles(userInput, []string{policyFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []Violation{{File: policyFile, Index: 0, Message: "missing property 'Sid'"}}
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("Expected %v, got %v", expected, violations)
+	}
+}
+
+func TestWritePolicySchema(t *testing.T) {
+	captureLogs(t, slog.LevelInfo)
+	tempDir := writeSchemaTestFiles(t, map[string]string{"schema.json": sidSchema})
+	userInput := inputs.UserInput{
+		MaxFiles: config.DefaultMaxFiles,
+		MaxSize:  config.MaxPolicySize,
+		Schema:   filepath.Join(tempDir, "schema.json"),
+		Policy:   `{"Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`,
+	}
+
+	// the inline policy declares no Version, which is only defaulted after the schema check
+	var out strings.Builder
+	err := WritePolicy(userInput, &out)
+	if err == nil || !strings.Contains(err.Error(), "2 violations of the schema") {
+		t.Errorf("Expected the missing Version and Sid reported, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing written, got %s", out.String())
+	}
+}
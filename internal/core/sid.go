@@ -0,0 +1,59 @@
+package core
+
+import "fmt"
+
+// sidPrefix starts each generated Sid, followed by a number
+const sidPrefix = "Corset"
+
+// assignSids gives each statement without a Sid a generated one, re-sizing it so packing counts the Sid.
+// Generated Sids are unique across all statements and never reuse an existing Sid, so they are unique in every file.
+func assignSids(statements []Statement) []Statement {
+	existing := make(map[string]bool)
+	for _, stmt := range statements {
+		if sid, ok := stmt.Content["Sid"].(string); ok && sid != "" {
+			existing[sid] = true
+		}
+	}
+
+	assigned := make([]Statement, len(statements))
+	next := 1
+	for i, stmt := range statements {
+		if sid, ok := stmt.Content["Sid"].(string); ok && sid != "" {
+			assigned[i] = stmt
+			continue
+		}
+
+		sid := fmt.Sprintf("%s%d", sidPrefix, next)
+		for existing[sid] {
+			next++
+			sid = fmt.Sprintf("%s%d", sidPrefix, next)
+		}
+		next++
+
+		content := make(map[string]interface{}, len(stmt.Content)+1)
+		for k, v := range stmt.Content {
+			content[k] = v
+		}
+		content["Sid"] = sid
+		resized := newStatement(content)
+		resized.Source, resized.Index = stmt.Source, stmt.Index
+		assigned[i] = resized
+	}
+	return assigned
+}
+
+// stripSids removes the Sid from every statement, re-sizing those that had one
+func stripSids(statements []Statement) []Statement {
+	return rewriteStatements(statements, func(content map[string]interface{}) (map[string]interface{}, bool) {
+		if _, ok := content["Sid"]; !ok {
+			return content, false
+		}
+		stripped := make(map[string]interface{}, len(content))
+		for k, v := range content {
+			if k != "Sid" {
+				stripped[k] = v
+			}
+		}
+		return stripped, true
+	})
+}
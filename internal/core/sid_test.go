@@ -0,0 +1,77 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+func TestAssignSids(t *testing.T) {
+	statements := []Statement{
+		newStatement(map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}),
+		newStatement(map[string]interface{}{"Sid": "Corset2", "Effect": "Deny", "Action": "ec2:*", "Resource": "*"}),
+		newStatement(map[string]interface{}{"Sid": "KeepMe", "Effect": "Deny", "Action": "iam:*", "Resource": "*"}),
+		newStatement(map[string]interface{}{"Sid": "", "Effect": "Deny", "Action": "kms:*", "Resource": "*"}),
+		newStatement(map[string]interface{}{"Effect": "Deny", "Action": "sts:*", "Resource": "*"}),
+	}
+	statements[0].Source, statements[0].Index = "policy.json", 4
+	original := statements[0].Size
+
+	assigned := assignSids(statements)
+
+	expected := []string{"Corset1", "Corset2", "KeepMe", "Corset3", "Corset4"}
+	seen := make(map[string]bool)
+	for i, stmt := range assigned {
+		sid := stmt.Content["Sid"]
+		if sid != expected[i] {
+			t.Errorf("Statement %d: expected Sid %s, got %v", i, expected[i], sid)
+		}
+		if seen[sid.(string)] {
+			t.Errorf("Sid %s is not unique", sid)
+		}
+		seen[sid.(string)] = true
+	}
+
+	// existing Sids are untouched, generated ones are counted in the size
+	if assigned[2].Size != statements[2].Size {
+		t.Errorf("Expected statement with a Sid to keep its size")
+	}
+	if assigned[0].Size <= original {
+		t.Errorf("Expected generated Sid to be counted in the size, got %d from %d", assigned[0].Size, original)
+	}
+	if assigned[0].Source != "policy.json" || assigned[0].Index != 4 {
+		t.Errorf("Expected source to be kept, got %s[%d]", assigned[0].Source, assigned[0].Index)
+	}
+	if string(assigned[0].Raw[:15]) != `{"Sid":"Corset1` {
+		t.Errorf("Expected Sid to be written first, got %s", assigned[0].Raw)
+	}
+	if _, ok := statements[0].Content["Sid"]; ok {
+		t.Error("Expected input content to be left unmodified")
+	}
+}
+
+func TestStripSids(t *testing.T) {
+	statements := []Statement{
+		newStatement(map[string]interface{}{"Sid": "DenyS3", "Effect": "Deny", "Action": "s3:*", "Resource": "*"}),
+		newStatement(map[string]interface{}{"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}),
+	}
+
+	stripped := stripSids(statements)
+
+	if _, ok := stripped[0].Content["Sid"]; ok {
+		t.Error("Expected Sid to be removed")
+	}
+	if stripped[0].Size != statements[0].Size-len(`"Sid":"DenyS3",`) {
+		t.Errorf("Expected size to shrink by the Sid, got %d from %d", stripped[0].Size, statements[0].Size)
+	}
+	if stripped[1].Size != statements[1].Size {
+		t.Error("Expected statement without a Sid to keep its size")
+	}
+
+	output := string(writeJSON(inputs.UserInput{}, Header{Version: config.SCPVersion}, stripped))
+	if strings.Contains(output, "Sid") {
+		t.Errorf("Expected no Sid in output, got %s", output)
+	}
+}
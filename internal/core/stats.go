@@ -0,0 +1,103 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+// ReportStats prints an analysis of the input statements without writing any output
+func ReportStats(userInput inputs.UserInput, files []string) error {
+	stats, err := collectStats(userInput, files)
+	if err != nil {
+		return err
+	}
+	if stats.Statements == 0 {
+		return stats.Failed.or(ErrNoStatements)
+	}
+
+	fmt.Fprintf(os.Stderr, "Policy type: %s\n", strings.ToUpper(policyType(userInput)))
+	fmt.Fprintf(os.Stderr, "Files: %d\n", stats.Files)
+	fmt.Fprintf(os.Stderr, "Statements: %d\n", stats.Statements)
+	if stats.OnDiskSize > 0 {
+		fmt.Fprintf(os.Stderr, "Total size: %s characters (%s with whitespace)\n", formatCount(stats.TotalSize), formatCount(stats.OnDiskSize))
+	} else {
+		fmt.Fprintf(os.Stderr, "Total size: %s characters\n", formatCount(stats.TotalSize))
+	}
+	fmt.Fprintf(os.Stderr, "Largest statement: %s Statement[%d] (%s characters)\n",
+		filepath.Base(stats.Largest.Source), stats.Largest.Index, formatCount(stats.Largest.Size))
+	if len(stats.Top) > 0 {
+		fmt.Fprintf(os.Stderr, "Largest %d statements:\n", len(stats.Top))
+		for _, stmt := range stats.Top {
+			fmt.Fprintf(os.Stderr, "- %s\n", describeStatement(stmt))
+		}
+	}
+	if stats.FilesNeeded == 0 {
+		fmt.Fprintf(os.Stderr, "Files needed: does not fit within %d files\n", config.MaxAllowedFiles)
+	} else {
+		fmt.Fprintf(os.Stderr, "Files needed: %d (limit %d)\n", stats.FilesNeeded, config.MaxAllowedFiles)
+	}
+	return stats.Failed.err()
+}
+
+func collectStats(userInput inputs.UserInput, files []string) (Stats, error) {
+	allStatements, header, _, failed, err := readFiles(userInput, files)
+	if err != nil {
+		return Stats{}, err
+	}
+	stats := Stats{
+		Files:      len(files) - len(failed),
+		Statements: len(allStatements),
+		Failed:     failed,
+	}
+	if len(allStatements) == 0 {
+		return stats, nil
+	}
+	if userInput.Collapse {
+		allStatements = rewriteStatements(allStatements, collapseArrays)
+	}
+
+	userInput.Minimize = true
+	packedFiles, err := packAllStatements(userInput, header, allStatements)
+	if err == nil {
+		stats.FilesNeeded = len(packedFiles)
+	}
+
+	// sizes are minified, as AWS counts them, with the indented size only for reference
+	for _, stmt := range allStatements {
+		stats.TotalSize += stmt.Size
+		if stmt.Size > stats.Largest.Size {
+			stats.Largest = stmt
+		}
+		if userInput.Whitespace {
+			stats.OnDiskSize += indentedSize(stmt, indentString(userInput))
+		}
+	}
+	if userInput.Top > 0 {
+		stats.Top = largestStatements(allStatements, userInput.Top)
+	}
+	return stats, nil
+}
+
+// largestStatements returns up to count statements, biggest first, with equal sizes ordered by file and index
+func largestStatements(statements []Statement, count int) []Statement {
+	sorted := append([]Statement(nil), statements...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Size != sorted[j].Size {
+			return sorted[i].Size > sorted[j].Size
+		}
+		if sorted[i].Source != sorted[j].Source {
+			return sorted[i].Source < sorted[j].Source
+		}
+		return sorted[i].Index < sorted[j].Index
+	})
+	if len(sorted) > count {
+		sorted = sorted[:count]
+	}
+	return sorted
+}
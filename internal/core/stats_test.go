@@ -0,0 +1,116 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+func TestCollectStats(t *testing.T) {
+	tempDir := t.TempDir()
+	contents := []string{
+		`{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`,
+		`{"Version": "2012-10-17", "Statement": [
+			{"Effect": "Deny", "Action": ["ec2:RunInstances", "ec2:StartInstances"], "Resource": "*"},
+			{"Effect": "Deny", "Action": "iam:*", "Resource": "*"}
+		]}`,
+	}
+
+	var files []string
+	for i, content := range contents {
+		filename := filepath.Join(tempDir, "policy-"+string(rune('a'+i))+".json")
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file %d: %v", i, err)
+		}
+		files = append(files, filename)
+	}
+
+	stats, _ := collectStats(inputs.UserInput{MaxFiles: config.DefaultMaxFiles}, files)
+
+	if stats.Files != 2 {
+		t.Errorf("Expected 2 files, got %d", stats.Files)
+	}
+	if stats.Statements != 3 {
+		t.Errorf("Expected 3 statements, got %d", stats.Statements)
+	}
+	if stats.FilesNeeded != 1 {
+		t.Errorf("Expected 1 file needed, got %d", stats.FilesNeeded)
+	}
+	if stats.Largest.Source != files[1] || stats.Largest.Index != 0 {
+		t.Errorf("Expected largest statement to be %s[0], got %s[%d]", files[1], stats.Largest.Source, stats.Largest.Index)
+	}
+
+	expectedTotal := 0
+	statements, _ := extractAllStatements(files)
+	for _, stmt := range statements {
+		expectedTotal += stmt.Size
+	}
+	if stats.TotalSize != expectedTotal {
+		t.Errorf("Expected total size %d, got %d", expectedTotal, stats.TotalSize)
+	}
+}
+
+func TestCollectStatsTop(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "policy.json")
+	content := `{"Version": "2012-10-17", "Statement": [
+		{"Sid": "Small", "Effect": "Deny", "Action": "s3:*", "Resource": "*"},
+		{"Sid": "Large", "Effect": "Deny", "Action": ["ec2:RunInstances", "ec2:StartInstances", "ec2:StopInstances"], "Resource": "*"},
+		{"Sid": "Medium", "Effect": "Deny", "Action": ["iam:CreateUser", "iam:DeleteUser"], "Resource": "*"}
+	]}`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	statements, _ := extractAllStatements([]string{testFile})
+
+	stats, _ := collectStats(inputs.UserInput{MaxFiles: config.DefaultMaxFiles, Top: 2}, []string{testFile})
+
+	expected := []int{1, 2}
+	if len(stats.Top) != len(expected) {
+		t.Fatalf("Expected %d largest statements, got %d", len(expected), len(stats.Top))
+	}
+	for i, index := range expected {
+		if stats.Top[i].Index != index || stats.Top[i].Size != statements[index].Size {
+			t.Errorf("Expected largest %d to be Statement[%d] of %d characters, got Statement[%d] of %d",
+				i+1, index, statements[index].Size, stats.Top[i].Index, stats.Top[i].Size)
+		}
+	}
+	if stats.Top[0].Size <= stats.Top[1].Size {
+		t.Errorf("Expected statements biggest first, got sizes %d then %d", stats.Top[0].Size, stats.Top[1].Size)
+	}
+
+	want := "policy.json Statement[1] Large (" + formatCount(statements[1].Size) + " characters)"
+	if got := describeStatement(stats.Top[0]); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	if stats, _ := collectStats(inputs.UserInput{MaxFiles: config.DefaultMaxFiles}, []string{testFile}); stats.Top != nil {
+		t.Errorf("Expected no largest statements without --top, got %d", len(stats.Top))
+	}
+}
+
+func TestCollectStatsDoesNotFit(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "policy.json")
+	data := writeJSON(inputs.UserInput{}, Header{Version: config.SCPVersion}, largeStatements(t, 200))
+	if err := os.WriteFile(testFile, data, 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	stats, _ := collectStats(inputs.UserInput{MaxFiles: config.DefaultMaxFiles}, []string{testFile})
+
+	if stats.FilesNeeded != 0 {
+		t.Errorf("Expected statements not to fit, got %d files needed", stats.FilesNeeded)
+	}
+}
+
+func largeStatements(t *testing.T, count int) []Statement {
+	t.Helper()
+	var statements []Statement
+	for i, content := range createLargeStatements(count) {
+		content["Sid"] = "Statement" + string(rune('a'+i%26)) + string(rune('a'+i/26))
+		statements = append(statements, newStatement(content))
+	}
+	return statements
+}
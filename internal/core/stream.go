@@ -0,0 +1,162 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// errNotPolicy is returned when a streamed document is not a JSON policy object
+var errNotPolicy = errors.New("not a JSON policy object")
+
+// streamPolicyFile extracts the statements of a JSON policy file as it reads it, so a large file is never
+// held in memory whole. Like decodeStatements, a file that can't be read or parsed has no statements and an error.
+func streamPolicyFile(filename string) ([]Statement, Header, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, Header{}, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	if magic, _ := reader.Peek(len(gzipMagic)); isGzipFile(filename) || bytes.Equal(magic, gzipMagic) {
+		decompressed, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, Header{}, err
+		}
+		defer decompressed.Close()
+		reader = bufio.NewReader(decompressed)
+	}
+	// json.Decoder rejects a leading BOM
+	if bom, _ := reader.Peek(len(utf8BOM)); bytes.Equal(bom, utf8BOM) {
+		reader.Discard(len(utf8BOM))
+	}
+
+	return streamStatements(filename, reader)
+}
+
+// streamStatements decodes a policy's Statement array one element at a time. An error anywhere in
+// the document discards it entirely, matching json.Unmarshal. An empty document has no statements.
+func streamStatements(filename string, r io.Reader) ([]Statement, Header, error) {
+	decoder := json.NewDecoder(r)
+	if err := expectDelim(decoder, '{'); err == io.EOF {
+		return nil, Header{}, nil
+	} else if err != nil {
+		return nil, Header{}, err
+	}
+
+	var statements []Statement
+	var header Header
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, Header{}, err
+		}
+		key, _ := token.(string)
+
+		// keys match case-insensitively, as they do for json.Unmarshal
+		switch {
+		case strings.EqualFold(key, "Version"):
+			err = decoder.Decode(&header.Version)
+		case strings.EqualFold(key, "Id"):
+			err = decoder.Decode(&header.Id)
+		case strings.EqualFold(key, "Statement"):
+			// a repeated key replaces the earlier value
+			statements, err = decodeStatementElement(filename, decoder)
+		default:
+			var skip json.RawMessage
+			err = decoder.Decode(&skip)
+		}
+		if err != nil {
+			return nil, Header{}, err
+		}
+	}
+	if err := expectDelim(decoder, '}'); err != nil {
+		return nil, Header{}, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, Header{}, errNotPolicy
+	}
+	return statements, header, nil
+}
+
+// decodeStatementElement decodes Statement as either an array or a single statement object
+func decodeStatementElement(filename string, decoder *json.Decoder) ([]Statement, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		raw, err := decodeObjectRest(decoder)
+		if err != nil {
+			return nil, err
+		}
+		return appendRawStatement(nil, filename, 0, raw)
+	case json.Delim('['):
+		var statements []Statement
+		for i := 0; decoder.More(); i++ {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				return nil, err
+			}
+			if statements, err = appendRawStatement(statements, filename, i, raw); err != nil {
+				return nil, err
+			}
+		}
+		return statements, expectDelim(decoder, ']')
+	case nil:
+		return nil, nil
+	}
+	return nil, errNotPolicy
+}
+
+// decodeObjectRest reassembles an object whose opening brace the decoder has already read
+func decodeObjectRest(decoder *json.Decoder) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		writeJSONString(&buf, token.(string))
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), expectDelim(decoder, '}')
+}
+
+// appendRawStatement builds the statement at index, failing on anything that isn't an object as decodeStatements does
+func appendRawStatement(statements []Statement, filename string, index int, raw json.RawMessage) ([]Statement, error) {
+	content, err := decodeContent(raw)
+	if err != nil {
+		return nil, fmt.Errorf("Statement[%d] is not a statement object", index)
+	}
+	return append(statements, buildStatement(filename, index, content, raw)), nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return errNotPolicy
+	}
+	return nil
+}
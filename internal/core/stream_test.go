@@ -0,0 +1,117 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// largePolicy returns an indented policy of count statements, a few megabytes for large counts
+func largePolicy(count int) []byte {
+	statements := make([]map[string]interface{}, count)
+	for i := range statements {
+		statements[i] = map[string]interface{}{
+			"Sid":       fmt.Sprintf("Deny%d", i),
+			"Effect":    "Deny",
+			"Action":    []string{"s3:DeleteObject", "s3:PutObject", "s3:PutObject"},
+			"Resource":  fmt.Sprintf("arn:aws:s3:::bucket-%d/*", i),
+			"Condition": map[string]interface{}{"StringNotEquals": map[string]interface{}{"aws:PrincipalOrgID": "o-example"}},
+		}
+	}
+	data, _ := json.MarshalIndent(map[string]interface{}{"Version": "2012-10-17", "Id": "Large", "Statement": statements}, "", "  ")
+	return data
+}
+
+func TestStreamPolicyFileMatchesParse(t *testing.T) {
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	writer.Write(largePolicy(3))
+	writer.Close()
+
+	tests := []struct {
+		name      string
+		filename  string
+		data      []byte
+		expectErr bool // a Statement element that isn't an object fails the whole file
+	}{
+		{name: "large policy", filename: "large.json", data: largePolicy(20000)},
+		{name: "single statement object", filename: "single.json", data: []byte(`{"Version":"2012-10-17","Statement":{"Effect":"Deny","Action":"s3:*","Resource":"*"}}`)},
+		{name: "byte order mark", filename: "bom.json", data: append(append([]byte{}, utf8BOM...), largePolicy(2)...)},
+		{name: "gzip", filename: "policy.json.gz", data: gzipped.Bytes()},
+		{name: "lowercase keys", filename: "lower.json", data: []byte(`{"version":"2012-10-17","statement":[{"Effect":"Deny","Action":"s3:*","Resource":"*"}]}`)},
+		{name: "repeated statement key", filename: "repeat.json", data: []byte(`{"Statement":[{"Effect":"Deny","Action":"s3:*"}],"Statement":[{"Effect":"Deny","Action":"ec2:*"}]}`)},
+		{name: "non-object statement", filename: "mixed.json", data: []byte(`{"Statement":["s3:*",{"Effect":"Deny","Action":"ec2:*"}]}`), expectErr: true},
+		{name: "null element", filename: "nulls.json", data: []byte(`{"Statement":[null]}`), expectErr: true},
+		{name: "null statement", filename: "null.json", data: []byte(`{"Version":"2012-10-17","Statement":null}`)},
+		{name: "truncated", filename: "truncated.json", data: []byte(`{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"s3:*"},`)},
+		{name: "trailing data", filename: "trailing.json", data: []byte(`{"Statement":[{"Effect":"Deny","Action":"s3:*"}]} {}`)},
+		{name: "not an object", filename: "array.json", data: []byte(`[{"Effect":"Deny","Action":"s3:*"}]`)},
+		{name: "wrong version type", filename: "version.json", data: []byte(`{"Version":2012,"Statement":[{"Effect":"Deny","Action":"s3:*"}]}`)},
+		{name: "empty", filename: "empty.json", data: []byte{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), tt.filename)
+			if err := os.WriteFile(filename, tt.data, 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+			data, _ := ReadPolicyFile(filename)
+			expected, expectedHeader, expectedErr := decodeStatements(filename, data)
+
+			statements, header, err := streamPolicyFile(filename)
+			if tt.expectErr {
+				if err == nil || expectedErr == nil || err.Error() != expectedErr.Error() {
+					t.Errorf("Expected both to fail with the same error, got %v and %v", err, expectedErr)
+				}
+				if statements != nil {
+					t.Errorf("Expected no statements from a failed file, got %+v", statements)
+				}
+				return
+			}
+			if (err != nil) != (expectedErr != nil) {
+				t.Errorf("Expected error %v, got %v", expectedErr, err)
+			}
+			if header != expectedHeader {
+				t.Errorf("Expected header %+v, got %+v", expectedHeader, header)
+			}
+			if len(statements) != len(expected) {
+				t.Fatalf("Expected %d statements, got %d", len(expected), len(statements))
+			}
+			for i := range expected {
+				if !reflect.DeepEqual(statements[i], expected[i]) {
+					t.Fatalf("Statement %d: expected %+v, got %+v", i, expected[i], statements[i])
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkStreamPolicyFile and BenchmarkParsePolicyFile compare the memory of streaming a large file with reading it whole
+func BenchmarkStreamPolicyFile(b *testing.B) {
+	filename := filepath.Join(b.TempDir(), "large.json")
+	if err := os.WriteFile(filename, largePolicy(20000), 0644); err != nil {
+		b.Fatalf("Failed to write test file: %v", err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		streamPolicyFile(filename)
+	}
+}
+
+func BenchmarkParsePolicyFile(b *testing.B) {
+	filename := filepath.Join(b.TempDir(), "large.json")
+	if err := os.WriteFile(filename, largePolicy(20000), 0644); err != nil {
+		b.Fatalf("Failed to write test file: %v", err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data, _ := ReadPolicyFile(filename)
+		parseStatements(filename, data)
+	}
+}
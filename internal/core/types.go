@@ -1,17 +1,134 @@
 package core
 
+import (
+	"encoding/json"
+)
+
 type Policy struct {
-	Version   string                   `json:"Version"`
-	Statement []map[string]interface{} `json:"Statement"`
+	Version   string                   `json:"Version" yaml:"Version"`
+	Id        string                   `json:"Id,omitempty" yaml:"Id"`
+	Statement []map[string]interface{} `json:"Statement" yaml:"Statement"`
+}
+
+// rawPolicy is used to read statements as their original JSON
+type rawPolicy struct {
+	Version   string            `json:"Version"`
+	Id        string            `json:"Id"`
+	Statement []json.RawMessage `json:"Statement"`
+}
+
+// cfnTemplate is a CloudFormation template creating one Organizations policy per packed file
+type cfnTemplate struct {
+	AWSTemplateFormatVersion string                 `json:"AWSTemplateFormatVersion" yaml:"AWSTemplateFormatVersion"`
+	Description              string                 `json:"Description" yaml:"Description"`
+	Resources                map[string]cfnResource `json:"Resources" yaml:"Resources"`
+}
+
+type cfnResource struct {
+	Type       string              `json:"Type" yaml:"Type"`
+	Properties cfnPolicyProperties `json:"Properties" yaml:"Properties"`
+}
+
+type cfnPolicyProperties struct {
+	Name        string      `json:"Name" yaml:"Name"`
+	Description string      `json:"Description" yaml:"Description"`
+	Type        string      `json:"Type" yaml:"Type"`
+	Content     interface{} `json:"Content" yaml:"Content"` // the policy, as raw JSON or a YAML node
+}
+
+// Header holds the top-level policy fields carried from input to output
+type Header struct {
+	Version string
+	Id      string
 }
 
 type Statement struct {
 	Content map[string]interface{}
+	Raw     json.RawMessage // minified JSON as written to output
 	Size    int
+	Source  string // file the statement was read from
+	Index   int    // position within the source Statement array
+}
+
+// Violation describes a statement that fails validation
+type Violation struct {
+	File    string
+	Index   int
+	Message string
+}
+
+// Conflict is an Allow statement that a Deny may override
+type Conflict struct {
+	Allow Statement
+	Deny  Statement
+}
+
+// PartitionMismatch is a resource ARN that names a different partition than the one targeted
+type PartitionMismatch struct {
+	Statement Statement
+	ARN       string
+	Partition string // the partition the ARN names
+}
+
+// PackError lists the statements that could not be placed within MaxFiles
+type PackError struct {
+	MaxFiles int
+	Unplaced []Statement
 }
 
 type WriteResult struct {
-	Filename   string
-	Size       int
+	Filename   string `json:"filename"`
+	Size       int    `json:"size"` // uncompressed characters, as AWS counts them
+	Statements int    `json:"statements"`
+	OnDisk     int    `json:"on_disk,omitempty"`    // characters as written, set only for whitespace output
+	Compressed int    `json:"compressed,omitempty"` // bytes on disk, set only for gzip output
+	Resource   string `json:"resource,omitempty"`   // logical ID, set only for CloudFormation output
+	Unchanged  bool   `json:"unchanged,omitempty"`  // the file already held this content and was not rewritten
+}
+
+// PackedPolicy is one output policy, packed in memory rather than written to a file
+type PackedPolicy struct {
+	Data       []byte
+	Size       int // characters, as AWS counts them
 	Statements int
 }
+
+// ResultsSummary is the outcome of a run, printed by ReportResults as the summary or with --json
+type ResultsSummary struct {
+	Inputs            []string      `json:"inputs"` // the files whose statements were packed
+	Files             []WriteResult `json:"files"`
+	InputSize         int           `json:"input_size"`
+	OutputSize        int           `json:"output_size"`
+	StatementsRead    int           `json:"statements_read"`
+	StatementsWritten int           `json:"statements_written"`
+}
+
+// Manifest records which statements were written to each output file
+type Manifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	File       string              `json:"file"`
+	Size       int                 `json:"size"`
+	Statements []ManifestStatement `json:"statements"`
+}
+
+// ManifestStatement identifies a statement by its Sid, when it has one, and its position in the source file
+type ManifestStatement struct {
+	Sid    string `json:"sid,omitempty"`
+	Source string `json:"source"`
+	Index  int    `json:"index"`
+}
+
+// Stats summarizes the input statements
+type Stats struct {
+	Files       int
+	Statements  int
+	TotalSize   int
+	OnDiskSize  int // statements' characters indented, set only with --whitespace
+	Largest     Statement
+	Top         []Statement // the largest statements, biggest first, set only with --top
+	FilesNeeded int         // 0 when the statements cannot be packed
+	Failed      FileErrors  // files that could not be read, left out with --continue-on-error
+}
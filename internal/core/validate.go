@@ -0,0 +1,254 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+// ValidateFiles checks every statement in the files without writing any output
+func ValidateFiles(userInput inputs.UserInput, files []string) ([]Violation, error) {
+	allStatements, _, fileHeaders, failed, err := readFiles(userInput, files)
+	if err != nil {
+		return nil, err
+	}
+	if len(allStatements) == 0 {
+		return nil, failed.or(ErrNoStatements)
+	}
+
+	check, err := statementCheck(userInput, structuralCheck(userInput))
+	if err != nil {
+		return nil, err
+	}
+	violations := validateStatements(allStatements, check)
+	schemaViolations, err := validateSchema(userInput, failed.exclude(files), fileHeaders, allStatements)
+	if err != nil {
+		return nil, err
+	}
+	violations = append(violations, schemaViolations...)
+	for _, violation := range violations {
+		slog.Error(violation.String())
+	}
+	reportConflicts(allStatements)
+	if userInput.Partition != "" {
+		reportPartitionMismatches(allStatements, userInput.Partition)
+	}
+	if userInput.Lint {
+		reportPermissive(allStatements)
+	}
+	if len(violations) == 0 && !userInput.Quiet {
+		fmt.Fprintf(os.Stderr, "%d %s statements are valid\n", len(allStatements), strings.ToUpper(policyType(userInput)))
+	}
+	return violations, failed.err()
+}
+
+// structuralCheck returns the full validation for the policy type
+func structuralCheck(userInput inputs.UserInput) func(map[string]interface{}) []string {
+	switch userInput.PolicyType {
+	case config.PolicyTypeRCP:
+		return validateRCPStatement
+	case config.PolicyTypeIAM:
+		return validateStatement
+	}
+	return validateSCPStatement
+}
+
+// basicCheck returns the checks run before every pack, for statements AWS rejects whatever the flags
+func basicCheck(userInput inputs.UserInput) func(map[string]interface{}) []string {
+	if policyType(userInput) != config.PolicyTypeSCP {
+		return validateEffect
+	}
+	return func(content map[string]interface{}) []string {
+		return append(validateEffect(content), validatePrincipalUnsupported(content)...)
+	}
+}
+
+// policyType returns the user's policy type, SCP unless set
+func policyType(userInput inputs.UserInput) string {
+	if userInput.PolicyType == "" {
+		return config.PolicyTypeSCP
+	}
+	return userInput.PolicyType
+}
+
+// statementCheck adds action linting to a check when --lint-actions is set
+func statementCheck(userInput inputs.UserInput, check func(map[string]interface{}) []string) (func(map[string]interface{}) []string, error) {
+	if !userInput.LintActions {
+		return check, nil
+	}
+	catalog, err := loadActionCatalog(userInput.ActionsFile)
+	if err != nil {
+		return nil, err
+	}
+	lint := lintActions(catalog)
+	return func(content map[string]interface{}) []string {
+		return append(check(content), lint(content)...)
+	}, nil
+}
+
+func (v Violation) String() string {
+	if v.Index < 0 {
+		return fmt.Sprintf("%s: %s", v.File, v.Message)
+	}
+	return fmt.Sprintf("%s: Statement[%d]: %s", v.File, v.Index, v.Message)
+}
+
+// validateStatements runs a check against every statement, collecting violations
+func validateStatements(statements []Statement, check func(map[string]interface{}) []string) []Violation {
+	var violations []Violation
+	for _, stmt := range statements {
+		for _, message := range check(stmt.Content) {
+			violations = append(violations, Violation{
+				File:    stmt.Source,
+				Index:   stmt.Index,
+				Message: message,
+			})
+		}
+	}
+	return violations
+}
+
+// validateStatement checks a statement is structurally valid, whatever the policy type
+func validateStatement(content map[string]interface{}) []string {
+	messages := validateEffect(content)
+
+	messages = append(messages, validateElementPair(content, "Action", "NotAction")...)
+	messages = append(messages, validateElementPair(content, "Resource", "NotResource")...)
+	messages = append(messages, validateARNs(content, "Resource")...)
+	messages = append(messages, validateARNs(content, "NotResource")...)
+
+	if condition, ok := content["Condition"]; ok {
+		operators, isObject := condition.(map[string]interface{})
+		if !isObject {
+			messages = append(messages, "Condition must be an object")
+		}
+		var names []string
+		for operator := range operators {
+			names = append(names, operator)
+		}
+		sort.Strings(names)
+		for _, operator := range names {
+			if _, isObject := operators[operator].(map[string]interface{}); !isObject {
+				messages = append(messages, fmt.Sprintf("Condition operator %s must be an object", operator))
+			}
+		}
+	}
+
+	return messages
+}
+
+// validateSCPStatement checks a statement is valid for a service control policy, which applies to
+// principals by where it is attached and so does not support Principal or NotPrincipal
+func validateSCPStatement(content map[string]interface{}) []string {
+	return append(validateStatement(content), validatePrincipalUnsupported(content)...)
+}
+
+func validatePrincipalUnsupported(content map[string]interface{}) []string {
+	var messages []string
+	for _, element := range []string{"Principal", "NotPrincipal"} {
+		if _, ok := content[element]; ok {
+			messages = append(messages, fmt.Sprintf("%s is not supported in a service control policy", element))
+		}
+	}
+	return messages
+}
+
+// validateRCPStatement checks a statement is valid for a resource control policy. RCPs only deny,
+// must apply to every principal, and do not support NotAction or NotPrincipal.
+func validateRCPStatement(content map[string]interface{}) []string {
+	messages := validateStatement(content)
+
+	if effect, ok := content["Effect"].(string); ok && effect == "Allow" {
+		messages = append(messages, `Effect must be "Deny" in a resource control policy`)
+	}
+	principal := content["Principal"]
+	if single, ok := singleString(principal); ok {
+		principal = single
+	}
+	if principal != "*" {
+		messages = append(messages, fmt.Sprintf(`Principal must be "*" in a resource control policy, got %s`, describe(principal)))
+	}
+	for _, element := range []string{"NotAction", "NotPrincipal"} {
+		if _, ok := content[element]; ok {
+			messages = append(messages, fmt.Sprintf("%s is not supported in a resource control policy", element))
+		}
+	}
+	return messages
+}
+
+// validateEffect checks Effect is exactly "Allow" or "Deny", AWS rejects anything else
+func validateEffect(content map[string]interface{}) []string {
+	if effect, ok := content["Effect"].(string); ok && (effect == "Allow" || effect == "Deny") {
+		return nil
+	}
+	return []string{fmt.Sprintf(`Effect must be "Allow" or "Deny", got %s`, describe(content["Effect"]))}
+}
+
+// validateElementPair checks exactly one of an element or its Not form is present and well formed
+func validateElementPair(content map[string]interface{}, element, notElement string) []string {
+	value, hasElement := content[element]
+	notValue, hasNotElement := content[notElement]
+
+	switch {
+	case hasElement && hasNotElement:
+		return []string{fmt.Sprintf("only one of %s or %s is allowed", element, notElement)}
+	case hasElement:
+		if !isStringOrStringArray(value) {
+			return []string{fmt.Sprintf("%s must be a string or array of strings", element)}
+		}
+	case hasNotElement:
+		if !isStringOrStringArray(notValue) {
+			return []string{fmt.Sprintf("%s must be a string or array of strings", notElement)}
+		}
+	default:
+		return []string{fmt.Sprintf("one of %s or %s is required", element, notElement)}
+	}
+	return nil
+}
+
+// validateARNs checks each entry of a resource element is * or an ARN, arn:partition:service:region:account:resource.
+// Region and account can be empty, as for S3, and any field can use wildcards.
+func validateARNs(content map[string]interface{}, element string) []string {
+	var messages []string
+	for _, resource := range actionList(content[element]) {
+		if resource != "*" && !isARN(resource) {
+			messages = append(messages, fmt.Sprintf("%s %q is not a valid ARN, expected arn:partition:service:region:account:resource", element, resource))
+		}
+	}
+	return messages
+}
+
+func isARN(resource string) bool {
+	fields := strings.SplitN(resource, ":", 6)
+	return len(fields) == 6 && fields[0] == "arn" && fields[1] != "" && fields[2] != "" && fields[5] != ""
+}
+
+func isStringOrStringArray(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return true
+	case []interface{}:
+		if len(v) == 0 {
+			return false
+		}
+		for _, item := range v {
+			if _, ok := item.(string); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func describe(value interface{}) string {
+	if value == nil {
+		return "nothing"
+	}
+	return fmt.Sprintf("%q", fmt.Sprint(value))
+}
@@ -0,0 +1,351 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+func TestValidateStatement(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  map[string]interface{}
+		expected []string // substrings expected in the violations, in order
+	}{
+		{
+			name: "valid statement",
+			content: map[string]interface{}{
+				"Effect":   "Deny",
+				"Action":   []interface{}{"s3:DeleteBucket", "s3:PutBucketPolicy"},
+				"Resource": "*",
+				"Condition": map[string]interface{}{
+					"StringNotEquals": map[string]interface{}{"aws:RequestedRegion": "eu-west-1"},
+				},
+			},
+			expected: nil,
+		},
+		{
+			name: "valid NotAction and NotResource",
+			content: map[string]interface{}{
+				"Effect":      "Deny",
+				"NotAction":   "iam:*",
+				"NotResource": []interface{}{"arn:aws:iam::*:role/admin"},
+			},
+			expected: nil,
+		},
+		{
+			name: "invalid effect",
+			content: map[string]interface{}{
+				"Effect":   "allow",
+				"Action":   "s3:*",
+				"Resource": "*",
+			},
+			expected: []string{"Effect must be"},
+		},
+		{
+			name: "missing action and resource",
+			content: map[string]interface{}{
+				"Effect": "Deny",
+			},
+			expected: []string{"one of Action or NotAction is required", "one of Resource or NotResource is required"},
+		},
+		{
+			name: "both action and not action",
+			content: map[string]interface{}{
+				"Effect":    "Deny",
+				"Action":    "s3:*",
+				"NotAction": "iam:*",
+				"Resource":  "*",
+			},
+			expected: []string{"only one of Action or NotAction is allowed"},
+		},
+		{
+			name: "non-string action",
+			content: map[string]interface{}{
+				"Effect":   "Deny",
+				"Action":   []interface{}{"s3:*", 42.0},
+				"Resource": "*",
+			},
+			expected: []string{"Action must be a string or array of strings"},
+		},
+		{
+			name: "valid ARNs",
+			content: map[string]interface{}{
+				"Effect":   "Deny",
+				"Action":   "s3:*",
+				"Resource": []interface{}{"arn:aws:s3:::bucket/*", "arn:aws:iam::111122223333:role/admin", "arn:aws:ec2:eu-west-1:111122223333:instance/i-0abc"},
+			},
+			expected: nil,
+		},
+		{
+			name: "wildcard ARNs",
+			content: map[string]interface{}{
+				"Effect":      "Deny",
+				"Action":      "iam:*",
+				"NotResource": []interface{}{"arn:*:iam::*:role/*", "arn:aws:*:*:*:*"},
+			},
+			expected: nil,
+		},
+		{
+			name: "malformed ARNs",
+			content: map[string]interface{}{
+				"Effect":   "Deny",
+				"Action":   "s3:*",
+				"Resource": []interface{}{"arn:aws:s3:bucket", "bucket/*", "arn:aws:iam::111122223333:"},
+			},
+			expected: []string{`Resource "arn:aws:s3:bucket" is not a valid ARN`, `Resource "bucket/*" is not a valid ARN`, `Resource "arn:aws:iam::111122223333:" is not a valid ARN`},
+		},
+		{
+			name: "condition not an object of objects",
+			content: map[string]interface{}{
+				"Effect":    "Deny",
+				"Action":    "s3:*",
+				"Resource":  "*",
+				"Condition": map[string]interface{}{"Bool": "true"},
+			},
+			expected: []string{"Condition operator Bool must be an object"},
+		},
+		{
+			name: "condition not an object",
+			content: map[string]interface{}{
+				"Effect":    "Deny",
+				"Action":    "s3:*",
+				"Resource":  "*",
+				"Condition": []interface{}{},
+			},
+			expected: []string{"Condition must be an object"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validateStatement(tt.content)
+
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %d violations, got %d: %v", len(tt.expected), len(result), result)
+			}
+			for i, expected := range tt.expected {
+				if !strings.Contains(result[i], expected) {
+					t.Errorf("Expected violation %d to contain %q, got %q", i, expected, result[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateStatements(t *testing.T) {
+	statements := []Statement{
+		{Content: map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}, Source: "a.json", Index: 0},
+		{Content: map[string]interface{}{"Effect": "Deny"}, Source: "a.json", Index: 1},
+		{Content: map[string]interface{}{"Effect": "Alow", "Action": "s3:*", "Resource": "*"}, Source: "b.json", Index: 0},
+	}
+
+	violations := validateStatements(statements, validateStatement)
+
+	if len(violations) != 3 {
+		t.Fatalf("Expected 3 violations, got %d: %v", len(violations), violations)
+	}
+
+	expected := []string{"a.json: Statement[1]", "a.json: Statement[1]", "b.json: Statement[0]"}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(violations[i].String(), prefix) {
+			t.Errorf("Expected violation %d to start with %q, got %q", i, prefix, violations[i].String())
+		}
+	}
+}
+
+func TestValidateRCPStatement(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  map[string]interface{}
+		expected []string // substrings expected in the violations, in order
+	}{
+		{
+			name: "valid statement",
+			content: map[string]interface{}{
+				"Effect":    "Deny",
+				"Principal": "*",
+				"Action":    "s3:*",
+				"Resource":  "*",
+			},
+			expected: nil,
+		},
+		{
+			name: "single-element principal array",
+			content: map[string]interface{}{
+				"Effect":    "Deny",
+				"Principal": []interface{}{"*"},
+				"Action":    "s3:*",
+				"Resource":  "*",
+			},
+			expected: nil,
+		},
+		{
+			name: "allow",
+			content: map[string]interface{}{
+				"Effect":    "Allow",
+				"Principal": "*",
+				"Action":    "s3:*",
+				"Resource":  "*",
+			},
+			expected: []string{`Effect must be "Deny"`},
+		},
+		{
+			name: "missing principal",
+			content: map[string]interface{}{
+				"Effect":   "Deny",
+				"Action":   "s3:*",
+				"Resource": "*",
+			},
+			expected: []string{`Principal must be "*"`},
+		},
+		{
+			name: "specific principal",
+			content: map[string]interface{}{
+				"Effect":    "Deny",
+				"Principal": map[string]interface{}{"AWS": "arn:aws:iam::111122223333:root"},
+				"Action":    "s3:*",
+				"Resource":  "*",
+			},
+			expected: []string{`Principal must be "*"`},
+		},
+		{
+			name: "not action and not principal",
+			content: map[string]interface{}{
+				"Effect":       "Deny",
+				"Principal":    "*",
+				"NotPrincipal": map[string]interface{}{"AWS": "arn:aws:iam::111122223333:root"},
+				"NotAction":    "s3:GetObject",
+				"Resource":     "*",
+			},
+			expected: []string{"NotAction is not supported", "NotPrincipal is not supported"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validateRCPStatement(tt.content)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %d violations, got %v", len(tt.expected), result)
+			}
+			for i, expected := range tt.expected {
+				if !strings.Contains(result[i], expected) {
+					t.Errorf("Expected violation %d to contain %q, got %q", i, expected, result[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBasicCheckPrincipal(t *testing.T) {
+	tests := []struct {
+		name       string
+		policyType string
+		content    map[string]interface{}
+		expected   []string
+	}{
+		{
+			name:       "principal in an scp",
+			policyType: config.PolicyTypeSCP,
+			content:    map[string]interface{}{"Effect": "Deny", "Principal": "*", "Action": "s3:*", "Resource": "*"},
+			expected:   []string{"Principal is not supported in a service control policy"},
+		},
+		{
+			name:       "not principal in an scp",
+			policyType: config.PolicyTypeSCP,
+			content:    map[string]interface{}{"Effect": "Deny", "NotPrincipal": map[string]interface{}{"AWS": "*"}, "Action": "s3:*", "Resource": "*"},
+			expected:   []string{"NotPrincipal is not supported in a service control policy"},
+		},
+		{
+			name:     "principal with the default type",
+			content:  map[string]interface{}{"Effect": "Deny", "Principal": "*", "Action": "s3:*", "Resource": "*"},
+			expected: []string{"Principal is not supported in a service control policy"},
+		},
+		{
+			name:       "principal in an rcp",
+			policyType: config.PolicyTypeRCP,
+			content:    map[string]interface{}{"Effect": "Deny", "Principal": "*", "Action": "s3:*", "Resource": "*"},
+		},
+		{
+			name:       "principal in an iam policy",
+			policyType: config.PolicyTypeIAM,
+			content:    map[string]interface{}{"Effect": "Allow", "Principal": "*", "Action": "s3:*", "Resource": "*"},
+		},
+		{
+			name:       "scp without a principal",
+			policyType: config.PolicyTypeSCP,
+			content:    map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": "*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := basicCheck(inputs.UserInput{PolicyType: tt.policyType})(tt.content)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestValidateEffect(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   map[string]interface{}
+		expectErr bool
+	}{
+		{name: "Allow", content: map[string]interface{}{"Effect": "Allow"}, expectErr: false},
+		{name: "Deny", content: map[string]interface{}{"Effect": "Deny"}, expectErr: false},
+		{name: "lowercase allow", content: map[string]interface{}{"Effect": "allow"}, expectErr: true},
+		{name: "typo", content: map[string]interface{}{"Effect": "Alow"}, expectErr: true},
+		{name: "missing effect", content: map[string]interface{}{"Action": "s3:*"}, expectErr: true},
+		{name: "non-string effect", content: map[string]interface{}{"Effect": true}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validateEffect(tt.content)
+			if tt.expectErr && len(result) == 0 {
+				t.Error("Expected a violation, got none")
+			}
+			if !tt.expectErr && len(result) > 0 {
+				t.Errorf("Expected no violation, got %v", result)
+			}
+		})
+	}
+}
+
+func TestValidateFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "policy.json")
+	original := `{"Version": "2012-10-17", "Statement": [
+		{"Effect": "Deny", "Action": "s3:*", "Resource": "*"},
+		{"Effect": "Deny", "Resource": "*"}
+	]}`
+	if err := os.WriteFile(testFile, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	violations, err := ValidateFiles(inputs.UserInput{}, []string{testFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(violations) != 1 || violations[0].Index != 1 {
+		t.Errorf("Expected one violation for Statement[1], got %v", violations)
+	}
+
+	// validate never writes
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	if string(data) != original {
+		t.Error("Expected validate to leave the file untouched")
+	}
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// verifyOutputs re-reads the written files for --verify, checking they hold exactly the packed statements
+func verifyOutputs(results []WriteResult, packedFiles [][]Statement) error {
+	var expected, found []Statement
+	for _, statements := range packedFiles {
+		expected = append(expected, statements...)
+	}
+	for _, result := range results {
+		statements, _, err := readStatements(result.Filename)
+		if err != nil {
+			return fmt.Errorf("verify failed, %s could not be read back: %w", result.Filename, err)
+		}
+		found = append(found, statements...)
+	}
+	return verifyStatements("the outputs", expected, found)
+}
+
+// verifyPacked checks, for --verify, that packing placed each of the rewritten statements once and added none
+func verifyPacked(statements []Statement, packedFiles [][]Statement) error {
+	var packed []Statement
+	for _, file := range packedFiles {
+		packed = append(packed, file...)
+	}
+	return verifyStatements("the packed files", statements, packed)
+}
+
+// verifyStatements checks found holds each statement of expected exactly as many times, listing
+// any that are missing and any that were never expected. where names what found was read from.
+func verifyStatements(where string, expected, found []Statement) error {
+	counts := make(map[string]int)
+	for _, stmt := range expected {
+		counts[contentKey(stmt)]++
+	}
+	var unexpected []Statement
+	for _, stmt := range found {
+		key := contentKey(stmt)
+		if counts[key] == 0 {
+			unexpected = append(unexpected, stmt)
+			continue
+		}
+		counts[key]--
+	}
+	var missing []Statement
+	for _, stmt := range expected {
+		if key := contentKey(stmt); counts[key] > 0 {
+			missing = append(missing, stmt)
+			counts[key]--
+		}
+	}
+	if len(missing) == 0 && len(unexpected) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "verify failed, %s are missing %d statements and hold %d that were not packed:", where, len(missing), len(unexpected))
+	for _, stmt := range missing {
+		fmt.Fprintf(&b, "\n- missing %s", describeStatement(stmt))
+	}
+	for _, stmt := range unexpected {
+		fmt.Fprintf(&b, "\n- unexpected %s", describeStatement(stmt))
+	}
+	return errors.New(b.String())
+}
+
+// contentKey identifies a statement by its exact content, whatever its key order or formatting
+func contentKey(stmt Statement) string {
+	key, _ := json.Marshal(stmt.Content) // map keys are marshaled in sorted order
+	return string(key)
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+func TestProcessFilesVerify(t *testing.T) {
+	tests := []struct {
+		name      string
+		userInput inputs.UserInput
+	}{
+		{name: "default", userInput: inputs.UserInput{}},
+		{name: "whitespace", userInput: inputs.UserInput{Whitespace: true}},
+		{name: "gzip", userInput: inputs.UserInput{Gzip: true}},
+		{name: "minimize best fit", userInput: inputs.UserInput{Minimize: true, Strategy: config.StrategyBestFit}},
+		{name: "rewrites", userInput: inputs.UserInput{Dedupe: true, Merge: true, Optimize: true, Sid: true, SortActions: true}},
+		{name: "explode", userInput: inputs.UserInput{Explode: true, PolicyType: config.PolicyTypeIAM, MaxSize: config.MaxIAMPolicySize}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			var files []string
+			for _, name := range []string{"policy1.json", "policy2.json", "policy3.json"} {
+				data, err := os.ReadFile(filepath.Join("..", "..", "testdata", name))
+				if err != nil {
+					t.Fatalf("Failed to read test data: %v", err)
+				}
+				file := filepath.Join(tempDir, name)
+				if err := os.WriteFile(file, data, 0644); err != nil {
+					t.Fatalf("Failed to write test file: %v", err)
+				}
+				files = append(files, file)
+			}
+
+			userInput := tt.userInput
+			userInput.Target, userInput.IsDirectory, userInput.Verify, userInput.Quiet = tempDir, true, true, true
+			userInput.MaxFiles = config.DefaultMaxFiles
+			if userInput.MaxSize == 0 {
+				userInput.MaxSize = config.MaxPolicySize
+			}
+			summary, err := ProcessFiles(userInput, files)
+			if err != nil {
+				t.Fatalf("Expected the outputs to verify, got %v", err)
+			}
+			if len(summary.Files) == 0 {
+				t.Fatal("Expected output files")
+			}
+		})
+	}
+}
+
+func TestVerifyOutputsCorrupted(t *testing.T) {
+	tempDir := t.TempDir()
+	statements := []Statement{
+		newStatement(map[string]interface{}{"Sid": "DenyS3", "Effect": "Deny", "Action": "s3:*", "Resource": "*"}),
+		newStatement(map[string]interface{}{"Sid": "DenyEC2", "Effect": "Deny", "Action": "ec2:*", "Resource": "*"}),
+	}
+	for i := range statements {
+		statements[i].Source, statements[i].Index = "policy.json", i
+	}
+	userInput := inputs.UserInput{MaxFiles: config.DefaultMaxFiles}
+	header := Header{Version: config.SCPVersion}
+	packedFiles := [][]Statement{statements}
+
+	filename := filepath.Join(tempDir, "corset.json")
+	result, err := writeOutputFile(userInput, header, filename, statements, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	results := []WriteResult{result}
+	if err := verifyOutputs(results, packedFiles); err != nil {
+		t.Fatalf("Expected the written file to verify, got %v", err)
+	}
+
+	// drop a statement and change another, as a faulty writer might
+	corrupted := `{"Version":"2012-10-17","Statement":[{"Sid":"DenyS3","Effect":"Deny","Action":"s3:*","Resource":"arn:aws:s3:::bucket"}]}`
+	if err := os.WriteFile(filename, []byte(corrupted), 0644); err != nil {
+		t.Fatalf("Failed to corrupt the output: %v", err)
+	}
+	err = verifyOutputs(results, packedFiles)
+	if err == nil {
+		t.Fatal("Expected verify to catch the corrupted output")
+	}
+	for _, want := range []string{
+		"the outputs are missing 2 statements and hold 1 that were not packed",
+		"- missing policy.json Statement[0] DenyS3",
+		"- missing policy.json Statement[1] DenyEC2",
+		"- unexpected corset.json Statement[0] DenyS3",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in the error, got:\n%v", want, err)
+		}
+	}
+
+	if err := os.Remove(filename); err != nil {
+		t.Fatalf("Failed to remove the output: %v", err)
+	}
+	if err := verifyOutputs(results, packedFiles); err == nil || !strings.Contains(err.Error(), "could not be read back") {
+		t.Errorf("Expected verify to fail on a missing output, got %v", err)
+	}
+}
+
+func TestVerifyPackedFaultyPacking(t *testing.T) {
+	var statements []Statement
+	for _, sid := range []string{"DenyS3", "DenyEC2", "DenyIAM"} {
+		stmt := newStatement(map[string]interface{}{"Sid": sid, "Effect": "Deny", "Action": "*", "Resource": "*"})
+		stmt.Source = "policy.json"
+		statements = append(statements, stmt)
+	}
+	userInput := inputs.UserInput{MaxFiles: config.DefaultMaxFiles, MaxSize: config.MaxPolicySize}
+	packedFiles, err := packAllStatements(userInput, Header{Version: config.SCPVersion}, append([]Statement(nil), statements...))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := verifyPacked(statements, packedFiles); err != nil {
+		t.Fatalf("Expected the packing to verify, got %v", err)
+	}
+
+	// a packer that loses one statement and places another twice still writes files that read back as packed
+	faulty := [][]Statement{append([]Statement(nil), packedFiles[0][:len(packedFiles[0])-1]...)}
+	lost := packedFiles[0][len(packedFiles[0])-1]
+	faulty = append(faulty, []Statement{faulty[0][0]})
+
+	tempDir := t.TempDir()
+	var results []WriteResult
+	for i, file := range faulty {
+		result, err := writeOutputFile(userInput, Header{Version: config.SCPVersion}, filepath.Join(tempDir, fmt.Sprintf("corset-%d.json", i+1)), file, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		results = append(results, result)
+	}
+	if err := verifyOutputs(results, faulty); err != nil {
+		t.Fatalf("Expected the files to match what was packed, got %v", err)
+	}
+
+	err = verifyPacked(statements, faulty)
+	if err == nil {
+		t.Fatal("Expected verify to catch the faulty packing")
+	}
+	for _, want := range []string{
+		"the packed files are missing 1 statements and hold 1 that were not packed",
+		"- missing " + describeStatement(lost),
+		"- unexpected " + describeStatement(faulty[0][0]),
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in the error, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestVerifyStatementsCounts(t *testing.T) {
+	deny := newStatement(map[string]interface{}{"Effect": "Deny", "Action": "s3:*", "Resource": "*"})
+	reordered := newStatement(map[string]interface{}{"Resource": "*", "Action": "s3:*", "Effect": "Deny"})
+
+	if err := verifyStatements("the outputs", []Statement{deny, deny}, []Statement{reordered, deny}); err != nil {
+		t.Errorf("Expected statements matching whatever their key order, got %v", err)
+	}
+	// a statement packed once where it was read twice is missing
+	err := verifyStatements("the packed files", []Statement{deny, deny}, []Statement{deny})
+	if err == nil || !strings.Contains(err.Error(), "the packed files are missing 1 statements and hold 0") {
+		t.Errorf("Expected a missing duplicate to fail, got %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+// watchDebounce is how long to wait after the last change before reprocessing
+var watchDebounce = 200 * time.Millisecond
+
+type watcher struct {
+	userInput inputs.UserInput
+	fsWatcher *fsnotify.Watcher
+	dirs      []string          // directory targets, changes anywhere beneath are watched
+	files     map[string]bool   // file targets
+	snapshot  map[string][]byte // policy contents after the last run by absolute path, to skip corset's own writes
+	written   map[string][]byte // outputs of the last run by absolute path, as they were written
+}
+
+// WatchFiles processes the targets, then reprocesses them whenever a policy file changes until stop is closed
+func WatchFiles(userInput inputs.UserInput, stop <-chan struct{}) error {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fsWatcher.Close()
+
+	w := &watcher{
+		userInput: userInput,
+		fsWatcher: fsWatcher,
+		files:     map[string]bool{},
+	}
+	if err := w.addTargets(); err != nil {
+		return err
+	}
+
+	w.run()
+	if !userInput.Quiet {
+		fmt.Fprintln(os.Stderr, "Watching for changes, press Ctrl+C to stop")
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return nil
+		case event, ok := <-fsWatcher.Events:
+			if !ok {
+				return nil
+			}
+			if w.isChange(event) {
+				debounce = time.After(watchDebounce)
+			}
+		case err, ok := <-fsWatcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error(err.Error())
+		case <-debounce:
+			debounce = nil
+			w.run()
+		}
+	}
+}
+
+// addTargets watches each directory target, and the parent directory of each file target
+func (w *watcher) addTargets() error {
+	for _, target := range w.userInput.Targets {
+		info, err := os.Stat(target)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			w.files[filepath.Clean(target)] = true
+			if err := w.fsWatcher.Add(filepath.Dir(target)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		w.dirs = append(w.dirs, filepath.Clean(target))
+		err = filepath.WalkDir(target, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return err
+			}
+			if w.userInput.NoRecurse && path != target {
+				return filepath.SkipDir
+			}
+			return w.fsWatcher.Add(path)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isChange reports whether an event is an edit to a target policy file, rather than corset's own output
+func (w *watcher) isChange(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+		return false
+	}
+
+	path := filepath.Clean(event.Name)
+	if event.Has(fsnotify.Create) && !w.userInput.NoRecurse && w.inDirectoryTarget(path) {
+		// new subdirectories are watched too
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			w.fsWatcher.Add(path)
+			return false
+		}
+	}
+
+	if !isPolicyFile(path) || w.isOutput(path) {
+		return false
+	}
+	if !w.files[path] && !w.inDirectoryTarget(path) {
+		return false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	previous, seen := w.snapshot[absPath(path)]
+	return !seen || string(previous) != string(data)
+}
+
+// isOutput reports whether a path is named like corset's own outputs, as discovery skips them or a
+// directory target's are replaced
+func (w *watcher) isOutput(path string) bool {
+	if isGeneratedOutput(path) {
+		return true
+	}
+	for _, dir := range w.dirs {
+		if isDirectoryOutput(absPath(dir), absPath(path)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *watcher) inDirectoryTarget(path string) bool {
+	for _, dir := range w.dirs {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if w.userInput.NoRecurse && strings.Contains(rel, string(filepath.Separator)) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// run processes the targets and records their contents, so the resulting writes are not treated as edits
+func (w *watcher) run() {
+	if !w.userInput.Quiet {
+		fmt.Fprintf(os.Stderr, "[%s] Processing\n", time.Now().Format("15:04:05"))
+	}
+	// the outputs of the last run may be overwritten, unless they have been edited since
+	userInput := w.userInput
+	for file, data := range w.written {
+		if current, err := os.ReadFile(file); err == nil && bytes.Equal(current, data) {
+			userInput.Replaceable = append(userInput.Replaceable, file)
+		}
+	}
+	summary, err := ProcessFiles(userInput, ResolveFiles(userInput))
+	ReportResults(userInput, summary)
+	if err != nil {
+		slog.Error(err.Error())
+	}
+
+	w.written = map[string][]byte{}
+	for _, result := range summary.Files {
+		if data, err := os.ReadFile(result.Filename); err == nil {
+			w.written[absPath(result.Filename)] = data
+		}
+	}
+
+	w.snapshot = map[string][]byte{}
+	for _, file := range ResolveFiles(w.userInput) {
+		if data, err := os.ReadFile(file); err == nil {
+			w.snapshot[absPath(file)] = data
+		}
+	}
+}
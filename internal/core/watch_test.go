@@ -0,0 +1,152 @@
+package core
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+func TestWatchFiles(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "policy.json")
+	initial := `{
+  "Version": "2012-10-17",
+  "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]
+}`
+	if err := os.WriteFile(testFile, []byte(initial), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	userInput := inputs.UserInput{
+		Target:   testFile,
+		Targets:  []string{testFile},
+		MaxFiles: config.DefaultMaxFiles,
+	}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- WatchFiles(userInput, stop) }()
+
+	waitForContent(t, testFile, `"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"s3:*","Resource":"*"}]`)
+
+	// an edit is picked up and reprocessed
+	edited := `{
+  "Version": "2012-10-17",
+  "Statement": [{"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}]
+}`
+	if err := os.WriteFile(testFile, []byte(edited), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	waitForContent(t, testFile, `"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"ec2:*","Resource":"*"}]`)
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestWatchFilesDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "pol")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create target directory: %v", err)
+	}
+	for name, action := range map[string]string{"a.json": "s3:*", "b.json": "ec2:*"} {
+		policy := `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"` + action + `","Resource":"*"}]}`
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(policy), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	logs := captureLogs(t, slog.LevelInfo)
+	userInput := inputs.UserInput{
+		Target:      dir,
+		Targets:     []string{dir},
+		IsDirectory: true,
+		MaxFiles:    config.DefaultMaxFiles,
+		Quiet:       true,
+	}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- WatchFiles(userInput, stop) }()
+
+	output := filepath.Join(dir, "pol.json")
+	waitForContent(t, output, `"ec2:*"`)
+	waitForContent(t, output, `"s3:*"`)
+
+	// a file added later is packed with the statements already in the output, not in place of them
+	added := `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"iam:*","Resource":"*"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "new.json"), []byte(added), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	waitForContent(t, output, `"iam:*"`)
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	for _, action := range []string{"s3:*", "ec2:*"} {
+		if !strings.Contains(string(data), action) {
+			t.Errorf("Expected %s kept in the output, got %s", action, data)
+		}
+	}
+
+	// the watcher's own writes are not taken for edits, so nothing runs again to fail
+	time.Sleep(3 * watchDebounce)
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Contains(logs.String(), "Error") {
+		t.Errorf("Expected no errors, got %q", logs.String())
+	}
+}
+
+func TestWatchFilesRespectsForce(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "policy.json")
+	deny := func(actions ...string) string {
+		var statements []string
+		for _, action := range actions {
+			statements = append(statements, `{"Effect":"Deny","Action":"`+action+`","Resource":"*"}`)
+		}
+		return `{"Version":"2012-10-17","Statement":[` + strings.Join(statements, ",") + `]}`
+	}
+	if err := os.WriteFile(testFile, []byte(deny("s3:*")), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	captureLogs(t, slog.LevelInfo)
+	// room for one statement a file, so two split into policy.json and policy-2.json
+	userInput := inputs.UserInput{Target: testFile, Targets: []string{testFile}, MaxFiles: config.DefaultMaxFiles, MaxSize: 100, Quiet: true}
+	w := &watcher{userInput: userInput}
+	w.run()
+
+	// a file of the user's where the next run's second output would go is kept without --force
+	existing := filepath.Join(tempDir, "policy-2.json")
+	if err := os.WriteFile(existing, []byte("keep"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(testFile, []byte(deny("s3:*", "ec2:*")), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	w.run()
+	if data, err := os.ReadFile(existing); err != nil || string(data) != "keep" {
+		t.Errorf("Expected %s left alone without --force, got %q", existing, data)
+	}
+}
+
+// waitForContent polls a file until it contains the expected text
+func waitForContent(t *testing.T, filename, expected string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(filename); err == nil && strings.Contains(string(data), expected) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s to contain %s", filename, expected)
+}
@@ -0,0 +1,73 @@
+package inputs
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isGlob reports whether the target contains shell-style glob metacharacters
+func isGlob(target string) bool {
+	return strings.ContainsAny(target, "*?[{")
+}
+
+// expandGlob returns the files matching a glob pattern, including {a,b} brace alternatives
+func expandGlob(pattern string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	for _, p := range expandBraces(pattern) {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files matched %s", pattern)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// expandBraces expands the first {a,b} group in a pattern, recursing for any that remain
+func expandBraces(pattern string) []string {
+	start := strings.Index(pattern, "{")
+	if start == -1 {
+		return []string{pattern}
+	}
+
+	depth := 0
+	var parts []string
+	last := start + 1
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case ',':
+			if depth == 1 {
+				parts = append(parts, pattern[last:i])
+				last = i + 1
+			}
+		case '}':
+			depth--
+			if depth == 0 {
+				parts = append(parts, pattern[last:i])
+				var expanded []string
+				for _, part := range parts {
+					expanded = append(expanded, expandBraces(pattern[:start]+part+pattern[i+1:])...)
+				}
+				return expanded
+			}
+		}
+	}
+
+	// unbalanced brace, treat literally
+	return []string{pattern}
+}
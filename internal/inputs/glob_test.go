@@ -0,0 +1,102 @@
+package inputs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{
+			name:     "no braces",
+			pattern:  "policies/*.json",
+			expected: []string{"policies/*.json"},
+		},
+		{
+			name:     "single group",
+			pattern:  "{a,b}.json",
+			expected: []string{"a.json", "b.json"},
+		},
+		{
+			name:     "multiple groups",
+			pattern:  "{a,b}-{1,2}.json",
+			expected: []string{"a-1.json", "a-2.json", "b-1.json", "b-2.json"},
+		},
+		{
+			name:     "nested group",
+			pattern:  "{a,b{1,2}}.json",
+			expected: []string{"a.json", "b1.json", "b2.json"},
+		},
+		{
+			name:     "unbalanced",
+			pattern:  "{a,b.json",
+			expected: []string{"{a,b.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := expandBraces(tt.pattern)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestExpandGlob(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a.json", "b.json", "c.json", "readme.txt"} {
+		err := os.WriteFile(filepath.Join(tempDir, name), []byte(`{}`), 0644)
+		if err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		pattern       string
+		expectedCount int
+		expectErr     bool
+	}{
+		{
+			name:          "wildcard",
+			pattern:       filepath.Join(tempDir, "*.json"),
+			expectedCount: 3,
+		},
+		{
+			name:          "braces",
+			pattern:       filepath.Join(tempDir, "{a,c}.json"),
+			expectedCount: 2,
+		},
+		{
+			name:      "no matches",
+			pattern:   filepath.Join(tempDir, "*.yaml"),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := expandGlob(tt.pattern)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(files) != tt.expectedCount {
+				t.Errorf("Expected %d files, got %d", tt.expectedCount, len(files))
+			}
+		})
+	}
+}
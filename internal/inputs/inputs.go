@@ -1,40 +1,512 @@
 package inputs
 
 import (
-	"log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/logging"
 	"github.com/spf13/pflag"
 )
 
 type UserInput struct {
-	Target      string
-	Whitespace  bool
-	IsDirectory bool
-	MaxFiles    int
+	Command       string
+	Target        string
+	Targets       []string // all files and directories given, with globs expanded
+	Whitespace    bool
+	IsDirectory   bool
+	MaxFiles      int
+	NoRecurse     bool
+	FollowLinks   bool // descend into symlinked directories
+	IncludeHidden bool // read dotfiles and hidden directories
+	Strategy      string
+	Minimize      bool
+	Merge         bool
+	Validate      bool
+	Gzip          bool
+	Watch         bool
+	Indent        string // indentation for whitespace output, empty for the default
+	Optimize      bool
+	MaxStatements int    // statements allowed per file, 0 for no cap
+	Apply         string // AWS Organizations policy name or ID to push the output to
+	Confirm       bool
+	LintActions   bool
+	ActionsFile   string // catalog of valid actions for --lint-actions, empty for the embedded subset
+	Dedupe        bool
+	IsArchive     bool // a single zip target, its policies are read without extracting
+	Sid           bool
+	StripSid      bool
+	SortActions   bool
+	Collapse      bool   // collapse single-element arrays to strings
+	PolicyType    string // scp or rcp
+	MaxSize       int    // character limit per file
+	Headroom      int    // characters packing leaves free in each file, for statements added later
+	WarnThreshold int    // percent of the limit a file can fill before a warning, 0 to never warn
+	FailThreshold bool   // fail when a file is over the warn threshold
+	Partition     string // warn about resource ARNs outside this partition, empty to skip the check
+	NoCombine     bool   // process each file on its own rather than pooling their statements
+	Manifest      bool
+	JSON          bool // report results as JSON on stdout instead of the summary
+	NoColor       bool
+	Quiet         bool   // hide progress, summaries and notices of files with no statements
+	NameTemplate  string // output filename pattern using {base}, {index} and {ext}, empty for the default naming
+	Force         bool   // overwrite existing files that are not inputs being replaced
+	Format        string // json, cloudformation or cloudformation-yaml
+	Lint          bool   // warn about overly permissive Allows
+	Check         bool   // only check the statements fit, writing nothing
+	Report        string // csv to write a report of the output files, empty for none
+	Output        string // pack everything into this one file, empty for the default naming
+	DryRun        bool   // list the files clean would remove without removing them
+	GroupBy       string // pack files apart by the part of their name before this delimiter, empty to pool them
+	Group         string // the prefix group being packed, set while processing rather than by a flag
+	Top           int    // largest statements stats lists, 0 for none
+	Policy        string // policy JSON given inline, packed to stdout in place of reading files
+	Explode       bool   // write each statement to a file of its own rather than packing
+	LogLevel      string // debug, info, warn or error
+	LogFormat     string // text or json
+	ContinueOnErr bool   // process the remaining files after one fails, reporting the failures at the end
+	Verify        bool   // re-read the outputs and check they hold exactly the statements packed, after any rewrites
+	Schema        string // JSON Schema file every policy must match, empty for none
+
+	// outputs of the last --watch run, unchanged since, which the next run may overwrite without --force
+	Replaceable []string
+	// where packing logs, set by the library, nil for the default logger
+	Logger *slog.Logger
 }
 
-// ParseFlags returns pased CLI flags and arguments
+var commands = []string{config.CommandSplit, config.CommandMerge, config.CommandValidate, config.CommandStats, config.CommandClean}
+
 func isDirectory(target string) bool {
-	info, _ := os.Stat(target)
-	return info.IsDir()
+	info, err := os.Stat(target)
+	return err == nil && info.IsDir()
 }
 
+// ParseFlags returns parsed CLI flags and arguments
 func ParseFlags() UserInput {
-	var whitespace bool
+	userInput, err := parseArgs(os.Args[1:])
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(config.ExitUsage)
+	}
+	return userInput
+}
+
+func parseArgs(args []string) (UserInput, error) {
+	// a bare invocation is an alias for split
+	command := config.CommandSplit
+	if len(args) > 0 && isCommand(args[0]) {
+		command = args[0]
+		args = args[1:]
+	}
+
+	userInput := UserInput{
+		Command:    command,
+		MaxFiles:   config.DefaultMaxFiles,
+		Strategy:   config.StrategyFirstFit,
+		PolicyType: config.PolicyTypeSCP,
+		MaxSize:    config.MaxPolicySize,
+		Format:     config.FormatJSON,
+		LogLevel:   config.LogLevelInfo,
+		LogFormat:  config.LogFormatText,
+	}
+
+	var indent, headroom string
+	flags := newFlagSet(command, &userInput, &indent, &headroom)
+	if err := flags.Parse(args); err != nil {
+		return userInput, err
+	}
+
+	level, err := logging.ParseLevel(userInput.LogLevel)
+	if err != nil {
+		return userInput, err
+	}
+	if userInput.LogFormat != config.LogFormatText && userInput.LogFormat != config.LogFormatJSON {
+		return userInput, fmt.Errorf("unknown log-format %s, use %s or %s", userInput.LogFormat, config.LogFormatText, config.LogFormatJSON)
+	}
+	// warnings about the flags follow --log-level and --log-format, which main only applies once they are parsed
+	logger := slog.New(logging.NewHandler(os.Stderr, level, userInput.LogFormat))
 
-	pflag.BoolVarP(&whitespace, "whitespace", "w", false, "retain whitespace")
-	pflag.Parse()
+	// an explicit indent implies whitespace output
+	if flags.Changed("indent") {
+		parsed, err := parseIndent(indent)
+		if err != nil {
+			return userInput, err
+		}
+		userInput.Indent = parsed
+		userInput.Whitespace = true
+	}
+
+	if userInput.Policy != "" {
+		if flags.NArg() > 0 {
+			return userInput, errors.New("--policy cannot be used with a file or directory")
+		}
+		if err := validatePolicy(userInput); err != nil {
+			return userInput, err
+		}
+	} else if flags.NArg() < 1 {
+		return userInput, errors.New("please specify a directory or file")
+	}
+	targets := make([]string, flags.NArg())
+	for i, target := range flags.Args() {
+		targets[i] = expandPath(target)
+	}
+	if len(targets) > 0 {
+		userInput.Target = targets[0]
+	}
+
+	if userInput.ActionsFile != "" {
+		userInput.LintActions = true
+	}
+
+	if userInput.Sid && userInput.StripSid {
+		return userInput, errors.New("--sid and --strip-sid cannot be used together")
+	}
+
+	if userInput.Check && (userInput.Apply != "" || userInput.Watch) {
+		return userInput, errors.New("--check cannot be used with --apply or --watch")
+	}
+
+	if userInput.Watch && userInput.Apply != "" {
+		return userInput, errors.New("--watch cannot be used with --apply, which would push every edit to AWS")
+	}
+
+	if userInput.Confirm && userInput.Apply == "" {
+		return userInput, errors.New("--confirm requires --apply")
+	}
+
+	if userInput.MaxStatements < 0 {
+		return userInput, fmt.Errorf("invalid max-statements %d, must be 0 or more", userInput.MaxStatements)
+	}
+
+	if userInput.WarnThreshold < 0 || userInput.WarnThreshold > 100 {
+		return userInput, fmt.Errorf("invalid warn-threshold %d, must be a percentage from 0 to 100", userInput.WarnThreshold)
+	}
+
+	if userInput.FailThreshold && userInput.WarnThreshold == 0 {
+		return userInput, errors.New("--fail-on-threshold needs a warn-threshold above 0")
+	}
+
+	if userInput.Top < 0 {
+		return userInput, fmt.Errorf("invalid top %d, must be 0 or more", userInput.Top)
+	}
+
+	if userInput.MaxSize < 1 {
+		return userInput, fmt.Errorf("invalid max-size %d, must be 1 or more", userInput.MaxSize)
+	}
+
+	switch userInput.PolicyType {
+	case config.PolicyTypeSCP, config.PolicyTypeRCP:
+	case config.PolicyTypeIAM:
+		if !flags.Changed("max-size") {
+			userInput.MaxSize = config.MaxIAMPolicySize
+		}
+		// AWS Organizations only holds SCPs and RCPs
+		if userInput.Apply != "" || userInput.Format != config.FormatJSON {
+			return userInput, errors.New("--type iam cannot be used with --apply or --format")
+		}
+	default:
+		return userInput, fmt.Errorf("unknown policy type %s, use %s, %s or %s",
+			userInput.PolicyType, config.PolicyTypeSCP, config.PolicyTypeRCP, config.PolicyTypeIAM)
+	}
+
+	// measured against the final max-size, so a percentage follows --type iam
+	if flags.Changed("headroom") {
+		parsed, err := parseHeadroom(headroom, userInput.MaxSize)
+		if err != nil {
+			return userInput, err
+		}
+		userInput.Headroom = parsed
+	}
+
+	if userInput.NameTemplate != "" {
+		if err := validateNameTemplate(userInput.NameTemplate); err != nil {
+			return userInput, err
+		}
+	}
+
+	if userInput.Output != "" {
+		if userInput.Minimize || userInput.NameTemplate != "" || userInput.NoCombine || userInput.Format != config.FormatJSON {
+			return userInput, errors.New("--output cannot be used with --minimize, --name-template, --no-combine or --format")
+		}
+		// a single named file is one policy
+		userInput.MaxFiles = 1
+	}
+
+	if userInput.Explode && (userInput.Output != "" || userInput.Minimize || userInput.NoCombine || userInput.Check) {
+		return userInput, errors.New("--explode cannot be used with --output, --minimize, --no-combine or --check")
+	}
+
+	// the statements are checked as they are read back from the written files
+	if userInput.Verify && (userInput.Check || userInput.Policy != "" || userInput.Format != config.FormatJSON) {
+		return userInput, errors.New("--verify cannot be used with --check, --policy or --format, which write no policy files")
+	}
+
+	// each group is written alongside the others, where a single named output, manifest or template would collide
+	if userInput.GroupBy != "" {
+		if userInput.NoCombine || userInput.Output != "" || userInput.Manifest || userInput.Report != "" ||
+			userInput.Format != config.FormatJSON || userInput.Apply != "" {
+			return userInput, errors.New("--group-by-prefix cannot be used with --no-combine, --output, --manifest, --report, --format or --apply")
+		}
+	}
 
-	if pflag.NArg() < 1 {
-		log.Fatal("Error: Please specify a directory or file")
+	// each file is written back on its own, and would overwrite the manifest, report or template of the one before
+	if userInput.NoCombine && (userInput.Manifest || userInput.Report != "" || userInput.Format != config.FormatJSON) {
+		return userInput, errors.New("--no-combine cannot be used with --manifest, --report or --format")
 	}
-	target := pflag.Arg(0)
-	return UserInput{
-		Target:      target,
-		Whitespace:  whitespace,
-		IsDirectory: isDirectory(target),
-		MaxFiles:    config.DefaultMaxFiles,
+
+	if userInput.Report != "" && userInput.Report != config.ReportCSV {
+		return userInput, fmt.Errorf("unknown report %s, use %s", userInput.Report, config.ReportCSV)
+	}
+
+	switch userInput.Format {
+	case config.FormatJSON:
+	case config.FormatCloudFormation, config.FormatCloudFormationYAML:
+		if userInput.Gzip || userInput.Manifest || userInput.Apply != "" {
+			return userInput, fmt.Errorf("--format %s cannot be used with --gzip, --manifest or --apply", userInput.Format)
+		}
+	default:
+		return userInput, fmt.Errorf("unknown format %s, use %s, %s or %s",
+			userInput.Format, config.FormatJSON, config.FormatCloudFormation, config.FormatCloudFormationYAML)
+	}
+
+	switch userInput.Partition {
+	case "", config.PartitionAWS, config.PartitionGovCloud, config.PartitionChina:
+	default:
+		return userInput, fmt.Errorf("unknown partition %s, use %s, %s or %s",
+			userInput.Partition, config.PartitionAWS, config.PartitionGovCloud, config.PartitionChina)
+	}
+
+	if userInput.PolicyType == config.PolicyTypeSCP && userInput.MaxSize > config.MaxPolicySize {
+		logger.Warn(fmt.Sprintf("max-size %d exceeds the AWS SCP limit of %d characters", userInput.MaxSize, config.MaxPolicySize))
+	}
+	if userInput.PolicyType == config.PolicyTypeIAM && userInput.MaxSize > config.MaxIAMPolicySize {
+		logger.Warn(fmt.Sprintf("max-size %d exceeds the AWS IAM managed policy limit of %d characters", userInput.MaxSize, config.MaxIAMPolicySize))
+	}
+
+	switch userInput.Strategy {
+	case config.StrategyFirstFit, config.StrategyBestFit, config.StrategyBalance:
+	default:
+		return userInput, fmt.Errorf("unknown strategy %s, use %s, %s or %s",
+			userInput.Strategy, config.StrategyFirstFit, config.StrategyBestFit, config.StrategyBalance)
+	}
+
+	for _, target := range targets {
+		if isGlob(target) {
+			files, err := expandGlob(target)
+			if err != nil {
+				return userInput, err
+			}
+			userInput.Targets = append(userInput.Targets, files...)
+			continue
+		}
+		if _, err := os.Stat(target); errors.Is(err, fs.ErrNotExist) {
+			return userInput, fmt.Errorf("target not found: %s", target)
+		} else if err != nil {
+			return userInput, err
+		}
+		userInput.Targets = append(userInput.Targets, target)
+	}
+
+	// a single directory target names its outputs after the directory
+	if flags.NArg() == 1 && !isGlob(userInput.Target) {
+		userInput.IsDirectory = isDirectory(userInput.Target)
+		userInput.IsArchive = !userInput.IsDirectory && strings.HasSuffix(userInput.Target, ".zip")
+	}
+
+	if command == config.CommandClean && !userInput.IsDirectory {
+		return userInput, fmt.Errorf("clean needs a directory, got %s", userInput.Target)
+	}
+
+	if userInput.GroupBy != "" && !userInput.IsDirectory {
+		return userInput, fmt.Errorf("--group-by-prefix needs a directory, got %s", userInput.Target)
+	}
+
+	if userInput.NoCombine && userInput.IsArchive {
+		return userInput, errors.New("--no-combine cannot write back into a zip archive")
+	}
+	return userInput, nil
+}
+
+// parseHeadroom converts a number of characters, or a percentage of maxSize such as 10%, into characters
+func parseHeadroom(value string, maxSize int) (int, error) {
+	number, percent := strings.CutSuffix(value, "%")
+	headroom, err := strconv.Atoi(number)
+	if err != nil || headroom < 0 || (percent && headroom > 99) {
+		return 0, fmt.Errorf("invalid headroom %s, use a number of characters or a percentage from 0%% to 99%%", value)
+	}
+	if percent {
+		headroom = maxSize * headroom / 100
+	}
+	if headroom >= maxSize {
+		return 0, fmt.Errorf("invalid headroom %s, it leaves no room within max-size %d", value, maxSize)
+	}
+	return headroom, nil
+}
+
+// parseIndent converts a count of spaces or the literal tab into an indent string
+func parseIndent(value string) (string, error) {
+	if value == "tab" {
+		return "\t", nil
+	}
+	spaces, err := strconv.Atoi(value)
+	if err != nil || spaces < 1 || spaces > 8 {
+		return "", fmt.Errorf("invalid indent %s, use a number of spaces from 1 to 8 or tab", value)
+	}
+	return strings.Repeat(" ", spaces), nil
+}
+
+// validatePolicy checks an inline --policy is a JSON object. The packed policies are written to stdout,
+// so flags that name or describe output files are rejected.
+func validatePolicy(userInput UserInput) error {
+	var document interface{}
+	if err := json.Unmarshal([]byte(userInput.Policy), &document); err != nil {
+		return fmt.Errorf("invalid --policy, it is not well-formed JSON: %v", err)
+	}
+	if _, ok := document.(map[string]interface{}); !ok {
+		return errors.New("invalid --policy, it must be a JSON object with a Statement")
+	}
+	if userInput.Output != "" || userInput.NoCombine || userInput.GroupBy != "" || userInput.Watch || userInput.JSON ||
+		userInput.Gzip || userInput.Manifest || userInput.Report != "" || userInput.Format != config.FormatJSON || userInput.Apply != "" {
+		return errors.New("--policy writes to stdout, and cannot be used with --output, --no-combine, --group-by-prefix, " +
+			"--watch, --json, --gzip, --manifest, --report, --format or --apply")
+	}
+	return nil
+}
+
+// validateNameTemplate rejects templates that would give every output file the same name
+func validateNameTemplate(template string) error {
+	if !strings.Contains(template, "{index}") {
+		return fmt.Errorf("invalid name-template %s, it must include {index} so each file has a distinct name", template)
+	}
+	rest := strings.NewReplacer("{base}", "", "{index}", "", "{ext}", "").Replace(template)
+	if strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("invalid name-template %s, the placeholders are {base}, {index} and {ext}", template)
+	}
+	if strings.ContainsRune(template, '/') {
+		return fmt.Errorf("invalid name-template %s, it must be a filename without a directory", template)
+	}
+	return nil
+}
+
+// newFlagSet registers the flags available to a command
+func newFlagSet(command string, userInput *UserInput, indent, headroom *string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(command, pflag.ContinueOnError)
+	flags.BoolVar(&userInput.NoRecurse, "no-recurse", false, "only scan the top level of a directory")
+	flags.BoolVar(&userInput.FollowLinks, "follow-symlinks", false, "descend into symlinked directories")
+	flags.BoolVar(&userInput.IncludeHidden, "include-hidden", false, "read dotfiles and hidden directories such as .git")
+	flags.BoolVarP(&userInput.Quiet, "quiet", "q", false, "hide progress, summaries and notices of files with no statements")
+	flags.StringVar(&userInput.LogLevel, "log-level", config.LogLevelInfo, "log messages at this level and above (debug, info, warn or error)")
+	flags.StringVar(&userInput.LogFormat, "log-format", config.LogFormatText, "log format (text or json)")
+	flags.StringVar(&userInput.PolicyType, "type", config.PolicyTypeSCP, "policy type (scp, rcp or iam)")
+
+	if command != config.CommandClean {
+		flags.BoolVar(&userInput.ContinueOnErr, "continue-on-error", false, "process the remaining files after one fails, reporting every failure at the end")
+	}
+
+	if command != config.CommandStats && command != config.CommandClean {
+		flags.BoolVar(&userInput.Lint, "lint", false, "warn about overly permissive Allow statements")
+		flags.StringVar(&userInput.Partition, "partition", "", "warn about resource ARNs outside this partition (aws, aws-us-gov or aws-cn)")
+		flags.StringVar(&userInput.Schema, "schema", "", "JSON Schema file each policy must match, as parsed with Statement an array, before it is packed")
+	}
+
+	switch command {
+	case config.CommandSplit:
+		flags.BoolVarP(&userInput.Whitespace, "whitespace", "w", false, "retain whitespace")
+		flags.StringVar(indent, "indent", "2", "indent whitespace output by a number of spaces or tab")
+		flags.StringVar(&userInput.Strategy, "strategy", config.StrategyFirstFit, "packing strategy (ffd, bfd or balance)")
+		flags.BoolVar(&userInput.Minimize, "minimize", false, "search for the fewest files the statements fit, up to the 5 policies AWS attaches to a target")
+		flags.BoolVar(&userInput.Check, "check", false, "check the statements fit without writing anything, for CI")
+		flags.StringVarP(&userInput.Output, "output", "o", "", "pack every statement into this one file, failing if they don't fit")
+		flags.StringVar(&userInput.NameTemplate, "name-template", "", "name output files with a pattern of {base}, {index} and {ext}")
+		flags.BoolVar(&userInput.NoCombine, "no-combine", false, "process each file on its own, writing it back under its own name")
+		flags.StringVar(&userInput.GroupBy, "group-by-prefix", "", "pack files apart by their name up to a delimiter, e.g. teamA-1.json into teamA.json")
+		flags.Lookup("group-by-prefix").NoOptDefVal = config.DefaultGroupDelimiter
+		flags.IntVar(&userInput.MaxStatements, "max-statements", 0, "maximum statements per file (default no limit)")
+		flags.IntVar(&userInput.MaxSize, "max-size", config.MaxPolicySize, "maximum characters per file, 6144 for --type iam")
+		flags.StringVar(headroom, "headroom", "", "characters, or a percentage of max-size, to leave free in each file")
+		flags.IntVar(&userInput.WarnThreshold, "warn-threshold", config.DefaultWarnThreshold, "warn when a file is over this percent of max-size, 0 to never warn")
+		flags.BoolVar(&userInput.FailThreshold, "fail-on-threshold", false, "fail when a file is over the warn threshold, for CI")
+		flags.BoolVar(&userInput.Merge, "merge", false, "merge statements that differ only in Action")
+		flags.BoolVar(&userInput.Optimize, "optimize", false, "drop actions already covered by a wildcard")
+		flags.BoolVar(&userInput.Validate, "validate", false, "check statements are valid before packing")
+		flags.BoolVar(&userInput.Dedupe, "dedupe", false, "remove statements equivalent to another, ignoring ordering and Sid")
+		flags.BoolVar(&userInput.Sid, "sid", false, "give statements without a Sid a generated one")
+		flags.BoolVar(&userInput.StripSid, "strip-sid", false, "remove every Sid to save characters")
+		flags.BoolVar(&userInput.SortActions, "sort-actions", false, "sort Action and Resource arrays alphabetically")
+		flags.BoolVar(&userInput.Collapse, "collapse-arrays", false, "collapse single-element arrays to strings to save characters")
+		flags.BoolVar(&userInput.LintActions, "lint-actions", false, "check actions against a list of known AWS actions")
+		flags.StringVar(&userInput.ActionsFile, "actions-file", "", "file of known actions for --lint-actions, one service:Action per line")
+		flags.BoolVar(&userInput.Gzip, "gzip", false, "gzip each output file")
+		flags.BoolVar(&userInput.JSON, "json", false, "print the results as JSON instead of a summary")
+		flags.BoolVar(&userInput.NoColor, "no-color", false, "print the summary without color")
+		flags.StringVar(&userInput.Format, "format", config.FormatJSON, "output format (json, cloudformation or cloudformation-yaml)")
+		flags.BoolVar(&userInput.Force, "force", false, "overwrite existing files that are not inputs being replaced")
+		flags.StringVar(&userInput.Report, "report", "", "write a report of the output files (csv)")
+		flags.BoolVar(&userInput.Manifest, "manifest", false, "write corset-manifest.json listing the statements in each output file")
+		flags.BoolVar(&userInput.Watch, "watch", false, "reprocess whenever a policy file changes")
+		flags.StringVar(&userInput.Apply, "apply", "", "push the output to the AWS Organizations policy with this name or ID (dry run)")
+		flags.BoolVar(&userInput.Confirm, "confirm", false, "make the --apply changes rather than a dry run")
+		flags.BoolVar(&userInput.Explode, "explode", false, "write each statement to a file of its own, without packing")
+		flags.StringVar(&userInput.Policy, "policy", "", "pack this policy JSON, given inline, and write the result to stdout")
+		flags.BoolVar(&userInput.Verify, "verify", false, "re-read the written files and fail unless they hold exactly the statements packed, as any rewrites left them")
+	case config.CommandMerge:
+		flags.BoolVarP(&userInput.Whitespace, "whitespace", "w", false, "retain whitespace")
+		flags.StringVar(indent, "indent", "2", "indent whitespace output by a number of spaces or tab")
+		flags.BoolVar(&userInput.Validate, "validate", false, "check statements are valid before merging")
+		flags.BoolVar(&userInput.Dedupe, "dedupe", false, "remove statements equivalent to another, ignoring ordering and Sid")
+		flags.BoolVar(&userInput.Sid, "sid", false, "give statements without a Sid a generated one")
+		flags.BoolVar(&userInput.StripSid, "strip-sid", false, "remove every Sid to save characters")
+		flags.BoolVar(&userInput.SortActions, "sort-actions", false, "sort Action and Resource arrays alphabetically")
+		flags.BoolVar(&userInput.Collapse, "collapse-arrays", false, "collapse single-element arrays to strings to save characters")
+		flags.BoolVar(&userInput.Optimize, "optimize", false, "drop actions already covered by a wildcard")
+		flags.StringVar(&userInput.GroupBy, "group-by-prefix", "", "merge files apart by their name up to a delimiter, e.g. teamA-1.json into teamA.json")
+		flags.Lookup("group-by-prefix").NoOptDefVal = config.DefaultGroupDelimiter
+		flags.BoolVar(&userInput.Gzip, "gzip", false, "gzip the output file")
+		flags.BoolVar(&userInput.JSON, "json", false, "print the results as JSON instead of a summary")
+		flags.BoolVar(&userInput.NoColor, "no-color", false, "print the summary without color")
+		flags.StringVar(&userInput.Format, "format", config.FormatJSON, "output format (json, cloudformation or cloudformation-yaml)")
+		flags.BoolVar(&userInput.Force, "force", false, "overwrite existing files that are not inputs being replaced")
+		flags.StringVar(&userInput.Report, "report", "", "write a report of the output files (csv)")
+		flags.BoolVar(&userInput.Manifest, "manifest", false, "write corset-manifest.json listing the statements in each output file")
+		flags.BoolVar(&userInput.Watch, "watch", false, "reprocess whenever a policy file changes")
+		flags.StringVar(&userInput.Apply, "apply", "", "push the output to the AWS Organizations policy with this name or ID (dry run)")
+		flags.BoolVar(&userInput.Confirm, "confirm", false, "make the --apply changes rather than a dry run")
+		flags.BoolVar(&userInput.Verify, "verify", false, "re-read the written files and fail unless they hold exactly the statements packed, as any rewrites left them")
+	case config.CommandValidate:
+		flags.BoolVar(&userInput.LintActions, "lint-actions", false, "check actions against a list of known AWS actions")
+		flags.StringVar(&userInput.ActionsFile, "actions-file", "", "file of known actions for --lint-actions, one service:Action per line")
+	case config.CommandStats:
+		flags.BoolVarP(&userInput.Whitespace, "whitespace", "w", false, "also measure the size with whitespace retained")
+		flags.StringVar(indent, "indent", "2", "measure with an indent of a number of spaces or tab")
+		flags.BoolVar(&userInput.Collapse, "collapse-arrays", false, "measure with single-element arrays collapsed to strings")
+		flags.IntVar(&userInput.MaxSize, "max-size", config.MaxPolicySize, "measure against a limit of this many characters per file")
+		flags.IntVar(&userInput.Top, "top", 0, "list this many of the largest statements")
+	case config.CommandClean:
+		flags.BoolVar(&userInput.DryRun, "dry-run", false, "list the generated files without removing them")
+	}
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: corset %s [flags] <file|directory>...\n\n", command)
+		fmt.Fprintf(os.Stderr, "Commands: %s (default), %s\n\n", commands[0], strings.Join(commands[1:], ", "))
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+func isCommand(arg string) bool {
+	for _, command := range commands {
+		if arg == command {
+			return true
+		}
 	}
+	return false
 }
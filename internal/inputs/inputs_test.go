@@ -33,6 +33,11 @@ func TestIsDirectory(t *testing.T) {
 			path:     testFile,
 			expected: false,
 		},
+		{
+			name:     "Missing path",
+			path:     filepath.Join(tempDir, "missing"),
+			expected: false,
+		},
 	}
 	
 	for _, tt := range tests {
@@ -95,4 +100,551 @@ func TestUserInputStructure(t *testing.T) {
 }
 
 // Note: Testing ParseFlags() would require mocking command line arguments
-// which is more complex and might be better suited for integration tests
\ No newline at end of file
+// which is more complex and might be better suited for integration tests
+func TestParseArgs(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "policy.json")
+	err := os.WriteFile(testFile, []byte(`{}`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		args            []string
+		expectedCommand string
+		expectErr       bool
+	}{
+		{
+			name:            "bare invocation is split",
+			args:            []string{"-w", testFile},
+			expectedCommand: config.CommandSplit,
+		},
+		{
+			name:            "split",
+			args:            []string{"split", "--strategy", "bfd", testFile},
+			expectedCommand: config.CommandSplit,
+		},
+		{
+			name:            "merge",
+			args:            []string{"merge", "-w", tempDir},
+			expectedCommand: config.CommandMerge,
+		},
+		{
+			name:            "validate",
+			args:            []string{"validate", testFile},
+			expectedCommand: config.CommandValidate,
+		},
+		{
+			name:            "stats",
+			args:            []string{"stats", tempDir},
+			expectedCommand: config.CommandStats,
+		},
+		{
+			name:            "inline policy",
+			args:            []string{"--policy", `{"Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`},
+			expectedCommand: config.CommandSplit,
+		},
+		{
+			name:      "malformed inline policy",
+			args:      []string{"--policy", `{"Statement": [`},
+			expectErr: true,
+		},
+		{
+			name:      "inline policy that is not an object",
+			args:      []string{"--policy", `[]`},
+			expectErr: true,
+		},
+		{
+			name:      "inline policy with a file",
+			args:      []string{"--policy", `{"Statement": []}`, testFile},
+			expectErr: true,
+		},
+		{
+			name:      "inline policy with json output",
+			args:      []string{"--policy", `{"Statement": []}`, "--json"},
+			expectErr: true,
+		},
+		{
+			name:      "missing target",
+			args:      []string{filepath.Join(tempDir, "missing.json")},
+			expectErr: true,
+		},
+		{
+			name:      "missing second target",
+			args:      []string{testFile, filepath.Join(tempDir, "missing")},
+			expectErr: true,
+		},
+		{
+			name:            "explode",
+			args:            []string{"--explode", testFile},
+			expectedCommand: config.CommandSplit,
+		},
+		{
+			name:      "explode with output",
+			args:      []string{"--explode", "-o", "out.json", testFile},
+			expectErr: true,
+		},
+		{
+			name:            "stats top",
+			args:            []string{"stats", "--top", "5", tempDir},
+			expectedCommand: config.CommandStats,
+		},
+		{
+			name:      "negative top",
+			args:      []string{"stats", "--top", "-1", tempDir},
+			expectErr: true,
+		},
+		{
+			name:      "top not available to split",
+			args:      []string{"--top", "5", testFile},
+			expectErr: true,
+		},
+		{
+			name:            "debug json logs",
+			args:            []string{"--log-level", "debug", "--log-format", "json", testFile},
+			expectedCommand: config.CommandSplit,
+		},
+		{
+			name:            "log level on a subcommand",
+			args:            []string{"validate", "--log-level", "error", testFile},
+			expectedCommand: config.CommandValidate,
+		},
+		{
+			name:            "continue on error",
+			args:            []string{"validate", "--continue-on-error", testFile},
+			expectedCommand: config.CommandValidate,
+		},
+		{
+			name:      "continue on error not available to clean",
+			args:      []string{"clean", "--continue-on-error", tempDir},
+			expectErr: true,
+		},
+		{
+			name:            "merge verify",
+			args:            []string{"merge", "--verify", testFile},
+			expectedCommand: config.CommandMerge,
+		},
+		{
+			name:      "verify with check",
+			args:      []string{"--verify", "--check", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "verify with cloudformation",
+			args:      []string{"--verify", "--format", "cloudformation", testFile},
+			expectErr: true,
+		},
+		{
+			name:            "validate schema",
+			args:            []string{"validate", "--schema", "schema.json", testFile},
+			expectedCommand: config.CommandValidate,
+		},
+		{
+			name:      "schema not available to stats",
+			args:      []string{"stats", "--schema", "schema.json", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "unknown log level",
+			args:      []string{"--log-level", "verbose", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "unknown log format",
+			args:      []string{"--log-format", "yaml", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "flag not available to command",
+			args:      []string{"validate", "--strategy", "bfd", testFile},
+			expectErr: true,
+		},
+		{
+			name:            "rcp type",
+			args:            []string{"validate", "--type", "rcp", testFile},
+			expectedCommand: config.CommandValidate,
+		},
+		{
+			name:      "watch with apply",
+			args:      []string{"--watch", "--apply", "guardrails", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "iam type with apply",
+			args:      []string{"--type", "iam", "--apply", "guardrails", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "unknown type",
+			args:      []string{"--type", "tag", testFile},
+			expectErr: true,
+		},
+		{
+			name:            "max size",
+			args:            []string{"--max-size", "6144", testFile},
+			expectedCommand: config.CommandSplit,
+		},
+		{
+			name:      "zero max size",
+			args:      []string{"--max-size", "0", testFile},
+			expectErr: true,
+		},
+		{
+			name:            "partition",
+			args:            []string{"validate", "--partition", "aws-cn", testFile},
+			expectedCommand: config.CommandValidate,
+		},
+		{
+			name:      "unknown partition",
+			args:      []string{"--partition", "aws-eu", testFile},
+			expectErr: true,
+		},
+		{
+			name:            "name template",
+			args:            []string{"--name-template", "{base}.part{index}{ext}", testFile},
+			expectedCommand: config.CommandSplit,
+		},
+		{
+			name:      "name template without index",
+			args:      []string{"--name-template", "{base}{ext}", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "name template with unknown placeholder",
+			args:      []string{"--name-template", "{name}-{index}.json", testFile},
+			expectErr: true,
+		},
+		{
+			name:            "cloudformation format",
+			args:            []string{"--format", "cloudformation", testFile},
+			expectedCommand: config.CommandSplit,
+		},
+		{
+			name:      "cloudformation format with gzip",
+			args:      []string{"--format", "cloudformation", "--gzip", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "unknown format",
+			args:      []string{"--format", "terraform", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "output with minimize",
+			args:      []string{"-o", "all.json", "--minimize", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "output with name template",
+			args:      []string{"--output", "all.json", "--name-template", "{base}-{index}{ext}", testFile},
+			expectErr: true,
+		},
+		{
+			name:            "csv report",
+			args:            []string{"--report", "csv", testFile},
+			expectedCommand: config.CommandSplit,
+		},
+		{
+			name:      "unknown report",
+			args:      []string{"--report", "xlsx", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "clean a file",
+			args:      []string{"clean", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "dry run not available to split",
+			args:      []string{"--dry-run", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "unknown strategy",
+			args:      []string{"--strategy", "worst", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "missing target",
+			args:      []string{"split"},
+			expectErr: true,
+		},
+		{
+			name:            "fail on threshold",
+			args:            []string{"--warn-threshold", "95", "--fail-on-threshold", testFile},
+			expectedCommand: config.CommandSplit,
+		},
+		{
+			name:      "warn threshold over 100",
+			args:      []string{"--warn-threshold", "101", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "fail on threshold without one",
+			args:      []string{"--warn-threshold", "0", "--fail-on-threshold", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "group by prefix of a file",
+			args:      []string{"--group-by-prefix", testFile},
+			expectErr: true,
+		},
+		{
+			name:      "no-combine with manifest",
+			args:      []string{"--no-combine", "--manifest", tempDir},
+			expectErr: true,
+		},
+		{
+			name:      "no-combine with report",
+			args:      []string{"--no-combine", "--report", "csv", tempDir},
+			expectErr: true,
+		},
+		{
+			name:      "no-combine with cloudformation",
+			args:      []string{"--no-combine", "--format", "cloudformation", tempDir},
+			expectErr: true,
+		},
+		{
+			name:      "group by prefix with no-combine",
+			args:      []string{"--group-by-prefix", "--no-combine", tempDir},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userInput, err := parseArgs(tt.args)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if userInput.Command != tt.expectedCommand {
+				t.Errorf("Expected command %s, got %s", tt.expectedCommand, userInput.Command)
+			}
+			if userInput.MaxFiles != config.DefaultMaxFiles {
+				t.Errorf("Expected MaxFiles %d, got %d", config.DefaultMaxFiles, userInput.MaxFiles)
+			}
+		})
+	}
+}
+
+func TestParseArgsIAMMaxSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{name: "scp default", args: []string{t.TempDir()}, expected: config.MaxPolicySize},
+		{name: "iam default", args: []string{"--type", "iam", t.TempDir()}, expected: config.MaxIAMPolicySize},
+		{name: "iam with max-size", args: []string{"--type", "iam", "--max-size", "4096", t.TempDir()}, expected: 4096},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userInput, err := parseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if userInput.MaxSize != tt.expected {
+				t.Errorf("Expected MaxSize %d, got %d", tt.expected, userInput.MaxSize)
+			}
+		})
+	}
+}
+
+func TestParseArgsOutput(t *testing.T) {
+	userInput, err := parseArgs([]string{"-o", "all.json", t.TempDir()})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if userInput.Output != "all.json" {
+		t.Errorf("Expected Output all.json, got %s", userInput.Output)
+	}
+	if userInput.MaxFiles != 1 {
+		t.Errorf("Expected --output to imply MaxFiles 1, got %d", userInput.MaxFiles)
+	}
+}
+
+func TestParseArgsHeadroom(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expected  int
+		expectErr bool
+	}{
+		{name: "none", args: []string{t.TempDir()}, expected: 0},
+		{name: "characters", args: []string{"--headroom", "500", t.TempDir()}, expected: 500},
+		{name: "percent", args: []string{"--headroom", "10%", t.TempDir()}, expected: 512},
+		{name: "percent of max-size", args: []string{"--headroom", "25%", "--max-size", "4000", t.TempDir()}, expected: 1000},
+		{name: "percent of iam limit", args: []string{"--headroom", "50%", "--type", "iam", t.TempDir()}, expected: 3072},
+		{name: "not a number", args: []string{"--headroom", "lots", t.TempDir()}, expectErr: true},
+		{name: "negative", args: []string{"--headroom", "-1", t.TempDir()}, expectErr: true},
+		{name: "whole file", args: []string{"--headroom", "100%", t.TempDir()}, expectErr: true},
+		{name: "over max-size", args: []string{"--headroom", "5120", t.TempDir()}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userInput, err := parseArgs(tt.args)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if userInput.Headroom != tt.expected {
+				t.Errorf("Expected Headroom %d, got %d", tt.expected, userInput.Headroom)
+			}
+		})
+	}
+}
+
+func TestParseArgsGroupByPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{name: "not grouped", args: []string{t.TempDir()}, expected: ""},
+		{name: "default delimiter", args: []string{"--group-by-prefix", t.TempDir()}, expected: "-"},
+		{name: "named delimiter", args: []string{"--group-by-prefix=_", t.TempDir()}, expected: "_"},
+		{name: "merge", args: []string{"merge", "--group-by-prefix", t.TempDir()}, expected: "-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userInput, err := parseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if userInput.GroupBy != tt.expected {
+				t.Errorf("Expected GroupBy %q, got %q", tt.expected, userInput.GroupBy)
+			}
+		})
+	}
+}
+
+func TestParseArgsMissingTarget(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	for _, command := range []string{config.CommandSplit, config.CommandStats, config.CommandClean} {
+		_, err := parseArgs([]string{command, missing})
+		if err == nil || err.Error() != "target not found: "+missing {
+			t.Errorf("Expected %s to report the missing target, got %v", command, err)
+		}
+	}
+}
+
+func TestParseArgsMultipleTargets(t *testing.T) {
+	tempDir := t.TempDir()
+	fileA := filepath.Join(tempDir, "a.json")
+	fileB := filepath.Join(tempDir, "b.json")
+	subDir := filepath.Join(tempDir, "more")
+	for _, file := range []string{fileA, fileB} {
+		if err := os.WriteFile(file, []byte(`{}`), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		args          []string
+		expectedCount int
+		isDirectory   bool
+	}{
+		{
+			name:          "two files",
+			args:          []string{fileA, fileB},
+			expectedCount: 2,
+			isDirectory:   false,
+		},
+		{
+			name:          "file and directory",
+			args:          []string{fileA, subDir},
+			expectedCount: 2,
+			isDirectory:   false,
+		},
+		{
+			name:          "glob and file",
+			args:          []string{filepath.Join(tempDir, "*.json"), fileA},
+			expectedCount: 3,
+			isDirectory:   false,
+		},
+		{
+			name:          "single directory",
+			args:          []string{subDir},
+			expectedCount: 1,
+			isDirectory:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userInput, err := parseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(userInput.Targets) != tt.expectedCount {
+				t.Errorf("Expected %d targets, got %v", tt.expectedCount, userInput.Targets)
+			}
+			if userInput.IsDirectory != tt.isDirectory {
+				t.Errorf("Expected IsDirectory %v, got %v", tt.isDirectory, userInput.IsDirectory)
+			}
+		})
+	}
+}
+
+func TestParseArgsIndent(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(testFile, []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		expected   string
+		whitespace bool
+		expectErr  bool
+	}{
+		{name: "default", args: []string{testFile}, expected: ""},
+		{name: "default with whitespace", args: []string{"-w", testFile}, expected: "", whitespace: true},
+		{name: "four spaces", args: []string{"--indent", "4", testFile}, expected: "    ", whitespace: true},
+		{name: "tab", args: []string{"--indent", "tab", testFile}, expected: "\t", whitespace: true},
+		{name: "merge", args: []string{"merge", "--indent=tab", testFile}, expected: "\t", whitespace: true},
+		{name: "invalid", args: []string{"--indent", "wide", testFile}, expectErr: true},
+		{name: "zero", args: []string{"--indent", "0", testFile}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userInput, err := parseArgs(tt.args)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if userInput.Indent != tt.expected {
+				t.Errorf("Expected indent %q, got %q", tt.expected, userInput.Indent)
+			}
+			if userInput.Whitespace != tt.whitespace {
+				t.Errorf("Expected whitespace %v, got %v", tt.whitespace, userInput.Whitespace)
+			}
+		})
+	}
+}
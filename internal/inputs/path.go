@@ -0,0 +1,18 @@
+package inputs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// expandPath expands a leading ~ to the home directory and any environment variables,
+// for targets quoted in scripts where the shell won't have expanded them
+func expandPath(target string) string {
+	if target == "~" || strings.HasPrefix(target, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			target = filepath.Join(home, target[1:])
+		}
+	}
+	return os.ExpandEnv(target)
+}
@@ -0,0 +1,68 @@
+package inputs
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandPath(t *testing.T) {
+	t.Setenv("HOME", "/home/corset")
+	t.Setenv("POLICY_DIR", "/srv/policies")
+
+	tests := []struct {
+		name     string
+		target   string
+		expected string
+	}{
+		{
+			name:     "home directory",
+			target:   "~",
+			expected: "/home/corset",
+		},
+		{
+			name:     "path under home",
+			target:   "~/policies",
+			expected: filepath.Join("/home/corset", "policies"),
+		},
+		{
+			name:     "another user's home is left alone",
+			target:   "~other/policies",
+			expected: "~other/policies",
+		},
+		{
+			name:     "environment variable",
+			target:   "$POLICY_DIR/scp.json",
+			expected: "/srv/policies/scp.json",
+		},
+		{
+			name:     "braced environment variable",
+			target:   "${HOME}/policies",
+			expected: "/home/corset/policies",
+		},
+		{
+			name:     "plain path",
+			target:   "policies/scp.json",
+			expected: "policies/scp.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := expandPath(tt.target); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseArgsExpandsTarget(t *testing.T) {
+	t.Setenv("POLICY_DIR", t.TempDir())
+
+	userInput, err := parseArgs([]string{"$POLICY_DIR"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !userInput.IsDirectory {
+		t.Errorf("Expected %s to be expanded to a directory", userInput.Target)
+	}
+}
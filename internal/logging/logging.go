@@ -0,0 +1,119 @@
+// Package logging builds the slog handlers behind --log-level and --log-format
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jakebark/corset/internal/config"
+)
+
+// ParseLevel converts a --log-level name into its slog level
+func ParseLevel(name string) (slog.Level, error) {
+	switch name {
+	case config.LogLevelDebug:
+		return slog.LevelDebug, nil
+	case config.LogLevelInfo:
+		return slog.LevelInfo, nil
+	case config.LogLevelWarn:
+		return slog.LevelWarn, nil
+	case config.LogLevelError:
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log-level %s, use %s, %s, %s or %s",
+		name, config.LogLevelDebug, config.LogLevelInfo, config.LogLevelWarn, config.LogLevelError)
+}
+
+// NewHandler returns a handler writing records at level and above to w, as slog JSON for
+// --log-format json or otherwise as the concise lines corset has always printed
+func NewHandler(w io.Writer, level slog.Level, format string) slog.Handler {
+	if format == config.LogFormatJSON {
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	}
+	return &textHandler{w: w, level: level, mu: &sync.Mutex{}}
+}
+
+// textHandler prints "Warning: message" and "Error: message", or just the message below warn,
+// followed by any attributes as key=value. Times and levels are left out, as for a person at a terminal.
+type textHandler struct {
+	w      io.Writer
+	level  slog.Level
+	mu     *sync.Mutex // shared with the handlers derived by WithAttrs and WithGroup
+	attrs  []slog.Attr
+	prefix string // group names, dotted, for the keys of later attributes
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, record slog.Record) error {
+	var b strings.Builder
+	switch {
+	case record.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case record.Level >= slog.LevelWarn:
+		b.WriteString("Warning: ")
+	}
+	b.WriteString(record.Message)
+	for _, attr := range h.attrs {
+		writeAttr(&b, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		writeAttr(&b, h.prefix, attr)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	derived := *h
+	derived.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, attr := range attrs {
+		attr.Key = h.prefix + attr.Key
+		derived.attrs = append(derived.attrs, attr)
+	}
+	return &derived
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	derived := *h
+	derived.prefix = h.prefix + name + "."
+	return &derived
+}
+
+// writeAttr appends an attribute as key=value, quoting values that would otherwise be ambiguous
+func writeAttr(b *strings.Builder, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		// a group without a key is inlined
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			writeAttr(b, prefix, member)
+		}
+		return
+	}
+	value := attr.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	fmt.Fprintf(b, " %s%s=%s", prefix, attr.Key, value)
+}
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jakebark/corset/internal/config"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  slog.Level
+		expectErr bool
+	}{
+		{name: config.LogLevelDebug, expected: slog.LevelDebug},
+		{name: config.LogLevelInfo, expected: slog.LevelInfo},
+		{name: config.LogLevelWarn, expected: slog.LevelWarn},
+		{name: config.LogLevelError, expected: slog.LevelError},
+		{name: "verbose", expectErr: true},
+		{name: "DEBUG", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, err := ParseLevel(tt.name)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error, got level %v", level)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if level != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, level)
+			}
+		})
+	}
+}
+
+func TestTextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, slog.LevelInfo, config.LogFormatText))
+
+	logger.Debug("hidden below the level")
+	logger.Info("read policy file", "file", "policy.json", "statements", 3)
+	logger.Warn("policies declare different Versions")
+	logger.Error("1 invalid statements", "reason", "bad effect")
+	logger.With("file", "a.json").WithGroup("statement").Info("placed", "index", 2)
+
+	expected := strings.Join([]string{
+		"read policy file file=policy.json statements=3",
+		"Warning: policies declare different Versions",
+		`Error: 1 invalid statements reason="bad effect"`,
+		"placed file=a.json statement.index=2",
+	}, "\n") + "\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestJSONHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, slog.LevelDebug, config.LogFormatJSON))
+	logger.Debug("placed statement", "index", 1, "file", 2)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %s: %v", buf.String(), err)
+	}
+	if record["level"] != "DEBUG" || record["msg"] != "placed statement" || record["index"] != 1.0 || record["file"] != 2.0 {
+		t.Errorf("Unexpected record %v", record)
+	}
+}
@@ -1,23 +1,58 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"os"
 
+	"github.com/jakebark/corset/internal/aws"
+	"github.com/jakebark/corset/internal/config"
 	"github.com/jakebark/corset/internal/core"
 	"github.com/jakebark/corset/internal/inputs"
+	"github.com/jakebark/corset/internal/logging"
 )
 
 func main() {
-	log.SetFlags(0) // remove timestamp from prints
+	// concise warnings and errors while parsing, until the flags choose the level and format
+	slog.SetDefault(slog.New(logging.NewHandler(os.Stderr, slog.LevelInfo, config.LogFormatText)))
 
 	userInput := inputs.ParseFlags()
+	level, _ := logging.ParseLevel(userInput.LogLevel)
+	slog.SetDefault(slog.New(logging.NewHandler(os.Stderr, level, userInput.LogFormat)))
 
-	var files []string
-	if userInput.IsDirectory {
-		files = core.FindJSONFilesInDirectory(userInput.Target)
-	} else {
-		files = []string{userInput.Target}
+	files := core.ResolveFiles(userInput)
+
+	var err error
+	switch userInput.Command {
+	case config.CommandValidate:
+		var violations []core.Violation
+		violations, err = core.ValidateFiles(userInput, files)
+		if err == nil && len(violations) > 0 {
+			os.Exit(config.ExitFailure)
+		}
+	case config.CommandStats:
+		err = core.ReportStats(userInput, files)
+	case config.CommandClean:
+		err = core.CleanDirectory(userInput)
+	default:
+		if userInput.Watch {
+			err = core.WatchFiles(userInput, nil)
+			break
+		}
+		if userInput.Policy != "" {
+			err = core.WritePolicy(userInput, os.Stdout)
+			break
+		}
+		var summary core.ResultsSummary
+		summary, err = core.ProcessFiles(userInput, files)
+		core.ReportResults(userInput, summary)
+		if err == nil && userInput.Apply != "" {
+			err = aws.ApplyResults(context.Background(), userInput, summary.Files)
+		}
 	}
 
-	core.ProcessFiles(userInput, files)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(config.ExitFailure)
+	}
 }
@@ -107,7 +107,7 @@ func TestEndToEndPolicyProcessing(t *testing.T) {
 			// Process files
 			var files []string
 			if tt.isDirectory {
-				files = core.FindJSONFilesInDirectory(targetPath)
+				files = core.FindJSONFilesInDirectory(userInput, targetPath)
 			} else {
 				files = []string{targetPath}
 			}
@@ -0,0 +1,108 @@
+// Package corset packs AWS Organizations policy statements into as few policies as possible
+// within the AWS character limit. It is the library behind the corset CLI, working on policies
+// held in memory without reading or writing files or printing anything. Packing only logs to
+// Options.Logger, when one is given.
+package corset
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jakebark/corset/internal/config"
+	"github.com/jakebark/corset/internal/core"
+	"github.com/jakebark/corset/internal/inputs"
+)
+
+// Packing strategies for Options.Strategy
+const (
+	StrategyFirstFit = config.StrategyFirstFit // first-fit-decreasing, the default
+	StrategyBestFit  = config.StrategyBestFit  // best-fit-decreasing
+	StrategyBalance  = config.StrategyBalance  // even file sizes, across the fewest files that fit
+)
+
+// MaxPolicySize is the AWS SCP character limit, the default for Options.MaxSize
+const MaxPolicySize = config.MaxPolicySize
+
+// MaxPolicies is the most SCPs AWS attaches to a target, the default and the limit for Options.MaxFiles
+const MaxPolicies = config.MaxAllowedFiles
+
+// ErrNoStatements is returned when the policies contain no statements
+var ErrNoStatements = core.ErrNoStatements
+
+// Options control packing, matching the CLI flags of the same names. The zero value packs
+// minified policies into up to 5 files of 5120 characters with first-fit-decreasing.
+type Options struct {
+	MaxFiles      int    // maximum policies to pack into, up to MaxPolicies, 0 for 5
+	MaxSize       int    // characters allowed per policy, 0 for MaxPolicySize
+	MaxStatements int    // statements allowed per policy, 0 for no cap
+	Strategy      string // StrategyFirstFit, StrategyBestFit or StrategyBalance, empty for first fit
+	Minimize      bool   // search for the fewest policies the statements fit, within MaxFiles
+	Whitespace    bool   // indent the output rather than minifying it
+	Indent        string // indent for whitespace output, empty for two spaces
+
+	Dedupe      bool // remove statements equivalent to another, ignoring ordering and Sid
+	Merge       bool // merge statements that differ only in Action
+	Optimize    bool // drop actions covered by a wildcard and merge compatible conditions
+	Sid         bool // give statements without a Sid a generated one
+	StripSid    bool // remove every Sid
+	SortActions bool // sort Action and Resource arrays alphabetically
+	Collapse    bool // collapse single-element arrays to strings
+
+	Logger *slog.Logger // where packing logs each placement, and any fallback to minified output, nil for nowhere
+}
+
+// PackedFile is one packed policy
+type PackedFile struct {
+	Policy     []byte // the policy JSON
+	Size       int    // characters, as AWS counts them
+	Statements int
+}
+
+// Pack combines the statements of the JSON policies and packs them into as few policies as fit.
+// Each input is a policy document, whose Statement may be an array or a single object.
+// An input that is not a well-formed policy is an error, rather than being left out.
+func Pack(policies [][]byte, opts Options) ([]PackedFile, error) {
+	switch opts.Strategy {
+	case "", StrategyFirstFit, StrategyBestFit, StrategyBalance:
+	default:
+		return nil, fmt.Errorf("unknown strategy %s, use %s, %s or %s", opts.Strategy, StrategyFirstFit, StrategyBestFit, StrategyBalance)
+	}
+	if opts.MaxFiles < 0 || opts.MaxSize < 0 || opts.MaxStatements < 0 {
+		return nil, errors.New("MaxFiles, MaxSize and MaxStatements must be 0 or more")
+	}
+
+	userInput := inputs.UserInput{
+		MaxFiles:      opts.MaxFiles,
+		MaxSize:       opts.MaxSize,
+		MaxStatements: opts.MaxStatements,
+		Strategy:      opts.Strategy,
+		Minimize:      opts.Minimize,
+		Whitespace:    opts.Whitespace,
+		Indent:        opts.Indent,
+		Dedupe:        opts.Dedupe,
+		Merge:         opts.Merge,
+		Optimize:      opts.Optimize,
+		Sid:           opts.Sid,
+		StripSid:      opts.StripSid,
+		SortActions:   opts.SortActions,
+		Collapse:      opts.Collapse,
+		Logger:        opts.Logger,
+	}
+	if userInput.Logger == nil {
+		userInput.Logger = slog.New(slog.DiscardHandler)
+	}
+	if userInput.MaxFiles == 0 {
+		userInput.MaxFiles = config.DefaultMaxFiles
+	}
+
+	packed, err := core.PackData(userInput, policies)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]PackedFile, len(packed))
+	for i, policy := range packed {
+		files[i] = PackedFile{Policy: policy.Data, Size: policy.Size, Statements: policy.Statements}
+	}
+	return files, nil
+}
@@ -0,0 +1,137 @@
+package corset
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestPack(t *testing.T) {
+	policies := [][]byte{
+		[]byte(`{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": ["s3:DeleteBucket"], "Resource": "*"}]}`),
+		[]byte(`{"Version": "2012-10-17", "Statement": {"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}}`),
+	}
+
+	files, err := Pack(policies, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(files))
+	}
+
+	expected := `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":["s3:DeleteBucket"],"Resource":"*"},{"Effect":"Deny","Action":"ec2:*","Resource":"*"}]}`
+	if string(files[0].Policy) != expected {
+		t.Errorf("Expected %s, got %s", expected, files[0].Policy)
+	}
+	if files[0].Size != len(expected) || files[0].Statements != 2 {
+		t.Errorf("Expected %d characters and 2 statements, got %d and %d", len(expected), files[0].Size, files[0].Statements)
+	}
+
+	files, err = Pack(policies, Options{Collapse: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"s3:DeleteBucket","Resource":"*"},{"Effect":"Deny","Action":"ec2:*","Resource":"*"}]}`
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(files))
+	}
+	if string(files[0].Policy) != expected {
+		t.Errorf("Expected %s with Collapse, got %s", expected, files[0].Policy)
+	}
+}
+
+func TestPackSplits(t *testing.T) {
+	var statements []string
+	for i := 0; i < 10; i++ {
+		statements = append(statements, fmt.Sprintf(`{"Sid": "Deny%d", "Effect": "Deny", "Action": "s3:*", "Resource": "arn:aws:s3:::bucket-%d"}`, i, i))
+	}
+	policy := []byte(`{"Version": "2012-10-17", "Statement": [` + strings.Join(statements, ",") + `]}`)
+
+	files, err := Pack([][]byte{policy}, Options{MaxSize: 300, Strategy: StrategyBestFit})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) < 2 {
+		t.Fatalf("Expected the statements to be split, got %d files", len(files))
+	}
+
+	total := 0
+	for _, file := range files {
+		if file.Size > 300 {
+			t.Errorf("Expected each file within 300 characters, got %d", file.Size)
+		}
+		var decoded struct{ Statement []json.RawMessage }
+		if err := json.Unmarshal(file.Policy, &decoded); err != nil {
+			t.Fatalf("Expected valid JSON: %v", err)
+		}
+		if len(decoded.Statement) != file.Statements {
+			t.Errorf("Expected %d statements, got %d", file.Statements, len(decoded.Statement))
+		}
+		total += file.Statements
+	}
+	if total != len(statements) {
+		t.Errorf("Expected %d statements, got %d", len(statements), total)
+	}
+}
+
+func TestPackErrors(t *testing.T) {
+	valid := [][]byte{[]byte(`{"Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`)}
+
+	tests := []struct {
+		name     string
+		policies [][]byte
+		opts     Options
+		expected string
+	}{
+		{name: "no statements", policies: [][]byte{[]byte(`{"Statement": []}`)}, expected: ErrNoStatements.Error()},
+		{name: "malformed policy", policies: [][]byte{valid[0], []byte(`{"Statement": [{"Effect": "Deny", "Action": "ec2:*"`)}, expected: "policies[1].json: "},
+		{name: "invalid effect", policies: [][]byte{[]byte(`{"Statement": [{"Effect": "allow", "Action": "s3:*", "Resource": "*"}]}`)}, expected: "1 invalid statements"},
+		{name: "does not fit", policies: valid, opts: Options{MaxSize: 20}, expected: "could not be placed"},
+		{name: "unknown strategy", policies: valid, opts: Options{Strategy: "worst"}, expected: "unknown strategy"},
+		{name: "negative max files", policies: valid, opts: Options{MaxFiles: -1}, expected: "must be 0 or more"},
+		{name: "too many max files", policies: valid, opts: Options{MaxFiles: MaxPolicies + 1}, expected: "at most 5 SCP policies"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Pack(tt.policies, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	if _, err := Pack(nil, Options{}); !errors.Is(err, ErrNoStatements) {
+		t.Errorf("Expected ErrNoStatements, got %v", err)
+	}
+}
+
+func TestPackLogger(t *testing.T) {
+	policies := [][]byte{[]byte(`{"Version": "2012-10-17", "Statement": [{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`)}
+
+	// nothing reaches the default logger, even at debug
+	var defaultLogs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&defaultLogs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	if _, err := Pack(policies, Options{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if defaultLogs.Len() != 0 {
+		t.Errorf("Expected nothing logged without a Logger, got %q", defaultLogs.String())
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if _, err := Pack(policies, Options{Logger: logger}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(logs.String(), "placed statement") || defaultLogs.Len() != 0 {
+		t.Errorf("Expected the placements logged to the Logger alone, got %q", logs.String())
+	}
+}